@@ -0,0 +1,1053 @@
+package container
+
+import (
+	"github.com/clipperhouse/gen/typewriter"
+)
+
+var btreeSortedSet = &typewriter.Template{
+	Text: `
+
+// A sorted set backed by a B-tree with up to {{.Fanout}} children per node.
+// It has the methods of {{.Name}}SortedSet, bar its skiplist options, so
+// switching a tag from one to the other only changes the type's name, but
+// keeps items packed in slices, which makes it faster to search and
+// smaller than a skiplist.
+// Changing a set invalidates its iterators, and f must not change the set
+// while Each or ReverseEach is running.
+type {{.Name}}BTreeSortedSet struct {
+	less    func(a, b {{.Pointer}}{{.Name}}) bool
+	compare func(a, b {{.Pointer}}{{.Name}}) int
+	root    *btreeSortedSet{{.Name}}Node // nil when the set is empty
+	length  int
+}
+
+// the struct to hold nodes of the B-tree; leaves have no children, other
+// nodes have one more child than items, children[i] holding the items
+// between items[i-1] and items[i]
+type btreeSortedSet{{.Name}}Node struct {
+	items    []{{.Pointer}}{{.Name}}
+	children []*btreeSortedSet{{.Name}}Node
+}
+
+// every node but the root holds between the min and max number of items
+const (
+	btreeSortedSet{{.Name}}MaxItems = {{.Fanout}} - 1
+	btreeSortedSet{{.Name}}MinItems = btreeSortedSet{{.Name}}MaxItems / 2
+)
+
+// Creates and returns a reference to an empty set.
+func New{{.Name}}BTreeSortedSet(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) *{{.Name}}BTreeSortedSet {
+	compare := func(a, b {{.Pointer}}{{.Name}}) int {
+		if less(a, b) {
+			return -1
+		}
+		if less(b, a) {
+			return 1
+		}
+		return 0
+	}
+	return &{{.Name}}BTreeSortedSet{less: less, compare: compare}
+}
+
+// Creates and returns a reference to an empty set ordered by a three-way
+// comparison, as New{{.Name}}SortedSetWithCompare.
+func New{{.Name}}BTreeSortedSetWithCompare(compare func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) int) *{{.Name}}BTreeSortedSet {
+	less := func(a, b {{.Pointer}}{{.Name}}) bool {
+		return compare(a, b) < 0
+	}
+	return &{{.Name}}BTreeSortedSet{less: less, compare: compare}
+}
+{{if .Natural}}
+{{if eq .Natural "Compare"}}// Creates and returns a reference to an empty set ordered by
+// {{.Pointer}}{{.Name}}'s Compare method.
+func New{{.Name}}BTreeSortedSetNatural() *{{.Name}}BTreeSortedSet {
+	return New{{.Name}}BTreeSortedSetWithCompare(func(a, b {{.Pointer}}{{.Name}}) int {
+		return a.Compare(b)
+	})
+}
+{{else if eq .Natural "Less"}}// Creates and returns a reference to an empty set ordered by
+// {{.Pointer}}{{.Name}}'s Less method.
+func New{{.Name}}BTreeSortedSetNatural() *{{.Name}}BTreeSortedSet {
+	return New{{.Name}}BTreeSortedSet(func(a, b {{.Pointer}}{{.Name}}) bool {
+		return a.Less(b)
+	})
+}
+{{else}}// Creates and returns a reference to an empty set ordered by the < operator.
+func New{{.Name}}BTreeSortedSetNatural() *{{.Name}}BTreeSortedSet {
+	return New{{.Name}}BTreeSortedSetWithCompare(func(a, b {{.Name}}) int {
+		switch {
+		case a < b:
+			return -1
+		case b < a:
+			return 1
+		}
+		return 0
+	})
+}
+{{end}}{{end}}
+// Creates and returns a reference to a set from an existing slice.
+func New{{.Name}}BTreeSortedSetFromSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) *{{.Name}}BTreeSortedSet {
+	bs := New{{.Name}}BTreeSortedSet(less)
+	for _, v := range s {
+		bs.Add(v)
+	}
+	return bs
+}
+
+// Creates and returns a reference to a set from a slice already sorted by
+// less, in O(n). Runs of equal items are only added once.
+func New{{.Name}}BTreeSortedSetFromSortedSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) *{{.Name}}BTreeSortedSet {
+	distinct := make([]{{.Pointer}}{{.Name}}, 0, len(s))
+	for i, v := range s {
+		if i > 0 && !less(s[i-1], v) {
+			// equal to the item before it
+			continue
+		}
+		distinct = append(distinct, v)
+	}
+	return New{{.Name}}BTreeSortedSet(less).fromSorted(distinct)
+}
+
+// fromSorted returns a new set ordered the same way as this one, holding
+// the given items, which must be sorted and distinct.
+func (bs *{{.Name}}BTreeSortedSet) fromSorted(items []{{.Pointer}}{{.Name}}) *{{.Name}}BTreeSortedSet {
+	set := &{{.Name}}BTreeSortedSet{less: bs.less, compare: bs.compare, length: len(items)}
+	if len(items) == 0 {
+		return set
+	}
+	// find the least height that can hold every item
+	levels := 1
+	for most := btreeSortedSet{{.Name}}MaxItems; most < len(items); most = most*(btreeSortedSet{{.Name}}MaxItems+1) + btreeSortedSet{{.Name}}MaxItems {
+		levels++
+	}
+	set.root = buildBTreeSortedSet{{.Name}}Node(items, levels, true)
+	return set
+}
+
+// buildBTreeSortedSet{{.Name}}Node returns a subtree of the given height
+// holding items, sharing them out as evenly as it can so that every node
+// is at least half full.
+func buildBTreeSortedSet{{.Name}}Node(items []{{.Pointer}}{{.Name}}, levels int, root bool) *btreeSortedSet{{.Name}}Node {
+	if levels == 1 {
+		n := newBTreeSortedSet{{.Name}}Node(false)
+		n.items = append(n.items, items...)
+		return n
+	}
+	// capacity is one more than the most items a child can hold
+	capacity := 1
+	for level := 1; level < levels; level++ {
+		capacity *= btreeSortedSet{{.Name}}MaxItems + 1
+	}
+	k := (len(items) + capacity) / capacity
+	least := btreeSortedSet{{.Name}}MinItems + 1
+	if root {
+		least = 2
+	}
+	if k < least {
+		k = least
+	}
+	n := newBTreeSortedSet{{.Name}}Node(true)
+	size, extra := (len(items)-(k-1))/k, (len(items)-(k-1))%k
+	for j := 0; j < k; j++ {
+		m := size
+		if j < extra {
+			m++
+		}
+		n.children = append(n.children, buildBTreeSortedSet{{.Name}}Node(items[:m], levels-1, false))
+		items = items[m:]
+		if j < k-1 {
+			n.items = append(n.items, items[0])
+			items = items[1:]
+		}
+	}
+	return n
+}
+
+func newBTreeSortedSet{{.Name}}Node(internal bool) *btreeSortedSet{{.Name}}Node {
+	n := &btreeSortedSet{{.Name}}Node{items: make([]{{.Pointer}}{{.Name}}, 0, btreeSortedSet{{.Name}}MaxItems)}
+	if internal {
+		n.children = make([]*btreeSortedSet{{.Name}}Node, 0, btreeSortedSet{{.Name}}MaxItems+1)
+	}
+	return n
+}
+
+func (n *btreeSortedSet{{.Name}}Node) leaf() bool {
+	return len(n.children) == 0
+}
+
+func (n *btreeSortedSet{{.Name}}Node) insertItem(i int, v {{.Pointer}}{{.Name}}) {
+	n.items = append(n.items, v)
+	copy(n.items[i+1:], n.items[i:])
+	n.items[i] = v
+}
+
+func (n *btreeSortedSet{{.Name}}Node) removeItem(i int) {{.Pointer}}{{.Name}} {
+	v := n.items[i]
+	copy(n.items[i:], n.items[i+1:])
+	var zero {{.Pointer}}{{.Name}}
+	n.items[len(n.items)-1] = zero
+	n.items = n.items[:len(n.items)-1]
+	return v
+}
+
+func (n *btreeSortedSet{{.Name}}Node) insertChild(i int, c *btreeSortedSet{{.Name}}Node) {
+	n.children = append(n.children, c)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = c
+}
+
+func (n *btreeSortedSet{{.Name}}Node) removeChild(i int) *btreeSortedSet{{.Name}}Node {
+	c := n.children[i]
+	copy(n.children[i:], n.children[i+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+	return c
+}
+
+// split moves the items after item i, and the children after them, into a
+// new node, returning item i, which is taken out too, and the new node.
+func (n *btreeSortedSet{{.Name}}Node) split(i int) ({{.Pointer}}{{.Name}}, *btreeSortedSet{{.Name}}Node) {
+	v := n.items[i]
+	next := newBTreeSortedSet{{.Name}}Node(!n.leaf())
+	next.items = append(next.items, n.items[i+1:]...)
+	var zero {{.Pointer}}{{.Name}}
+	for j := i; j < len(n.items); j++ {
+		n.items[j] = zero
+	}
+	n.items = n.items[:i]
+	if !n.leaf() {
+		next.children = append(next.children, n.children[i+1:]...)
+		for j := i + 1; j < len(n.children); j++ {
+			n.children[j] = nil
+		}
+		n.children = n.children[:i+1]
+	}
+	return v, next
+}
+
+// find returns the index of v in n's items, and true, or else the index of
+// the child v belongs under, and false.
+func (bs *{{.Name}}BTreeSortedSet) find(n *btreeSortedSet{{.Name}}Node, v {{.Pointer}}{{.Name}}) (int, bool) {
+	lo, hi := 0, len(n.items)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		switch c := bs.compare(n.items[m], v); {
+		case c < 0:
+			lo = m + 1
+		case c > 0:
+			hi = m
+		default:
+			return m, true
+		}
+	}
+	return lo, false
+}
+
+// Adds an item to the set if it doesn't already exist in the set.
+func (bs *{{.Name}}BTreeSortedSet) Add(v {{.Pointer}}{{.Name}}) bool {
+	if bs.root == nil {
+		bs.root = newBTreeSortedSet{{.Name}}Node(false)
+	}
+	// full nodes are split on the way down, so there is always room for v
+	if len(bs.root.items) == btreeSortedSet{{.Name}}MaxItems {
+		old := bs.root
+		item, next := old.split(btreeSortedSet{{.Name}}MaxItems / 2)
+		bs.root = newBTreeSortedSet{{.Name}}Node(true)
+		bs.root.items = append(bs.root.items, item)
+		bs.root.children = append(bs.root.children, old, next)
+	}
+	n := bs.root
+	for {
+		i, found := bs.find(n, v)
+		if found {
+			return false
+		}
+		if n.leaf() {
+			n.insertItem(i, v)
+			bs.length++
+			return true
+		}
+		if len(n.children[i].items) == btreeSortedSet{{.Name}}MaxItems {
+			item, next := n.children[i].split(btreeSortedSet{{.Name}}MaxItems / 2)
+			n.insertItem(i, item)
+			n.insertChild(i+1, next)
+			switch c := bs.compare(v, item); {
+			case c == 0:
+				return false
+			case c > 0:
+				i++
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// AddAll adds every given item not already in the set, returning how many were added.
+func (bs *{{.Name}}BTreeSortedSet) AddAll(items ...{{.Pointer}}{{.Name}}) int {
+	added := 0
+	for _, v := range items {
+		if bs.Add(v) {
+			added++
+		}
+	}
+	return added
+}
+
+// grow gives n.children[i], which has the fewest items a node may have, one
+// more, by taking one from a sibling through n or by merging it with one.
+func (bs *{{.Name}}BTreeSortedSet) grow(n *btreeSortedSet{{.Name}}Node, i int) {
+	switch {
+	case i > 0 && len(n.children[i-1].items) > btreeSortedSet{{.Name}}MinItems:
+		// rotate an item in from the left sibling
+		child, left := n.children[i], n.children[i-1]
+		child.insertItem(0, n.items[i-1])
+		n.items[i-1] = left.removeItem(len(left.items) - 1)
+		if !left.leaf() {
+			child.insertChild(0, left.removeChild(len(left.children)-1))
+		}
+	case i < len(n.items) && len(n.children[i+1].items) > btreeSortedSet{{.Name}}MinItems:
+		// rotate an item in from the right sibling
+		child, right := n.children[i], n.children[i+1]
+		child.items = append(child.items, n.items[i])
+		n.items[i] = right.removeItem(0)
+		if !right.leaf() {
+			child.children = append(child.children, right.removeChild(0))
+		}
+	default:
+		// merge the child with a sibling and the item between them
+		if i == len(n.items) {
+			i--
+		}
+		child := n.children[i]
+		child.items = append(child.items, n.removeItem(i))
+		right := n.removeChild(i + 1)
+		child.items = append(child.items, right.items...)
+		child.children = append(child.children, right.children...)
+	}
+}
+
+// remove removes v from the subtree under n, which must have more than the
+// fewest items a node may have unless it is the root.
+func (bs *{{.Name}}BTreeSortedSet) remove(n *btreeSortedSet{{.Name}}Node, v {{.Pointer}}{{.Name}}) bool {
+	for {
+		i, found := bs.find(n, v)
+		if n.leaf() {
+			if found {
+				n.removeItem(i)
+			}
+			return found
+		}
+		if len(n.children[i].items) <= btreeSortedSet{{.Name}}MinItems {
+			// make room to remove from the child, then look again, as
+			// this may have moved v
+			bs.grow(n, i)
+			continue
+		}
+		if found {
+			// replace v with the greatest item before it, which is in a leaf
+			n.items[i] = bs.removeLast(n.children[i])
+			return true
+		}
+		n = n.children[i]
+	}
+}
+
+// removeFirst removes and returns the least item under n, which must not
+// be empty and must have more than the fewest items a node may have
+// unless it is the root.
+func (bs *{{.Name}}BTreeSortedSet) removeFirst(n *btreeSortedSet{{.Name}}Node) {{.Pointer}}{{.Name}} {
+	for !n.leaf() {
+		if len(n.children[0].items) <= btreeSortedSet{{.Name}}MinItems {
+			bs.grow(n, 0)
+			continue
+		}
+		n = n.children[0]
+	}
+	return n.removeItem(0)
+}
+
+// removeLast removes and returns the greatest item under n, as removeFirst.
+func (bs *{{.Name}}BTreeSortedSet) removeLast(n *btreeSortedSet{{.Name}}Node) {{.Pointer}}{{.Name}} {
+	for !n.leaf() {
+		last := len(n.children) - 1
+		if len(n.children[last].items) <= btreeSortedSet{{.Name}}MinItems {
+			bs.grow(n, last)
+			continue
+		}
+		n = n.children[last]
+	}
+	return n.removeItem(len(n.items) - 1)
+}
+
+// shrink drops the root once it has no items left, as merging its last
+// two children or removing the last item leaves it.
+func (bs *{{.Name}}BTreeSortedSet) shrink() {
+	if bs.root != nil && len(bs.root.items) == 0 {
+		if bs.root.leaf() {
+			bs.root = nil
+		} else {
+			bs.root = bs.root.children[0]
+		}
+	}
+}
+
+// Allows the removal of a single item in the set.
+// Returns true if the item was in the set.
+func (bs *{{.Name}}BTreeSortedSet) Remove(v {{.Pointer}}{{.Name}}) bool {
+	if bs.root == nil {
+		return false
+	}
+	removed := bs.remove(bs.root, v)
+	bs.shrink()
+	if removed {
+		bs.length--
+	}
+	return removed
+}
+
+// RemoveAll removes every given item that is in the set, returning how many were removed.
+func (bs *{{.Name}}BTreeSortedSet) RemoveAll(items ...{{.Pointer}}{{.Name}}) int {
+	removed := 0
+	for _, v := range items {
+		if bs.Remove(v) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// RetainAll removes every item that is not in the other set, returning how
+// many were removed.
+func (bs *{{.Name}}BTreeSortedSet) RetainAll(other *{{.Name}}BTreeSortedSet) int {
+	before := bs.length
+	bs.IntersectWith(other)
+	return before - bs.length
+}
+
+// RemoveIf removes every item for which pred returns true, returning how
+// many were removed. The set is rebuilt in a single pass.
+func (bs *{{.Name}}BTreeSortedSet) RemoveIf(pred func(v {{.Pointer}}{{.Name}}) bool) int {
+	before := bs.length
+	var kept []{{.Pointer}}{{.Name}}
+	bs.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		if !pred(v) {
+			kept = append(kept, v)
+		}
+		return true
+	})
+	*bs = *bs.fromSorted(kept)
+	return before - bs.length
+}
+
+// Clears the entire set to be the empty set.
+func (bs *{{.Name}}BTreeSortedSet) Clear() {
+	bs.root = nil
+	bs.length = 0
+}
+
+// Determines if a given item is already in the set.
+func (bs *{{.Name}}BTreeSortedSet) Contains(v {{.Pointer}}{{.Name}}) bool {
+	n := bs.root
+	for n != nil {
+		i, found := bs.find(n, v)
+		if found {
+			return true
+		}
+		if n.leaf() {
+			return false
+		}
+		n = n.children[i]
+	}
+	return false
+}
+
+// Determines if the given items are all in the set
+func (bs *{{.Name}}BTreeSortedSet) ContainsAll(i ...{{.Pointer}}{{.Name}}) bool {
+	for _, v := range i {
+		if !bs.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// floor returns the greatest item in the set less than or equal to v, or
+// if strict, less than v.
+func (bs *{{.Name}}BTreeSortedSet) floor(v {{.Pointer}}{{.Name}}, strict bool) (val {{.Pointer}}{{.Name}}, ok bool) {
+	for n := bs.root; n != nil; {
+		i, found := bs.find(n, v)
+		if found && !strict {
+			return n.items[i], true
+		}
+		// the item before i is less than v, and children[i] may hold greater ones
+		if i > 0 {
+			val, ok = n.items[i-1], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return
+}
+
+// ceiling returns the least item in the set greater than or equal to v, or
+// if strict, greater than v.
+func (bs *{{.Name}}BTreeSortedSet) ceiling(v {{.Pointer}}{{.Name}}, strict bool) (val {{.Pointer}}{{.Name}}, ok bool) {
+	for n := bs.root; n != nil; {
+		i, found := bs.find(n, v)
+		if found {
+			if !strict {
+				return n.items[i], true
+			}
+			i++
+		}
+		// item i is greater than v, and children[i] may hold lesser ones
+		if i < len(n.items) {
+			val, ok = n.items[i], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return
+}
+
+// Floor returns the greatest item in the set less than or equal to v.
+// ok is false if there is no such item.
+func (bs *{{.Name}}BTreeSortedSet) Floor(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return bs.floor(v, false)
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+// ok is false if there is no such item.
+func (bs *{{.Name}}BTreeSortedSet) Ceiling(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return bs.ceiling(v, false)
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+// ok is false if there is no such item.
+func (bs *{{.Name}}BTreeSortedSet) Lower(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return bs.floor(v, true)
+}
+
+// Higher returns the least item in the set strictly greater than v.
+// ok is false if there is no such item.
+func (bs *{{.Name}}BTreeSortedSet) Higher(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return bs.ceiling(v, true)
+}
+
+// A read-only view of the items of a {{.Name}}BTreeSortedSet between two
+// bounds. The view is evaluated lazily, seeking to its lower bound each time
+// it is iterated, so it reflects changes made to the set after it was created.
+type {{.Name}}BTreeSortedSetView struct {
+	bs                       *{{.Name}}BTreeSortedSet
+	lo, hi                   {{.Pointer}}{{.Name}}
+	hasLo, hasHi             bool
+	loInclusive, hiInclusive bool
+}
+
+// Range returns a view of the items between lo and hi, each bound being
+// included or excluded as requested.
+func (bs *{{.Name}}BTreeSortedSet) Range(lo, hi {{.Pointer}}{{.Name}}, loInclusive, hiInclusive bool) {{.Name}}BTreeSortedSetView {
+	return {{.Name}}BTreeSortedSetView{bs: bs, lo: lo, hi: hi, hasLo: true, hasHi: true, loInclusive: loInclusive, hiInclusive: hiInclusive}
+}
+
+// HeadSet returns a view of the items strictly less than hi.
+func (bs *{{.Name}}BTreeSortedSet) HeadSet(hi {{.Pointer}}{{.Name}}) {{.Name}}BTreeSortedSetView {
+	return {{.Name}}BTreeSortedSetView{bs: bs, hi: hi, hasHi: true}
+}
+
+// TailSet returns a view of the items greater than or equal to lo.
+func (bs *{{.Name}}BTreeSortedSet) TailSet(lo {{.Pointer}}{{.Name}}) {{.Name}}BTreeSortedSetView {
+	return {{.Name}}BTreeSortedSetView{bs: bs, lo: lo, hasLo: true, loInclusive: true}
+}
+
+// Each calls f for every item in the view in ascending order, stopping
+// early if f returns false.
+func (sv {{.Name}}BTreeSortedSetView) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	it := sv.bs.Iterator()
+	var ok bool
+	if !sv.hasLo {
+		ok = it.Next()
+	} else if ok = it.Seek(sv.lo); ok && !sv.loInclusive && !sv.bs.less(sv.lo, it.Value()) {
+		ok = it.Next()
+	}
+	for ; ok; ok = it.Next() {
+		v := it.Value()
+		if sv.hasHi && (sv.bs.less(sv.hi, v) || !sv.hiInclusive && !sv.bs.less(v, sv.hi)) {
+			return
+		}
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// Cardinality returns how many items are currently in the view.
+// Unlike the set's, this walks every item in the view.
+func (sv {{.Name}}BTreeSortedSetView) Cardinality() int {
+	n := 0
+	sv.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// ToSlice returns the items in the view in ascending order.
+func (sv {{.Name}}BTreeSortedSetView) ToSlice() []{{.Pointer}}{{.Name}} {
+	var s []{{.Pointer}}{{.Name}}
+	sv.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// ToSet copies the items in the view into a new set.
+func (sv {{.Name}}BTreeSortedSetView) ToSet() *{{.Name}}BTreeSortedSet {
+	return sv.bs.fromSorted(sv.ToSlice())
+}
+
+// merge walks both sets side by side in O(n+m), calling f with every item
+// in either set and whether it is in each, until f returns false.
+func (bs *{{.Name}}BTreeSortedSet) merge(other *{{.Name}}BTreeSortedSet, f func(v {{.Pointer}}{{.Name}}, inThis, inOther bool) bool) {
+	it, ot := bs.Iterator(), other.Iterator()
+	ok, ook := it.Next(), ot.Next()
+	for ok || ook {
+		var c int
+		switch {
+		case !ook:
+			c = -1
+		case !ok:
+			c = 1
+		default:
+			c = bs.compare(it.Value(), ot.Value())
+		}
+		switch {
+		case c < 0:
+			if !f(it.Value(), true, false) {
+				return
+			}
+			ok = it.Next()
+		case c > 0:
+			if !f(ot.Value(), false, true) {
+				return
+			}
+			ook = ot.Next()
+		default:
+			if !f(it.Value(), true, true) {
+				return
+			}
+			ok, ook = it.Next(), ot.Next()
+		}
+	}
+}
+
+// mergeInto returns a new set of the items merge finds for which keep
+// returns true.
+func (bs *{{.Name}}BTreeSortedSet) mergeInto(other *{{.Name}}BTreeSortedSet, keep func(inThis, inOther bool) bool) *{{.Name}}BTreeSortedSet {
+	var items []{{.Pointer}}{{.Name}}
+	bs.merge(other, func(v {{.Pointer}}{{.Name}}, inThis, inOther bool) bool {
+		if keep(inThis, inOther) {
+			items = append(items, v)
+		}
+		return true
+	})
+	return bs.fromSorted(items)
+}
+
+// IsSubset determines if every item in this set is in the other set.
+func (bs *{{.Name}}BTreeSortedSet) IsSubset(other *{{.Name}}BTreeSortedSet) bool {
+	if bs.length > other.length {
+		return false
+	}
+	subset := true
+	bs.merge(other, func(v {{.Pointer}}{{.Name}}, inThis, inOther bool) bool {
+		subset = !inThis || inOther
+		return subset
+	})
+	return subset
+}
+
+// IsSuperset determines if every item in the other set is in this set.
+func (bs *{{.Name}}BTreeSortedSet) IsSuperset(other *{{.Name}}BTreeSortedSet) bool {
+	return other.IsSubset(bs)
+}
+
+// IsProperSubset determines if this set is a subset of the other set and
+// the other set has at least one item this one does not.
+func (bs *{{.Name}}BTreeSortedSet) IsProperSubset(other *{{.Name}}BTreeSortedSet) bool {
+	return bs.length < other.length && bs.IsSubset(other)
+}
+
+// IsProperSuperset determines if this set is a superset of the other set
+// and has at least one item the other set does not.
+func (bs *{{.Name}}BTreeSortedSet) IsProperSuperset(other *{{.Name}}BTreeSortedSet) bool {
+	return other.IsProperSubset(bs)
+}
+
+// IsDisjoint determines if the two sets have no items in common.
+// An empty set is disjoint with every set, itself included.
+func (bs *{{.Name}}BTreeSortedSet) IsDisjoint(other *{{.Name}}BTreeSortedSet) bool {
+	disjoint := true
+	bs.merge(other, func(v {{.Pointer}}{{.Name}}, inThis, inOther bool) bool {
+		disjoint = !inThis || !inOther
+		return disjoint
+	})
+	return disjoint
+}
+
+// Overlaps determines if the two sets have at least one item in common.
+func (bs *{{.Name}}BTreeSortedSet) Overlaps(other *{{.Name}}BTreeSortedSet) bool {
+	return !bs.IsDisjoint(other)
+}
+
+// Returns a new set with all items in both sets, in O(n+m).
+func (bs *{{.Name}}BTreeSortedSet) Union(other *{{.Name}}BTreeSortedSet) *{{.Name}}BTreeSortedSet {
+	return bs.mergeInto(other, func(inThis, inOther bool) bool {
+		return true
+	})
+}
+
+// Returns a new set with items that exist only in both sets, in O(n+m).
+func (bs *{{.Name}}BTreeSortedSet) Intersect(other *{{.Name}}BTreeSortedSet) *{{.Name}}BTreeSortedSet {
+	return bs.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis && inOther
+	})
+}
+
+// Returns a new set with items in the current set but not in the other set, in O(n+m).
+func (bs *{{.Name}}BTreeSortedSet) Difference(other *{{.Name}}BTreeSortedSet) *{{.Name}}BTreeSortedSet {
+	return bs.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis && !inOther
+	})
+}
+
+// Returns a new set with items in the current set or the other set but not in both, in O(n+m).
+func (bs *{{.Name}}BTreeSortedSet) SymmetricDifference(other *{{.Name}}BTreeSortedSet) *{{.Name}}BTreeSortedSet {
+	return bs.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis != inOther
+	})
+}
+
+// UnionWith adds every item of the other set to this one.
+func (bs *{{.Name}}BTreeSortedSet) UnionWith(other *{{.Name}}BTreeSortedSet) {
+	*bs = *bs.Union(other)
+}
+
+// IntersectWith removes every item that is not also in the other set.
+func (bs *{{.Name}}BTreeSortedSet) IntersectWith(other *{{.Name}}BTreeSortedSet) {
+	*bs = *bs.Intersect(other)
+}
+
+// DifferenceWith removes every item that is also in the other set.
+func (bs *{{.Name}}BTreeSortedSet) DifferenceWith(other *{{.Name}}BTreeSortedSet) {
+	*bs = *bs.Difference(other)
+}
+
+// Cardinality returns how many items are currently in the set.
+func (bs *{{.Name}}BTreeSortedSet) Cardinality() int {
+	return bs.length
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (bs *{{.Name}}BTreeSortedSet) Len() int {
+	return bs.length
+}
+
+// A cursor over the items of a {{.Name}}BTreeSortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next, Prev or Seek has returned true.
+type {{.Name}}BTreeSortedSetIterator struct {
+	bs *{{.Name}}BTreeSortedSet
+	// the path from the root to the item at the cursor: the top frame
+	// holds the item's index, the ones below the child descended into
+	stack   []btreeSortedSet{{.Name}}Frame
+	started bool // with an empty stack, whether the cursor is past the last item rather than before the first
+}
+
+type btreeSortedSet{{.Name}}Frame struct {
+	n *btreeSortedSet{{.Name}}Node
+	i int
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (bs *{{.Name}}BTreeSortedSet) Iterator() *{{.Name}}BTreeSortedSetIterator {
+	return &{{.Name}}BTreeSortedSetIterator{bs: bs}
+}
+
+// ReverseIterator returns a cursor positioned after the last item of the
+// set, to be walked from largest to smallest with Prev.
+func (bs *{{.Name}}BTreeSortedSet) ReverseIterator() *{{.Name}}BTreeSortedSetIterator {
+	return &{{.Name}}BTreeSortedSetIterator{bs: bs, started: true}
+}
+
+// pushFirst extends the path down to the least item under n.
+func (it *{{.Name}}BTreeSortedSetIterator) pushFirst(n *btreeSortedSet{{.Name}}Node) {
+	for {
+		it.stack = append(it.stack, btreeSortedSet{{.Name}}Frame{n, 0})
+		if n.leaf() {
+			return
+		}
+		n = n.children[0]
+	}
+}
+
+// pushLast extends the path down to the greatest item under n.
+func (it *{{.Name}}BTreeSortedSetIterator) pushLast(n *btreeSortedSet{{.Name}}Node) {
+	for !n.leaf() {
+		it.stack = append(it.stack, btreeSortedSet{{.Name}}Frame{n, len(n.children) - 1})
+		n = n.children[len(n.children)-1]
+	}
+	it.stack = append(it.stack, btreeSortedSet{{.Name}}Frame{n, len(n.items) - 1})
+}
+
+// up climbs from a leaf whose items have all been passed to the nearest
+// item after it, returning false if there is none.
+func (it *{{.Name}}BTreeSortedSetIterator) up() bool {
+	for {
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			return false
+		}
+		// the child descended into comes just before the item of the same index
+		if top := it.stack[len(it.stack)-1]; top.i < len(top.n.items) {
+			return true
+		}
+	}
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *{{.Name}}BTreeSortedSetIterator) Next() bool {
+	if len(it.stack) == 0 {
+		if it.started || it.bs.root == nil {
+			it.started = true
+			return false
+		}
+		it.started = true
+		it.pushFirst(it.bs.root)
+		return true
+	}
+	top := &it.stack[len(it.stack)-1]
+	top.i++
+	if !top.n.leaf() {
+		it.pushFirst(top.n.children[top.i])
+		return true
+	}
+	if top.i < len(top.n.items) {
+		return true
+	}
+	return it.up()
+}
+
+// Prev moves the cursor to the previous item, returning false once it has
+// moved before the first one.
+func (it *{{.Name}}BTreeSortedSetIterator) Prev() bool {
+	if len(it.stack) == 0 {
+		if !it.started || it.bs.root == nil {
+			it.started = false
+			return false
+		}
+		it.pushLast(it.bs.root)
+		return true
+	}
+	top := &it.stack[len(it.stack)-1]
+	if !top.n.leaf() {
+		it.pushLast(top.n.children[top.i])
+		return true
+	}
+	top.i--
+	if top.i >= 0 {
+		return true
+	}
+	for {
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			it.started = false
+			return false
+		}
+		// the item before the child descended into has the index before it
+		if top := &it.stack[len(it.stack)-1]; top.i > 0 {
+			top.i--
+			return true
+		}
+	}
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *{{.Name}}BTreeSortedSetIterator) Seek(v {{.Pointer}}{{.Name}}) bool {
+	it.stack = it.stack[:0]
+	it.started = true
+	n := it.bs.root
+	for n != nil {
+		i, found := it.bs.find(n, v)
+		it.stack = append(it.stack, btreeSortedSet{{.Name}}Frame{n, i})
+		if found {
+			return true
+		}
+		if n.leaf() {
+			if i < len(n.items) {
+				return true
+			}
+			// every item in the leaf is less than v
+			return it.up()
+		}
+		n = n.children[i]
+	}
+	return false
+}
+
+// Value returns the item at the cursor.
+func (it *{{.Name}}BTreeSortedSetIterator) Value() {{.Pointer}}{{.Name}} {
+	top := it.stack[len(it.stack)-1]
+	return top.n.items[top.i]
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (bs *{{.Name}}BTreeSortedSet) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	if bs.root != nil {
+		bs.root.each(f)
+	}
+}
+
+func (n *btreeSortedSet{{.Name}}Node) each(f func(v {{.Pointer}}{{.Name}}) bool) bool {
+	for i, v := range n.items {
+		if !n.leaf() && !n.children[i].each(f) {
+			return false
+		}
+		if !f(v) {
+			return false
+		}
+	}
+	return n.leaf() || n.children[len(n.items)].each(f)
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+func (bs *{{.Name}}BTreeSortedSet) ReverseEach(f func(v {{.Pointer}}{{.Name}}) bool) {
+	if bs.root != nil {
+		bs.root.reverseEach(f)
+	}
+}
+
+func (n *btreeSortedSet{{.Name}}Node) reverseEach(f func(v {{.Pointer}}{{.Name}}) bool) bool {
+	for i := len(n.items) - 1; i >= 0; i-- {
+		if !n.leaf() && !n.children[i+1].reverseEach(f) {
+			return false
+		}
+		if !f(n.items[i]) {
+			return false
+		}
+	}
+	return n.leaf() || n.children[0].reverseEach(f)
+}
+
+// First returns the least item in the set.
+// ok is false if the set is empty.
+func (bs *{{.Name}}BTreeSortedSet) First() (val {{.Pointer}}{{.Name}}, ok bool) {
+	n := bs.root
+	if n == nil {
+		return
+	}
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return n.items[0], true
+}
+
+// PopFirst removes and returns the least item in the set.
+// ok is false if the set is empty.
+func (bs *{{.Name}}BTreeSortedSet) PopFirst() (val {{.Pointer}}{{.Name}}, ok bool) {
+	if bs.root == nil {
+		return
+	}
+	val = bs.removeFirst(bs.root)
+	bs.shrink()
+	bs.length--
+	return val, true
+}
+
+// PopN removes and returns the k least items in the set in ascending
+// order, or every item if there are fewer than k.
+func (bs *{{.Name}}BTreeSortedSet) PopN(k int) []{{.Pointer}}{{.Name}} {
+	var popped []{{.Pointer}}{{.Name}}
+	for len(popped) < k {
+		v, ok := bs.PopFirst()
+		if !ok {
+			break
+		}
+		popped = append(popped, v)
+	}
+	return popped
+}
+
+// Last returns the greatest item in the set.
+// ok is false if the set is empty.
+func (bs *{{.Name}}BTreeSortedSet) Last() (val {{.Pointer}}{{.Name}}, ok bool) {
+	n := bs.root
+	if n == nil {
+		return
+	}
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	return n.items[len(n.items)-1], true
+}
+
+// PopLast removes and returns the greatest item in the set.
+// ok is false if the set is empty.
+func (bs *{{.Name}}BTreeSortedSet) PopLast() (val {{.Pointer}}{{.Name}}, ok bool) {
+	if bs.root == nil {
+		return
+	}
+	val = bs.removeLast(bs.root)
+	bs.shrink()
+	bs.length--
+	return val, true
+}
+
+// PopMax removes and returns the greatest item in the set, like PopLast.
+func (bs *{{.Name}}BTreeSortedSet) PopMax() (val {{.Pointer}}{{.Name}}, ok bool) {
+	return bs.PopLast()
+}
+
+// Iter() returns a channel of type {{.Pointer}}{{.Name}} that you can range over,
+// like {{.Name}}SortedSet's, so code ranging over a set keeps working when
+// the tag is switched. The set must not be changed until the channel is
+// drained, and breaking out of the range loop leaks the goroutine feeding
+// the channel, so prefer Iterator or Each.
+func (bs *{{.Name}}BTreeSortedSet) Iter() <-chan {{.Pointer}}{{.Name}} {
+	ch := make(chan {{.Pointer}}{{.Name}})
+	go func() {
+		bs.Each(func(v {{.Pointer}}{{.Name}}) bool {
+			ch <- v
+			return true
+		})
+		close(ch)
+	}()
+
+	return ch
+}
+
+// Equal determines if two sets are equal to each other.
+// If they both are the same size and have the same items they are considered equal.
+func (bs *{{.Name}}BTreeSortedSet) Equal(other *{{.Name}}BTreeSortedSet) bool {
+	return bs.length == other.length && bs.IsSubset(other)
+}
+
+// Returns a clone of the set, in O(n).
+// Does NOT clone the underlying elements.
+func (bs *{{.Name}}BTreeSortedSet) Clone() *{{.Name}}BTreeSortedSet {
+	items := make([]{{.Pointer}}{{.Name}}, 0, bs.length)
+	bs.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		items = append(items, v)
+		return true
+	})
+	return bs.fromSorted(items)
+}
+`,
+}
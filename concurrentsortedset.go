@@ -0,0 +1,380 @@
+package container
+
+import (
+	"github.com/clipperhouse/gen/typewriter"
+)
+
+var concurrentSortedSet = &typewriter.Template{
+	Text: `
+
+// A {{.Name}}SortedSet that is safe to share between goroutines.
+// Reads take a read lock and writes take the write lock. Methods that
+// take another set copy it under its own read lock first, so two sets
+// are never locked at once.
+type {{.Name}}ConcurrentSortedSet struct {
+	mu  sync.RWMutex
+	set *{{.Name}}SortedSet
+}
+
+// Creates and returns a reference to an empty concurrent set.
+func New{{.Name}}ConcurrentSortedSet(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) *{{.Name}}ConcurrentSortedSet {
+	return &{{.Name}}ConcurrentSortedSet{set: New{{.Name}}SortedSet(less)}
+}
+
+// Creates and returns a reference to an empty concurrent set ordered by a
+// three-way comparison, as New{{.Name}}SortedSetWithCompare.
+func New{{.Name}}ConcurrentSortedSetWithCompare(compare func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) int) *{{.Name}}ConcurrentSortedSet {
+	return &{{.Name}}ConcurrentSortedSet{set: New{{.Name}}SortedSetWithCompare(compare)}
+}
+
+// Creates and returns a reference to an empty concurrent set whose
+// skiplist is tuned by the given options, as New{{.Name}}SortedSetWithOptions.
+func New{{.Name}}ConcurrentSortedSetWithOptions(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, options ...{{.Name}}SortedSetOption) *{{.Name}}ConcurrentSortedSet {
+	return &{{.Name}}ConcurrentSortedSet{set: New{{.Name}}SortedSetWithOptions(less, options...)}
+}
+{{if .Natural}}
+// Creates and returns a reference to an empty concurrent set in the
+// natural order of {{.Pointer}}{{.Name}}, as New{{.Name}}SortedSetNatural.
+func New{{.Name}}ConcurrentSortedSetNatural() *{{.Name}}ConcurrentSortedSet {
+	return &{{.Name}}ConcurrentSortedSet{set: New{{.Name}}SortedSetNatural()}
+}
+{{end}}
+// Creates and returns a reference to a concurrent set from an existing slice.
+func New{{.Name}}ConcurrentSortedSetFromSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) *{{.Name}}ConcurrentSortedSet {
+	return &{{.Name}}ConcurrentSortedSet{set: New{{.Name}}SortedSetFromSlice(less, s)}
+}
+
+// Creates and returns a reference to a concurrent set from a slice already
+// sorted by less, in O(n), as New{{.Name}}SortedSetFromSortedSlice.
+func New{{.Name}}ConcurrentSortedSetFromSortedSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) *{{.Name}}ConcurrentSortedSet {
+	return &{{.Name}}ConcurrentSortedSet{set: New{{.Name}}SortedSetFromSortedSlice(less, s)}
+}
+
+// Snapshot returns a copy of the set as it is now, which the caller can
+// read and iterate without any locking. Taking one doesn't draw from a rand
+// given by {{.Name}}SortedSetWithRand, but adding to it does, as the copy
+// shares that rand.
+func (cs *{{.Name}}ConcurrentSortedSet) Snapshot() *{{.Name}}SortedSet {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Clone()
+}
+
+// Returns a clone of the set, in O(n).
+// Does NOT clone the underlying elements.
+func (cs *{{.Name}}ConcurrentSortedSet) Clone() *{{.Name}}ConcurrentSortedSet {
+	return &{{.Name}}ConcurrentSortedSet{set: cs.Snapshot()}
+}
+
+// Adds an item to the set if it doesn't already exist in the set.
+func (cs *{{.Name}}ConcurrentSortedSet) Add(v {{.Pointer}}{{.Name}}) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.Add(v)
+}
+
+// AddAll adds every given item not already in the set, returning how many were added.
+func (cs *{{.Name}}ConcurrentSortedSet) AddAll(items ...{{.Pointer}}{{.Name}}) int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.AddAll(items...)
+}
+
+// Allows the removal of a single item in the set.
+// Returns true if the item was in the set.
+func (cs *{{.Name}}ConcurrentSortedSet) Remove(v {{.Pointer}}{{.Name}}) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.Remove(v)
+}
+
+// RemoveAll removes every given item that is in the set, returning how many were removed.
+func (cs *{{.Name}}ConcurrentSortedSet) RemoveAll(items ...{{.Pointer}}{{.Name}}) int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.RemoveAll(items...)
+}
+
+// RemoveIf removes every item for which pred returns true, returning how many were removed.
+// pred is called with the write lock held, so it must not use the set.
+func (cs *{{.Name}}ConcurrentSortedSet) RemoveIf(pred func(v {{.Pointer}}{{.Name}}) bool) int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.RemoveIf(pred)
+}
+
+// RetainAll removes every item that is not in the other set, returning how
+// many were removed.
+func (cs *{{.Name}}ConcurrentSortedSet) RetainAll(other *{{.Name}}ConcurrentSortedSet) int {
+	if other == cs {
+		return 0
+	}
+	o := other.Snapshot()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.RetainAll(o)
+}
+
+// Clears the entire set to be the empty set.
+func (cs *{{.Name}}ConcurrentSortedSet) Clear() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.set.Clear()
+}
+
+// Determines if a given item is already in the set.
+func (cs *{{.Name}}ConcurrentSortedSet) Contains(v {{.Pointer}}{{.Name}}) bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Contains(v)
+}
+
+// Determines if the given items are all in the set
+func (cs *{{.Name}}ConcurrentSortedSet) ContainsAll(i ...{{.Pointer}}{{.Name}}) bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.ContainsAll(i...)
+}
+
+// Cardinality returns how many items are currently in the set.
+func (cs *{{.Name}}ConcurrentSortedSet) Cardinality() int {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Cardinality()
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (cs *{{.Name}}ConcurrentSortedSet) Len() int {
+	return cs.Cardinality()
+}
+
+// Floor returns the greatest item in the set less than or equal to v.
+func (cs *{{.Name}}ConcurrentSortedSet) Floor(v {{.Pointer}}{{.Name}}) ({{.Pointer}}{{.Name}}, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Floor(v)
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+func (cs *{{.Name}}ConcurrentSortedSet) Ceiling(v {{.Pointer}}{{.Name}}) ({{.Pointer}}{{.Name}}, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Ceiling(v)
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+func (cs *{{.Name}}ConcurrentSortedSet) Lower(v {{.Pointer}}{{.Name}}) ({{.Pointer}}{{.Name}}, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Lower(v)
+}
+
+// Higher returns the least item in the set strictly greater than v.
+func (cs *{{.Name}}ConcurrentSortedSet) Higher(v {{.Pointer}}{{.Name}}) ({{.Pointer}}{{.Name}}, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Higher(v)
+}
+
+// First returns the least item in the set.
+func (cs *{{.Name}}ConcurrentSortedSet) First() ({{.Pointer}}{{.Name}}, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.First()
+}
+
+// Last returns the greatest item in the set.
+func (cs *{{.Name}}ConcurrentSortedSet) Last() ({{.Pointer}}{{.Name}}, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Last()
+}
+
+// PopFirst removes and returns the least item in the set.
+func (cs *{{.Name}}ConcurrentSortedSet) PopFirst() ({{.Pointer}}{{.Name}}, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.PopFirst()
+}
+
+// PopLast removes and returns the greatest item in the set.
+func (cs *{{.Name}}ConcurrentSortedSet) PopLast() ({{.Pointer}}{{.Name}}, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.PopLast()
+}
+
+// PopMax removes and returns the greatest item in the set, like PopLast.
+func (cs *{{.Name}}ConcurrentSortedSet) PopMax() ({{.Pointer}}{{.Name}}, bool) {
+	return cs.PopLast()
+}
+
+// PopN removes and returns the k least items in the set in ascending order.
+func (cs *{{.Name}}ConcurrentSortedSet) PopN(k int) []{{.Pointer}}{{.Name}} {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.PopN(k)
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+// f is called with the read lock held, so it must not change the set.
+func (cs *{{.Name}}ConcurrentSortedSet) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	cs.set.Each(f)
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+// f is called with the read lock held, so it must not change the set.
+func (cs *{{.Name}}ConcurrentSortedSet) ReverseEach(f func(v {{.Pointer}}{{.Name}}) bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	cs.set.ReverseEach(f)
+}
+
+// Iter returns a channel of the items of a snapshot of the set, in ascending order.
+func (cs *{{.Name}}ConcurrentSortedSet) Iter() <-chan {{.Pointer}}{{.Name}} {
+	return cs.Snapshot().Iter()
+}
+
+// Range returns a view of the items of a snapshot of the set between lo
+// and hi, each bound being included or excluded as requested. Unlike a
+// {{.Name}}SortedSet's, the view doesn't see later changes to the set.
+func (cs *{{.Name}}ConcurrentSortedSet) Range(lo, hi {{.Pointer}}{{.Name}}, loInclusive, hiInclusive bool) {{.Name}}SortedSetView {
+	return cs.Snapshot().Range(lo, hi, loInclusive, hiInclusive)
+}
+
+// HeadSet returns a view of the items of a snapshot of the set strictly less than hi.
+func (cs *{{.Name}}ConcurrentSortedSet) HeadSet(hi {{.Pointer}}{{.Name}}) {{.Name}}SortedSetView {
+	return cs.Snapshot().HeadSet(hi)
+}
+
+// TailSet returns a view of the items of a snapshot of the set greater than or equal to lo.
+func (cs *{{.Name}}ConcurrentSortedSet) TailSet(lo {{.Pointer}}{{.Name}}) {{.Name}}SortedSetView {
+	return cs.Snapshot().TailSet(lo)
+}
+
+// Iterator returns a cursor over a snapshot of the set, so it can be
+// walked at any pace without holding up writers.
+func (cs *{{.Name}}ConcurrentSortedSet) Iterator() *{{.Name}}SortedSetIterator {
+	return cs.Snapshot().Iterator()
+}
+
+// ReverseIterator returns a cursor positioned after the last item of a
+// snapshot of the set, to be walked from largest to smallest with Prev.
+func (cs *{{.Name}}ConcurrentSortedSet) ReverseIterator() *{{.Name}}SortedSetIterator {
+	return cs.Snapshot().ReverseIterator()
+}
+
+// Returns a new set with all items in both sets.
+func (cs *{{.Name}}ConcurrentSortedSet) Union(other *{{.Name}}ConcurrentSortedSet) *{{.Name}}ConcurrentSortedSet {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return &{{.Name}}ConcurrentSortedSet{set: cs.set.Union(o)}
+}
+
+// Returns a new set with items that exist only in both sets.
+func (cs *{{.Name}}ConcurrentSortedSet) Intersect(other *{{.Name}}ConcurrentSortedSet) *{{.Name}}ConcurrentSortedSet {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return &{{.Name}}ConcurrentSortedSet{set: cs.set.Intersect(o)}
+}
+
+// Returns a new set with items in the current set but not in the other set.
+func (cs *{{.Name}}ConcurrentSortedSet) Difference(other *{{.Name}}ConcurrentSortedSet) *{{.Name}}ConcurrentSortedSet {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return &{{.Name}}ConcurrentSortedSet{set: cs.set.Difference(o)}
+}
+
+// Returns a new set with items in the current set or the other set but not in both.
+func (cs *{{.Name}}ConcurrentSortedSet) SymmetricDifference(other *{{.Name}}ConcurrentSortedSet) *{{.Name}}ConcurrentSortedSet {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return &{{.Name}}ConcurrentSortedSet{set: cs.set.SymmetricDifference(o)}
+}
+
+// UnionWith adds every item of the other set to this one.
+func (cs *{{.Name}}ConcurrentSortedSet) UnionWith(other *{{.Name}}ConcurrentSortedSet) {
+	o := other.Snapshot()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.set.UnionWith(o)
+}
+
+// IntersectWith removes every item that is not also in the other set.
+func (cs *{{.Name}}ConcurrentSortedSet) IntersectWith(other *{{.Name}}ConcurrentSortedSet) {
+	o := other.Snapshot()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.set.IntersectWith(o)
+}
+
+// DifferenceWith removes every item that is also in the other set.
+func (cs *{{.Name}}ConcurrentSortedSet) DifferenceWith(other *{{.Name}}ConcurrentSortedSet) {
+	o := other.Snapshot()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.set.DifferenceWith(o)
+}
+
+// IsSubset determines if every item in this set is in the other set.
+func (cs *{{.Name}}ConcurrentSortedSet) IsSubset(other *{{.Name}}ConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.IsSubset(o)
+}
+
+// IsSuperset determines if every item in the other set is in this set.
+func (cs *{{.Name}}ConcurrentSortedSet) IsSuperset(other *{{.Name}}ConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.IsSuperset(o)
+}
+
+// IsProperSubset determines if this set is a subset of the other set and
+// the other set has at least one item this one does not.
+func (cs *{{.Name}}ConcurrentSortedSet) IsProperSubset(other *{{.Name}}ConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.IsProperSubset(o)
+}
+
+// IsProperSuperset determines if this set is a superset of the other set
+// and has at least one item the other set does not.
+func (cs *{{.Name}}ConcurrentSortedSet) IsProperSuperset(other *{{.Name}}ConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.IsProperSuperset(o)
+}
+
+// IsDisjoint determines if the two sets have no items in common.
+func (cs *{{.Name}}ConcurrentSortedSet) IsDisjoint(other *{{.Name}}ConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.IsDisjoint(o)
+}
+
+// Overlaps determines if the two sets have at least one item in common.
+func (cs *{{.Name}}ConcurrentSortedSet) Overlaps(other *{{.Name}}ConcurrentSortedSet) bool {
+	return !cs.IsDisjoint(other)
+}
+
+// Equal determines if two sets are equal to each other.
+func (cs *{{.Name}}ConcurrentSortedSet) Equal(other *{{.Name}}ConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Equal(o)
+}
+`,
+}
@@ -1,9 +1,13 @@
 package container
 
 import (
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	"github.com/clipperhouse/gen/typewriter"
+	"golang.org/x/tools/go/types"
 )
 
 func init() {
@@ -23,6 +27,97 @@ func NewContainerWriter() *ContainerWriter {
 	}
 }
 
+// model is what the templates are executed against; it adds the tag
+// parameters (e.g. the value type in SortedMap[string]) to the tagged type.
+type model struct {
+	typewriter.Type
+	ValueType string
+	Natural   string // see naturalOrder
+	Fanout    int    // the most children a BTreeSortedSet node has
+}
+
+// the fanout of a BTreeSortedSet with no parameter
+const defaultFanout = 32
+
+// parseFanout reads the fanout in a tag item such as "BTreeSortedSet[64]".
+func parseFanout(param string) (int, error) {
+	if param == "" {
+		return defaultFanout, nil
+	}
+	fanout, err := strconv.Atoi(param)
+	if err != nil || fanout < 4 || fanout%2 != 0 {
+		return 0, fmt.Errorf("BTreeSortedSet fanout must be an even number of at least 4, got %q", param)
+	}
+	return fanout, nil
+}
+
+// naturalOrder returns how t can be ordered without a comparator:
+// "Compare" or "Less" if it has such a method taking another t, "<" if
+// it is an ordered builtin such as an int, a float or a string, and ""
+// if it can't be.
+func naturalOrder(t typewriter.Type) string {
+	self := t.Type
+	if t.Pointer {
+		self = types.NewPointer(t.Type)
+	}
+	methods := types.NewMethodSet(self)
+	if hasMethod(methods, "Compare", self, types.Typ[types.Int]) {
+		return "Compare"
+	}
+	if hasMethod(methods, "Less", self, types.Typ[types.Bool]) {
+		return "Less"
+	}
+	if t.Ordered() && !bool(t.Pointer) {
+		return "<"
+	}
+	return ""
+}
+
+// hasMethod determines if methods has one called name that takes a single
+// arg and returns a single result.
+func hasMethod(methods *types.MethodSet, name string, arg, result types.Type) bool {
+	sel := methods.Lookup(nil, name)
+	if sel == nil {
+		return false
+	}
+	sig, ok := sel.Type().(*types.Signature)
+	return ok && sig.Params().Len() == 1 && types.Identical(sig.Params().At(0).Type(), arg) &&
+		sig.Results().Len() == 1 && types.Identical(sig.Results().At(0).Type(), result)
+}
+
+// parseItem splits a tag item such as "SortedMap[string]" into its
+// template name and its bracketed parameter, if any.
+func parseItem(item string) (name, param string) {
+	i := strings.Index(item, "[")
+	if i < 0 || !strings.HasSuffix(item, "]") {
+		return item, ""
+	}
+	return item[:i], strings.TrimSpace(item[i+1 : len(item)-1])
+}
+
+// items returns the tag items to write for t, in order, with any
+// templates they are built on put ahead of them.
+func (c ContainerWriter) items(t typewriter.Type) []string {
+	var items []string
+	written := make(map[string]bool)
+	var add func(item string)
+	add = func(item string) {
+		name, _ := parseItem(item)
+		if written[name] {
+			return
+		}
+		written[name] = true
+		for _, required := range templateRequires[name] {
+			add(required)
+		}
+		items = append(items, item)
+	}
+	for _, item := range c.tagsByType[t.String()].Items {
+		add(item)
+	}
+	return items
+}
+
 func (c ContainerWriter) Name() string {
 	return "sorted_container"
 }
@@ -37,11 +132,28 @@ func (c ContainerWriter) Validate(t typewriter.Type) (bool, error) {
 	// must include at least one item that we recognize
 	any := false
 	for _, item := range tag.Items {
-		if templates.Contains(item) {
-			// found one, move on
-			any = true
-			break
+		name, param := parseItem(item)
+		if !templates.Contains(name) || templateInternal[name] {
+			continue
+		}
+		if name == "SortedMap" && param == "" {
+			return false, fmt.Errorf("%s: SortedMap needs a value type, e.g. SortedMap[string]", t.String())
+		}
+		if name == "SortedSet" && param != "" {
+			if param != "natural" {
+				return false, fmt.Errorf("%s: unknown SortedSet parameter %q, only SortedSet[natural] is supported", t.String(), param)
+			}
+			if naturalOrder(t) == "" {
+				return false, fmt.Errorf("%s: SortedSet[natural] needs %s to be an ordered builtin type (an int, float or string) or to have a Compare(%s) int or Less(%s) bool method", t.String(), t.String(), t.String(), t.String())
+			}
 		}
+		if name == "BTreeSortedSet" {
+			if _, err := parseFanout(param); err != nil {
+				return false, fmt.Errorf("%s: %v", t.String(), err)
+			}
+		}
+		// found one, move on
+		any = true
 	}
 
 	if !any {
@@ -54,8 +166,9 @@ func (c ContainerWriter) Validate(t typewriter.Type) (bool, error) {
 }
 
 func (c ContainerWriter) WriteHeader(w io.Writer, t typewriter.Type) {
-	for _, s := range c.tagsByType[t.String()].Items {
-		switch s {
+	for _, item := range c.items(t) {
+		name, _ := parseItem(item)
+		switch name {
 		case "SortedSet":
 			license := `// SortedSet is a modification of https://github.com/wfreeman/go-skiplist/sortedset.go
 // The MIT License (MIT)
@@ -67,21 +180,35 @@ func (c ContainerWriter) WriteHeader(w io.Writer, t typewriter.Type) {
 }
 
 func (c ContainerWriter) Imports(t typewriter.Type) []typewriter.ImportSpec {
-	return []typewriter.ImportSpec{
-		typewriter.ImportSpec{Path: "math"},
-		typewriter.ImportSpec{Path: "math/rand"},
+	var imports []typewriter.ImportSpec
+	seen := make(map[string]bool)
+	for _, item := range c.items(t) {
+		name, _ := parseItem(item)
+		for _, path := range templateImports[name] {
+			if !seen[path] {
+				seen[path] = true
+				imports = append(imports, typewriter.ImportSpec{Path: path})
+			}
+		}
 	}
+	return imports
 }
 
 func (c ContainerWriter) WriteBody(w io.Writer, t typewriter.Type) {
-	tag := c.tagsByType[t.String()] // validated above
+	items := c.items(t) // validated above
+	natural := naturalOrder(t)
 
-	for _, s := range tag.Items {
-		tmpl, err := templates.Get(s)
+	for _, item := range items {
+		name, param := parseItem(item)
+		tmpl, err := templates.Get(name)
 		if err != nil {
 			continue
 		}
-		tmpl.Execute(w, t)
+		m := model{Type: t, ValueType: param, Natural: natural}
+		if name == "BTreeSortedSet" {
+			m.Fanout, _ = parseFanout(param) // validated above
+		}
+		tmpl.Execute(w, m)
 	}
 
 	return
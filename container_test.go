@@ -0,0 +1,51 @@
+package container
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/clipperhouse/gen/typewriter"
+	"golang.org/x/tools/go/types"
+)
+
+// pointType returns a struct type, which has no natural order, tagged with
+// the given containers.
+func pointType(items ...string) typewriter.Type {
+	pkg := types.NewPackage("main", "main")
+	named := types.NewNamed(types.NewTypeName(0, pkg, "Point", nil), types.NewStruct(nil, nil), nil)
+	return typewriter.Type{
+		Name: "Point",
+		Type: named,
+		Tags: typewriter.Tags{{Name: "containers", Items: items}},
+	}
+}
+
+func Test_ValidateNaturalNeedsAnOrder(t *testing.T) {
+	c := NewContainerWriter()
+	ok, err := c.Validate(pointType("SortedSet[natural]"))
+	if ok || err == nil {
+		t.Fatal("SortedSet[natural] should be an error for a type with no natural order")
+	}
+	if !strings.Contains(err.Error(), "SortedSet[natural]") {
+		t.Errorf("the error should name the tag, got %q", err)
+	}
+}
+
+func Test_ValidateSortedSetLeavesOutNatural(t *testing.T) {
+	// a plain SortedSet is fine on any type, it just has no Natural constructor
+	c := NewContainerWriter()
+	pt := pointType("SortedSet")
+	ok, err := c.Validate(pt)
+	if !ok || err != nil {
+		t.Fatalf("SortedSet should be valid for a type with no natural order, got %v", err)
+	}
+	var body bytes.Buffer
+	c.WriteBody(&body, pt)
+	if !strings.Contains(body.String(), "func NewPointSortedSet(") {
+		t.Error("SortedSet should still have its constructor")
+	}
+	if strings.Contains(body.String(), "NewPointSortedSetNatural") {
+		t.Error("SortedSet should leave out NewPointSortedSetNatural for a type with no natural order")
+	}
+}
@@ -0,0 +1,658 @@
+package container
+
+import (
+	"github.com/clipperhouse/gen/typewriter"
+)
+
+var immutableSortedSet = &typewriter.Template{
+	Text: `
+
+// A sorted set that never changes once made, backed by a persistent treap.
+// With and Without return new sets that share every node off the changed
+// path with the old one, so old versions stay valid and cost nothing to
+// keep or to hand to other goroutines.
+type {{.Name}}ImmutableSortedSet struct {
+	less   func(a, b {{.Pointer}}{{.Name}}) bool
+	root   *immutableSortedSet{{.Name}}Node
+	length int
+}
+
+// the struct to hold nodes of the treap; nodes are never changed once
+// they are reachable from a set
+type immutableSortedSet{{.Name}}Node struct {
+	val         {{.Pointer}}{{.Name}}
+	priority    uint32
+	left, right *immutableSortedSet{{.Name}}Node
+}
+
+// Creates and returns an empty immutable set.
+func New{{.Name}}ImmutableSortedSet(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) {{.Name}}ImmutableSortedSet {
+	return {{.Name}}ImmutableSortedSet{less: less}
+}
+
+// Creates and returns an immutable set from a slice already sorted by
+// less, in O(n). Runs of equal items are only added once.
+func New{{.Name}}ImmutableSortedSetFromSortedSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) {{.Name}}ImmutableSortedSet {
+	is := {{.Name}}ImmutableSortedSet{less: less}
+	// build the treap left to right, keeping its right spine on a stack
+	var spine []*immutableSortedSet{{.Name}}Node
+	for i, v := range s {
+		if i > 0 && !less(s[i-1], v) {
+			// equal to the item before it
+			continue
+		}
+		n := &immutableSortedSet{{.Name}}Node{val: v, priority: rand.Uint32()}
+		var last *immutableSortedSet{{.Name}}Node
+		for len(spine) > 0 && spine[len(spine)-1].priority < n.priority {
+			last = spine[len(spine)-1]
+			spine = spine[:len(spine)-1]
+		}
+		n.left = last
+		if len(spine) > 0 {
+			spine[len(spine)-1].right = n
+		}
+		spine = append(spine, n)
+		is.length++
+	}
+	if len(spine) > 0 {
+		is.root = spine[0]
+	}
+	return is
+}
+
+// Creates and returns an immutable set from an existing slice.
+func New{{.Name}}ImmutableSortedSetFromSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) {{.Name}}ImmutableSortedSet {
+	is := New{{.Name}}ImmutableSortedSet(less)
+	for _, v := range s {
+		is = is.With(v)
+	}
+	return is
+}
+
+// With returns a set holding v as well as every item of this one, or this
+// set itself if v is already in it.
+func (is {{.Name}}ImmutableSortedSet) With(v {{.Pointer}}{{.Name}}) {{.Name}}ImmutableSortedSet {
+	root := is.with(is.root, v)
+	if root == is.root {
+		return is
+	}
+	return {{.Name}}ImmutableSortedSet{less: is.less, root: root, length: is.length + 1}
+}
+
+// with returns a copy of the path from n down to where v belongs, with v
+// added, or n itself if v is already there.
+func (is {{.Name}}ImmutableSortedSet) with(n *immutableSortedSet{{.Name}}Node, v {{.Pointer}}{{.Name}}) *immutableSortedSet{{.Name}}Node {
+	if n == nil {
+		// random priorities keep the treap balanced; the global source is
+		// used since sets can be shared between goroutines
+		return &immutableSortedSet{{.Name}}Node{val: v, priority: rand.Uint32()}
+	}
+	switch {
+	case is.less(v, n.val):
+		left := is.with(n.left, v)
+		if left == n.left {
+			return n
+		}
+		// left is a fresh copy, so it's ours to rotate
+		if left.priority > n.priority {
+			c := *n
+			c.left = left.right
+			left.right = &c
+			return left
+		}
+		c := *n
+		c.left = left
+		return &c
+	case is.less(n.val, v):
+		right := is.with(n.right, v)
+		if right == n.right {
+			return n
+		}
+		if right.priority > n.priority {
+			c := *n
+			c.right = right.left
+			right.left = &c
+			return right
+		}
+		c := *n
+		c.right = right
+		return &c
+	}
+	return n
+}
+
+// Without returns a set holding every item of this one except v, or this
+// set itself if v is not in it.
+func (is {{.Name}}ImmutableSortedSet) Without(v {{.Pointer}}{{.Name}}) {{.Name}}ImmutableSortedSet {
+	root := is.without(is.root, v)
+	if root == is.root {
+		return is
+	}
+	return {{.Name}}ImmutableSortedSet{less: is.less, root: root, length: is.length - 1}
+}
+
+// without returns a copy of the path from n down to v, with v removed,
+// or n itself if v is not there.
+func (is {{.Name}}ImmutableSortedSet) without(n *immutableSortedSet{{.Name}}Node, v {{.Pointer}}{{.Name}}) *immutableSortedSet{{.Name}}Node {
+	if n == nil {
+		return nil
+	}
+	switch {
+	case is.less(v, n.val):
+		left := is.without(n.left, v)
+		if left == n.left {
+			return n
+		}
+		c := *n
+		c.left = left
+		return &c
+	case is.less(n.val, v):
+		right := is.without(n.right, v)
+		if right == n.right {
+			return n
+		}
+		c := *n
+		c.right = right
+		return &c
+	}
+	return mergeImmutableSortedSet{{.Name}}Nodes(n.left, n.right)
+}
+
+// mergeImmutableSortedSet{{.Name}}Nodes joins two treaps, every item of a
+// being less than every item of b, copying only the nodes along the seam.
+func mergeImmutableSortedSet{{.Name}}Nodes(a, b *immutableSortedSet{{.Name}}Node) *immutableSortedSet{{.Name}}Node {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.priority > b.priority {
+		c := *a
+		c.right = mergeImmutableSortedSet{{.Name}}Nodes(a.right, b)
+		return &c
+	}
+	c := *b
+	c.left = mergeImmutableSortedSet{{.Name}}Nodes(a, b.left)
+	return &c
+}
+
+// Determines if a given item is in the set.
+func (is {{.Name}}ImmutableSortedSet) Contains(v {{.Pointer}}{{.Name}}) bool {
+	n := is.root
+	for n != nil {
+		switch {
+		case is.less(v, n.val):
+			n = n.left
+		case is.less(n.val, v):
+			n = n.right
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Cardinality returns how many items are in the set.
+func (is {{.Name}}ImmutableSortedSet) Cardinality() int {
+	return is.length
+}
+
+// Len returns how many items are in the set, like Cardinality.
+func (is {{.Name}}ImmutableSortedSet) Len() int {
+	return is.length
+}
+
+// First returns the least item in the set.
+// ok is false if the set is empty.
+func (is {{.Name}}ImmutableSortedSet) First() (val {{.Pointer}}{{.Name}}, ok bool) {
+	n := is.root
+	for n != nil && n.left != nil {
+		n = n.left
+	}
+	if n != nil {
+		return n.val, true
+	}
+	return
+}
+
+// Last returns the greatest item in the set.
+// ok is false if the set is empty.
+func (is {{.Name}}ImmutableSortedSet) Last() (val {{.Pointer}}{{.Name}}, ok bool) {
+	n := is.root
+	for n != nil && n.right != nil {
+		n = n.right
+	}
+	if n != nil {
+		return n.val, true
+	}
+	return
+}
+
+// floor returns the greatest item in the set less than or equal to v, or
+// if strict, less than v.
+func (is {{.Name}}ImmutableSortedSet) floor(v {{.Pointer}}{{.Name}}, strict bool) (val {{.Pointer}}{{.Name}}, ok bool) {
+	for n := is.root; n != nil; {
+		switch {
+		case is.less(n.val, v):
+			// a candidate, but there may be a greater one to the right
+			val, ok = n.val, true
+			n = n.right
+		case is.less(v, n.val) || strict:
+			n = n.left
+		default:
+			return n.val, true
+		}
+	}
+	return
+}
+
+// ceiling returns the least item in the set greater than or equal to v, or
+// if strict, greater than v.
+func (is {{.Name}}ImmutableSortedSet) ceiling(v {{.Pointer}}{{.Name}}, strict bool) (val {{.Pointer}}{{.Name}}, ok bool) {
+	for n := is.root; n != nil; {
+		switch {
+		case is.less(v, n.val):
+			// a candidate, but there may be a lesser one to the left
+			val, ok = n.val, true
+			n = n.left
+		case is.less(n.val, v) || strict:
+			n = n.right
+		default:
+			return n.val, true
+		}
+	}
+	return
+}
+
+// Floor returns the greatest item in the set less than or equal to v.
+// ok is false if there is no such item.
+func (is {{.Name}}ImmutableSortedSet) Floor(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return is.floor(v, false)
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+// ok is false if there is no such item.
+func (is {{.Name}}ImmutableSortedSet) Ceiling(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return is.ceiling(v, false)
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+// ok is false if there is no such item.
+func (is {{.Name}}ImmutableSortedSet) Lower(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return is.floor(v, true)
+}
+
+// Higher returns the least item in the set strictly greater than v.
+// ok is false if there is no such item.
+func (is {{.Name}}ImmutableSortedSet) Higher(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return is.ceiling(v, true)
+}
+
+// A read-only view of the items of a {{.Name}}ImmutableSortedSet between
+// two bounds. The set never changes, so neither does the view.
+type {{.Name}}ImmutableSortedSetView struct {
+	is                       {{.Name}}ImmutableSortedSet
+	lo, hi                   {{.Pointer}}{{.Name}}
+	hasLo, hasHi             bool
+	loInclusive, hiInclusive bool
+}
+
+// Range returns a view of the items between lo and hi, each bound being
+// included or excluded as requested.
+func (is {{.Name}}ImmutableSortedSet) Range(lo, hi {{.Pointer}}{{.Name}}, loInclusive, hiInclusive bool) {{.Name}}ImmutableSortedSetView {
+	return {{.Name}}ImmutableSortedSetView{is: is, lo: lo, hi: hi, hasLo: true, hasHi: true, loInclusive: loInclusive, hiInclusive: hiInclusive}
+}
+
+// HeadSet returns a view of the items strictly less than hi.
+func (is {{.Name}}ImmutableSortedSet) HeadSet(hi {{.Pointer}}{{.Name}}) {{.Name}}ImmutableSortedSetView {
+	return {{.Name}}ImmutableSortedSetView{is: is, hi: hi, hasHi: true}
+}
+
+// TailSet returns a view of the items greater than or equal to lo.
+func (is {{.Name}}ImmutableSortedSet) TailSet(lo {{.Pointer}}{{.Name}}) {{.Name}}ImmutableSortedSetView {
+	return {{.Name}}ImmutableSortedSetView{is: is, lo: lo, hasLo: true, loInclusive: true}
+}
+
+// Each calls f for every item in the view in ascending order, stopping
+// early if f returns false.
+func (sv {{.Name}}ImmutableSortedSetView) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	it := sv.is.Iterator()
+	var ok bool
+	if !sv.hasLo {
+		ok = it.Next()
+	} else if ok = it.Seek(sv.lo); ok && !sv.loInclusive && !sv.is.less(sv.lo, it.Value()) {
+		ok = it.Next()
+	}
+	for ; ok; ok = it.Next() {
+		v := it.Value()
+		if sv.hasHi && (sv.is.less(sv.hi, v) || !sv.hiInclusive && !sv.is.less(v, sv.hi)) {
+			return
+		}
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// Cardinality returns how many items are in the view.
+// Unlike the set's, this walks every item in the view.
+func (sv {{.Name}}ImmutableSortedSetView) Cardinality() int {
+	n := 0
+	sv.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// ToSlice returns the items in the view in ascending order.
+func (sv {{.Name}}ImmutableSortedSetView) ToSlice() []{{.Pointer}}{{.Name}} {
+	var s []{{.Pointer}}{{.Name}}
+	sv.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// ToSet returns the items in the view as a set of their own, in O(k).
+func (sv {{.Name}}ImmutableSortedSetView) ToSet() {{.Name}}ImmutableSortedSet {
+	return New{{.Name}}ImmutableSortedSetFromSortedSlice(sv.is.less, sv.ToSlice())
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (is {{.Name}}ImmutableSortedSet) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	it := is.Iterator()
+	for it.Next() {
+		if !f(it.Value()) {
+			return
+		}
+	}
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+func (is {{.Name}}ImmutableSortedSet) ReverseEach(f func(v {{.Pointer}}{{.Name}}) bool) {
+	it := is.ReverseIterator()
+	for it.Prev() {
+		if !f(it.Value()) {
+			return
+		}
+	}
+}
+
+// ToSlice returns the items in the set in ascending order.
+func (is {{.Name}}ImmutableSortedSet) ToSlice() []{{.Pointer}}{{.Name}} {
+	s := make([]{{.Pointer}}{{.Name}}, 0, is.length)
+	is.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// ToBuilder returns a builder starting out with the items of this set.
+func (is {{.Name}}ImmutableSortedSet) ToBuilder() *{{.Name}}ImmutableSortedSetBuilder {
+	return &{{.Name}}ImmutableSortedSetBuilder{set: is}
+}
+
+// A cursor over the items of a {{.Name}}ImmutableSortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next, Prev or Seek has returned true.
+type {{.Name}}ImmutableSortedSetIterator struct {
+	is      {{.Name}}ImmutableSortedSet
+	stack   []*immutableSortedSet{{.Name}}Node // the path from the root to the node at the cursor
+	started bool                               // with an empty stack, whether the cursor is past the last item rather than before the first
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (is {{.Name}}ImmutableSortedSet) Iterator() *{{.Name}}ImmutableSortedSetIterator {
+	return &{{.Name}}ImmutableSortedSetIterator{is: is}
+}
+
+// ReverseIterator returns a cursor positioned after the last item of the
+// set, to be walked from largest to smallest with Prev.
+func (is {{.Name}}ImmutableSortedSet) ReverseIterator() *{{.Name}}ImmutableSortedSetIterator {
+	return &{{.Name}}ImmutableSortedSetIterator{is: is, started: true}
+}
+
+// pushFirst extends the path down to the least item under n.
+func (it *{{.Name}}ImmutableSortedSetIterator) pushFirst(n *immutableSortedSet{{.Name}}Node) {
+	for ; n != nil; n = n.left {
+		it.stack = append(it.stack, n)
+	}
+}
+
+// pushLast extends the path down to the greatest item under n.
+func (it *{{.Name}}ImmutableSortedSetIterator) pushLast(n *immutableSortedSet{{.Name}}Node) {
+	for ; n != nil; n = n.right {
+		it.stack = append(it.stack, n)
+	}
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *{{.Name}}ImmutableSortedSetIterator) Next() bool {
+	if len(it.stack) == 0 {
+		if it.started || it.is.root == nil {
+			it.started = true
+			return false
+		}
+		it.started = true
+		it.pushFirst(it.is.root)
+		return true
+	}
+	if n := it.stack[len(it.stack)-1]; n.right != nil {
+		it.pushFirst(n.right)
+		return true
+	}
+	// climb to the nearest node whose left subtree the cursor was in
+	for {
+		child := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			return false
+		}
+		if it.stack[len(it.stack)-1].left == child {
+			return true
+		}
+	}
+}
+
+// Prev moves the cursor to the previous item, returning false once it has
+// moved before the first one.
+func (it *{{.Name}}ImmutableSortedSetIterator) Prev() bool {
+	if len(it.stack) == 0 {
+		if !it.started || it.is.root == nil {
+			it.started = false
+			return false
+		}
+		it.pushLast(it.is.root)
+		return true
+	}
+	if n := it.stack[len(it.stack)-1]; n.left != nil {
+		it.pushLast(n.left)
+		return true
+	}
+	// climb to the nearest node whose right subtree the cursor was in
+	for {
+		child := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			it.started = false
+			return false
+		}
+		if it.stack[len(it.stack)-1].right == child {
+			return true
+		}
+	}
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *{{.Name}}ImmutableSortedSetIterator) Seek(v {{.Pointer}}{{.Name}}) bool {
+	it.stack = it.stack[:0]
+	it.started = true
+	// the length of the path down to the least item seen greater than v
+	ceiling := 0
+	for n := it.is.root; n != nil; {
+		it.stack = append(it.stack, n)
+		switch {
+		case it.is.less(v, n.val):
+			ceiling = len(it.stack)
+			n = n.left
+		case it.is.less(n.val, v):
+			n = n.right
+		default:
+			return true
+		}
+	}
+	it.stack = it.stack[:ceiling]
+	return ceiling > 0
+}
+
+// Value returns the item at the cursor.
+func (it *{{.Name}}ImmutableSortedSetIterator) Value() {{.Pointer}}{{.Name}} {
+	return it.stack[len(it.stack)-1].val
+}
+
+// A mutable sorted set whose current contents can be taken as a
+// {{.Name}}ImmutableSortedSet in O(1), at any time and as often as needed.
+// Changes made afterwards copy only the nodes they touch, so they never
+// show up in snapshots already taken. Use it in place of a
+// {{.Name}}SortedSet wherever readers need snapshots; a SortedSet can only
+// hand them a Clone, which copies every item.
+type {{.Name}}ImmutableSortedSetBuilder struct {
+	set {{.Name}}ImmutableSortedSet
+}
+
+// Creates and returns a reference to an empty builder.
+func New{{.Name}}ImmutableSortedSetBuilder(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) *{{.Name}}ImmutableSortedSetBuilder {
+	return &{{.Name}}ImmutableSortedSetBuilder{set: New{{.Name}}ImmutableSortedSet(less)}
+}
+
+// Adds an item to the builder if it isn't already in it.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Add(v {{.Pointer}}{{.Name}}) bool {
+	before := b.set.length
+	b.set = b.set.With(v)
+	return b.set.length != before
+}
+
+// Allows the removal of a single item in the builder.
+// Returns true if the item was in it.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Remove(v {{.Pointer}}{{.Name}}) bool {
+	before := b.set.length
+	b.set = b.set.Without(v)
+	return b.set.length != before
+}
+
+// Determines if a given item is in the builder.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Contains(v {{.Pointer}}{{.Name}}) bool {
+	return b.set.Contains(v)
+}
+
+// Removes every item from the builder, leaving snapshots as they were.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Clear() {
+	b.set = New{{.Name}}ImmutableSortedSet(b.set.less)
+}
+
+// Cardinality returns how many items are in the builder.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Cardinality() int {
+	return b.set.length
+}
+
+// Len returns how many items are in the builder, like Cardinality.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Len() int {
+	return b.set.length
+}
+
+// First returns the least item in the builder.
+// ok is false if the builder is empty.
+func (b *{{.Name}}ImmutableSortedSetBuilder) First() (val {{.Pointer}}{{.Name}}, ok bool) {
+	return b.set.First()
+}
+
+// Last returns the greatest item in the builder.
+// ok is false if the builder is empty.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Last() (val {{.Pointer}}{{.Name}}, ok bool) {
+	return b.set.Last()
+}
+
+// Floor returns the greatest item in the builder less than or equal to v.
+// ok is false if there is no such item.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Floor(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return b.set.Floor(v)
+}
+
+// Ceiling returns the least item in the builder greater than or equal to v.
+// ok is false if there is no such item.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Ceiling(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return b.set.Ceiling(v)
+}
+
+// Lower returns the greatest item in the builder strictly less than v.
+// ok is false if there is no such item.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Lower(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return b.set.Lower(v)
+}
+
+// Higher returns the least item in the builder strictly greater than v.
+// ok is false if there is no such item.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Higher(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return b.set.Higher(v)
+}
+
+// Range returns a view of the builder's current items between lo and hi,
+// each bound being included or excluded as requested. Later changes to the
+// builder don't show up in the view.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Range(lo, hi {{.Pointer}}{{.Name}}, loInclusive, hiInclusive bool) {{.Name}}ImmutableSortedSetView {
+	return b.set.Range(lo, hi, loInclusive, hiInclusive)
+}
+
+// HeadSet returns a view of the builder's current items strictly less than hi.
+func (b *{{.Name}}ImmutableSortedSetBuilder) HeadSet(hi {{.Pointer}}{{.Name}}) {{.Name}}ImmutableSortedSetView {
+	return b.set.HeadSet(hi)
+}
+
+// TailSet returns a view of the builder's current items greater than or equal to lo.
+func (b *{{.Name}}ImmutableSortedSetBuilder) TailSet(lo {{.Pointer}}{{.Name}}) {{.Name}}ImmutableSortedSetView {
+	return b.set.TailSet(lo)
+}
+
+// Each calls f for every item in ascending order, stopping early if f
+// returns false. f may change the builder; the walk carries on over the
+// items as they were when it started.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	b.set.Each(f)
+}
+
+// ReverseEach calls f for every item in descending order, stopping early
+// if f returns false. Like Each, f may change the builder.
+func (b *{{.Name}}ImmutableSortedSetBuilder) ReverseEach(f func(v {{.Pointer}}{{.Name}}) bool) {
+	b.set.ReverseEach(f)
+}
+
+// Iterator returns a cursor positioned before the first of the builder's
+// current items. It walks a snapshot, so later changes to the builder
+// neither show up in it nor invalidate it.
+func (b *{{.Name}}ImmutableSortedSetBuilder) Iterator() *{{.Name}}ImmutableSortedSetIterator {
+	return b.set.Iterator()
+}
+
+// ReverseIterator returns a cursor positioned after the last of the
+// builder's current items, to be walked from largest to smallest with Prev.
+func (b *{{.Name}}ImmutableSortedSetBuilder) ReverseIterator() *{{.Name}}ImmutableSortedSetIterator {
+	return b.set.ReverseIterator()
+}
+
+// ToSlice returns the items in the builder in ascending order.
+func (b *{{.Name}}ImmutableSortedSetBuilder) ToSlice() []{{.Pointer}}{{.Name}} {
+	return b.set.ToSlice()
+}
+
+// Snapshot returns the builder's current items as an immutable set, in O(1).
+func (b *{{.Name}}ImmutableSortedSetBuilder) Snapshot() {{.Name}}ImmutableSortedSet {
+	return b.set
+}
+`,
+}
@@ -0,0 +1,309 @@
+package container
+
+import (
+	"github.com/clipperhouse/gen/typewriter"
+)
+
+var lockFreeSortedSet = &typewriter.Template{
+	Text: `
+
+// A sorted set that is safe to share between goroutines without locking,
+// backed by a lock-free skiplist (Herlihy and Shavit's, after Fraser).
+// An item is removed by first marking its element's links as deleted,
+// which is what makes it gone, and then unlinking it; any goroutine
+// that comes across a marked element helps unlink it.
+// Iteration is weakly consistent: it sees every item present for the whole
+// walk and may or may not see items added or removed during it.
+type {{.Name}}LockFreeSortedSet struct {
+	less   func(a, b {{.Pointer}}{{.Name}}) bool
+	head   *lockFreeSortedSet{{.Name}}Element // sentinel before the first item
+	length atomic.Int64
+	level  atomic.Int32 // how many levels have ever had elements on them; it never goes down
+}
+
+// the most levels an element can be on, enough for 2^64 items
+const lockFreeSortedSet{{.Name}}MaxLevels = 64
+
+// the struct to hold elements of the skiplist
+type lockFreeSortedSet{{.Name}}Element struct {
+	val  {{.Pointer}}{{.Name}}
+	next []atomic.Pointer[lockFreeSortedSet{{.Name}}Link]
+}
+
+// a link to the next element on one level, together with whether the
+// element holding the link has been removed. Links are never changed,
+// only swapped, so the two always change together.
+type lockFreeSortedSet{{.Name}}Link struct {
+	next   *lockFreeSortedSet{{.Name}}Element
+	marked bool
+}
+
+// Creates and returns a reference to an empty lock-free set.
+func New{{.Name}}LockFreeSortedSet(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) *{{.Name}}LockFreeSortedSet {
+	var zero {{.Pointer}}{{.Name}}
+	ls := &{{.Name}}LockFreeSortedSet{
+		less: less,
+		head: newLockFreeSortedSet{{.Name}}Element(zero, lockFreeSortedSet{{.Name}}MaxLevels, nil),
+	}
+	ls.level.Store(1)
+	return ls
+}
+
+func newLockFreeSortedSet{{.Name}}Element(v {{.Pointer}}{{.Name}}, levels int, succs []*lockFreeSortedSet{{.Name}}Element) *lockFreeSortedSet{{.Name}}Element {
+	e := &lockFreeSortedSet{{.Name}}Element{val: v, next: make([]atomic.Pointer[lockFreeSortedSet{{.Name}}Link], levels)}
+	for level := range e.next {
+		var next *lockFreeSortedSet{{.Name}}Element
+		if succs != nil {
+			next = succs[level]
+		}
+		e.next[level].Store(&lockFreeSortedSet{{.Name}}Link{next: next})
+	}
+	return e
+}
+
+// randomLevels uses the global source from math/rand, which is safe to
+// use from many goroutines at once. Levels are capped a few above the
+// log2(n) a set of n items needs, as in {{.Name}}SortedSet.
+func (ls *{{.Name}}LockFreeSortedSet) randomLevels() int {
+	limit := bits.Len(uint(ls.Len())) + 4
+	if limit > lockFreeSortedSet{{.Name}}MaxLevels {
+		limit = lockFreeSortedSet{{.Name}}MaxLevels
+	}
+	level := int(math.Log(1.0-rand.Float64()) / math.Log(0.5))
+	if level >= limit {
+		level = limit
+	}
+	if level == 0 {
+		level++
+	}
+	return level
+}
+
+// raise makes sure searches start at least as high as the given number of
+// levels. It's called before an element on that many levels is linked, so
+// any search that can see the element goes through all its levels.
+func (ls *{{.Name}}LockFreeSortedSet) raise(levels int) {
+	for {
+		level := ls.level.Load()
+		if int(level) >= levels || ls.level.CompareAndSwap(level, int32(levels)) {
+			return
+		}
+	}
+}
+
+// find fills preds and succs with the elements either side of where v
+// belongs on every level in use, unlinking any marked elements it passes.
+// Returns whether succs[0] holds v.
+func (ls *{{.Name}}LockFreeSortedSet) find(v {{.Pointer}}{{.Name}}, preds, succs []*lockFreeSortedSet{{.Name}}Element) bool {
+retry:
+	for {
+		pred := ls.head
+		var curr *lockFreeSortedSet{{.Name}}Element
+		for level := int(ls.level.Load()) - 1; level >= 0; level-- {
+			curr = pred.next[level].Load().next
+			for curr != nil {
+				link := curr.next[level].Load()
+				for link.marked {
+					// curr is removed, try to unlink it from pred
+					predLink := pred.next[level].Load()
+					if predLink.marked || predLink.next != curr ||
+						!pred.next[level].CompareAndSwap(predLink, &lockFreeSortedSet{{.Name}}Link{next: link.next}) {
+						continue retry
+					}
+					curr = link.next
+					if curr == nil {
+						break
+					}
+					link = curr.next[level].Load()
+				}
+				if curr == nil || !ls.less(curr.val, v) {
+					break
+				}
+				pred = curr
+				curr = link.next
+			}
+			preds[level] = pred
+			succs[level] = curr
+		}
+		return curr != nil && !ls.less(v, curr.val)
+	}
+}
+
+// Adds an item to the set if it doesn't already exist in the set.
+func (ls *{{.Name}}LockFreeSortedSet) Add(v {{.Pointer}}{{.Name}}) bool {
+	// arrays rather than slices, so they stay on the stack
+	var preds, succs [lockFreeSortedSet{{.Name}}MaxLevels]*lockFreeSortedSet{{.Name}}Element
+	levels := ls.randomLevels()
+	ls.raise(levels)
+	for {
+		if ls.find(v, preds[:], succs[:]) {
+			return false
+		}
+		e := newLockFreeSortedSet{{.Name}}Element(v, levels, succs[:])
+
+		// the item is in the set once it is linked on the bottom level
+		predLink := preds[0].next[0].Load()
+		if predLink.marked || predLink.next != succs[0] ||
+			!preds[0].next[0].CompareAndSwap(predLink, &lockFreeSortedSet{{.Name}}Link{next: e}) {
+			continue
+		}
+		ls.length.Add(1)
+
+		// the levels above are only shortcuts, link them as we can
+		for level := 1; level < levels; level++ {
+			for {
+				link := e.next[level].Load()
+				if link.marked {
+					// already being removed, no point linking it further
+					return true
+				}
+				if link.next != succs[level] &&
+					!e.next[level].CompareAndSwap(link, &lockFreeSortedSet{{.Name}}Link{next: succs[level]}) {
+					continue
+				}
+				predLink := preds[level].next[level].Load()
+				if !predLink.marked && predLink.next == succs[level] &&
+					preds[level].next[level].CompareAndSwap(predLink, &lockFreeSortedSet{{.Name}}Link{next: e}) {
+					break
+				}
+				ls.find(v, preds[:], succs[:])
+			}
+		}
+		return true
+	}
+}
+
+// Allows the removal of a single item in the set.
+// Returns true if this call removed the item.
+func (ls *{{.Name}}LockFreeSortedSet) Remove(v {{.Pointer}}{{.Name}}) bool {
+	var preds, succs [lockFreeSortedSet{{.Name}}MaxLevels]*lockFreeSortedSet{{.Name}}Element
+	if !ls.find(v, preds[:], succs[:]) {
+		return false
+	}
+	e := succs[0]
+
+	// mark the levels above first, so nobody links e any higher
+	for level := len(e.next) - 1; level >= 1; level-- {
+		link := e.next[level].Load()
+		for !link.marked {
+			e.next[level].CompareAndSwap(link, &lockFreeSortedSet{{.Name}}Link{next: link.next, marked: true})
+			link = e.next[level].Load()
+		}
+	}
+
+	// whoever marks the bottom level removes the item
+	for {
+		link := e.next[0].Load()
+		if link.marked {
+			return false
+		}
+		if e.next[0].CompareAndSwap(link, &lockFreeSortedSet{{.Name}}Link{next: link.next, marked: true}) {
+			ls.length.Add(-1)
+			// unlink it
+			ls.find(v, preds[:], succs[:])
+			return true
+		}
+	}
+}
+
+// Determines if a given item is in the set. It never waits on other
+// goroutines, and leaves unlinking marked elements to Add and Remove.
+func (ls *{{.Name}}LockFreeSortedSet) Contains(v {{.Pointer}}{{.Name}}) bool {
+	pred := ls.head
+	var curr *lockFreeSortedSet{{.Name}}Element
+	for level := int(ls.level.Load()) - 1; level >= 0; level-- {
+		curr = pred.next[level].Load().next
+		for curr != nil {
+			link := curr.next[level].Load()
+			if link.marked {
+				// step over removed elements
+				curr = link.next
+				continue
+			}
+			if !ls.less(curr.val, v) {
+				break
+			}
+			pred = curr
+			curr = link.next
+		}
+	}
+	return curr != nil && !ls.less(v, curr.val) && !curr.next[0].Load().marked
+}
+
+// Cardinality returns how many items are currently in the set.
+// While other goroutines are changing the set it is only approximate: a
+// Remove can count its item out before the Add that put it in counts it
+// in, so it is clamped at 0.
+func (ls *{{.Name}}LockFreeSortedSet) Cardinality() int {
+	if n := ls.length.Load(); n > 0 {
+		return int(n)
+	}
+	return 0
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (ls *{{.Name}}LockFreeSortedSet) Len() int {
+	return ls.Cardinality()
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (ls *{{.Name}}LockFreeSortedSet) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	it := ls.Iterator()
+	for it.Next() {
+		if !f(it.Value()) {
+			return
+		}
+	}
+}
+
+// ToSlice returns the items in the set in ascending order.
+func (ls *{{.Name}}LockFreeSortedSet) ToSlice() []{{.Pointer}}{{.Name}} {
+	var s []{{.Pointer}}{{.Name}}
+	ls.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// A cursor over the items of a {{.Name}}LockFreeSortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next or Seek has returned true.
+type {{.Name}}LockFreeSortedSetIterator struct {
+	ls *{{.Name}}LockFreeSortedSet
+	e  *lockFreeSortedSet{{.Name}}Element
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (ls *{{.Name}}LockFreeSortedSet) Iterator() *{{.Name}}LockFreeSortedSetIterator {
+	return &{{.Name}}LockFreeSortedSetIterator{ls: ls, e: ls.head}
+}
+
+// Next moves the cursor to the next item still in the set, returning false
+// once it has moved past the last one.
+func (it *{{.Name}}LockFreeSortedSetIterator) Next() bool {
+	if it.e == nil {
+		return false
+	}
+	it.e = it.e.next[0].Load().next
+	for it.e != nil && it.e.next[0].Load().marked {
+		it.e = it.e.next[0].Load().next
+	}
+	return it.e != nil
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *{{.Name}}LockFreeSortedSetIterator) Seek(v {{.Pointer}}{{.Name}}) bool {
+	var preds, succs [lockFreeSortedSet{{.Name}}MaxLevels]*lockFreeSortedSet{{.Name}}Element
+	it.ls.find(v, preds[:], succs[:])
+	it.e = preds[0]
+	return it.Next()
+}
+
+// Value returns the item at the cursor.
+func (it *{{.Name}}LockFreeSortedSetIterator) Value() {{.Pointer}}{{.Name}} {
+	return it.e.val
+}
+`,
+}
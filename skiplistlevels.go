@@ -0,0 +1,53 @@
+package container
+
+import (
+	"github.com/clipperhouse/gen/typewriter"
+)
+
+var skiplistLevels = &typewriter.Template{
+	Text: `
+
+// the height bookkeeping shared by the skiplists behind {{.Name}}SortedSet,
+// {{.Name}}SortedMap, {{.Name}}SortedMultiSet and {{.Name}}SortedList
+type skiplist{{.Name}}Levels struct {
+	level     int // how many levels have any elements on them
+	maxLevels int
+	p         float64
+	r         *rand.Rand
+}
+
+func newSkiplist{{.Name}}Levels() skiplist{{.Name}}Levels {
+	return skiplist{{.Name}}Levels{
+		maxLevels: 64,
+		p:         0.5,
+		r:         rand.New(rand.NewSource(123123)),
+	}
+}
+
+// randomLevels picks how many levels a new element is on, in a skiplist of
+// n items. Levels are capped a few above the log2(n) such a skiplist needs,
+// so an unlucky run can't make a tower that every search has to climb down.
+func (lv *skiplist{{.Name}}Levels) randomLevels(n int) int {
+	limit := bits.Len(uint(n)) + 4
+	if limit > lv.maxLevels {
+		limit = lv.maxLevels
+	}
+	level := int(math.Log(1.0-lv.r.Float64()) / math.Log(lv.p))
+	if level >= limit {
+		level = limit
+	}
+	if level == 0 {
+		level++
+	}
+	return level
+}
+
+// raise makes searches start high enough to see an element on the given
+// number of levels.
+func (lv *skiplist{{.Name}}Levels) raise(levels int) {
+	if levels > lv.level {
+		lv.level = levels
+	}
+}
+`,
+}
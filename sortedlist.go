@@ -0,0 +1,257 @@
+package container
+
+import (
+	"github.com/clipperhouse/gen/typewriter"
+)
+
+var sortedList = &typewriter.Template{
+	Text: `
+
+// A sorted list of {{.Pointer}}{{.Name}} that can be indexed by position,
+// backed by an indexable skiplist.
+// Equal items are all kept, in the order they were added.
+type {{.Name}}SortedList struct {
+	less   func(a, b {{.Pointer}}{{.Name}}) bool
+	head   *sortedList{{.Name}}Element // sentinel before the first item
+	length int
+	skiplist{{.Name}}Levels
+	update []*sortedList{{.Name}}Element // scratch backPointer for Add and the removes
+	pos    []int                         // scratch positions of update for Add
+}
+
+// the struct to hold elements of the skiplist; width[level] is how many
+// items next[level] is ahead of this element, counting a nil next as one
+// past the last item. Only the widths below the list's level are kept up.
+type sortedList{{.Name}}Element struct {
+	val   {{.Pointer}}{{.Name}}
+	next  []*sortedList{{.Name}}Element
+	width []int
+}
+
+// Creates and returns a reference to an empty list.
+func New{{.Name}}SortedList(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) *{{.Name}}SortedList {
+	var zero {{.Pointer}}{{.Name}}
+	sl := &{{.Name}}SortedList{
+		less:                    less,
+		skiplist{{.Name}}Levels: newSkiplist{{.Name}}Levels(),
+	}
+	sl.head = newSortedList{{.Name}}Element(zero, sl.maxLevels)
+	sl.update = make([]*sortedList{{.Name}}Element, sl.maxLevels)
+	sl.pos = make([]int, sl.maxLevels)
+	// the bottom level is always searched, even with nothing on it
+	sl.head.width[0] = 1
+	sl.raise(1)
+	return sl
+}
+
+func newSortedList{{.Name}}Element(v {{.Pointer}}{{.Name}}, levels int) *sortedList{{.Name}}Element {
+	return &sortedList{{.Name}}Element{v, make([]*sortedList{{.Name}}Element, levels), make([]int, levels)}
+}
+
+// updateVector returns the list's scratch backPointer, cleared, so that Add
+// and the removes don't allocate one each call. Only methods that change the
+// list may use it, so that many goroutines can read the list at once.
+func (sl *{{.Name}}SortedList) updateVector() []*sortedList{{.Name}}Element {
+	for i := range sl.update {
+		sl.update[i] = nil
+	}
+	return sl.update
+}
+
+// seek descends the skiplist recording in backPointer, unless it is nil, the
+// last element on every level that is less than v (or, if after is true, not
+// greater than v), and in pos, unless it is nil, how many items come before
+// each. It returns the index of the item after the element found on the
+// bottom level, and that element.
+func (sl *{{.Name}}SortedList) seek(v {{.Pointer}}{{.Name}}, after bool, backPointer []*sortedList{{.Name}}Element, pos []int) (int, *sortedList{{.Name}}Element) {
+	e := sl.head
+	i := 0
+	for level := sl.level - 1; level >= 0; level-- {
+		for next := e.next[level]; next != nil; next = e.next[level] {
+			// if inspected val is past v, go down a level
+			if after && sl.less(v, next.val) || !after && !sl.less(next.val, v) {
+				break
+			}
+			i += e.width[level]
+			e = next
+		}
+		if backPointer != nil {
+			backPointer[level] = e
+		}
+		if pos != nil {
+			pos[level] = i
+		}
+	}
+	return i, e
+}
+
+// seekIndex is like seek, but stops before the item at index i.
+func (sl *{{.Name}}SortedList) seekIndex(i int, backPointer []*sortedList{{.Name}}Element) *sortedList{{.Name}}Element {
+	e := sl.head
+	pos := 0
+	for level := sl.level - 1; level >= 0; level-- {
+		for e.next[level] != nil && pos+e.width[level] <= i {
+			pos += e.width[level]
+			e = e.next[level]
+		}
+		if backPointer != nil {
+			backPointer[level] = e
+		}
+	}
+	return e
+}
+
+func (sl *{{.Name}}SortedList) equal(a, b {{.Pointer}}{{.Name}}) bool {
+	return !sl.less(a, b) && !sl.less(b, a)
+}
+
+// Adds an item to the list, after any equal items already in it.
+func (sl *{{.Name}}SortedList) Add(v {{.Pointer}}{{.Name}}) {
+	// create new element, which will sit at index i
+	e := newSortedList{{.Name}}Element(v, sl.randomLevels(sl.length))
+
+	// levels coming into use start out with head spanning the whole list
+	for level := sl.level; level < len(e.next); level++ {
+		sl.head.width[level] = sl.length + 1
+	}
+	sl.raise(len(e.next))
+
+	backPointer, pos := sl.updateVector(), sl.pos
+	i, _ := sl.seek(v, true, backPointer, pos)
+
+	// connect new element up with backPointer, splitting the widths it lands in
+	for level := 0; level < sl.level; level++ {
+		b := backPointer[level]
+		if level < len(e.next) {
+			e.next[level] = b.next[level]
+			b.next[level] = e
+			e.width[level] = b.width[level] - (i - pos[level])
+			b.width[level] = i + 1 - pos[level]
+		} else {
+			b.width[level]++
+		}
+	}
+
+	sl.length++
+}
+
+// unlink removes the element after backPointer on the bottom level.
+func (sl *{{.Name}}SortedList) unlink(backPointer []*sortedList{{.Name}}Element) {
+	e := backPointer[0].next[0]
+	for level := 0; level < sl.level; level++ {
+		b := backPointer[level]
+		if level < len(e.next) {
+			b.width[level] += e.width[level] - 1
+			b.next[level] = e.next[level]
+		} else {
+			b.width[level]--
+		}
+	}
+	// drop the height to the highest level that still has elements
+	for sl.level > 1 && sl.head.next[sl.level-1] == nil {
+		sl.level--
+	}
+	sl.length--
+}
+
+// Remove removes the earliest added copy of an item.
+// Returns true if there was a copy to remove.
+func (sl *{{.Name}}SortedList) Remove(v {{.Pointer}}{{.Name}}) bool {
+	backPointer := sl.updateVector()
+	_, b := sl.seek(v, false, backPointer, nil)
+	if e := b.next[0]; e == nil || !sl.equal(v, e.val) {
+		return false
+	}
+	sl.unlink(backPointer)
+	return true
+}
+
+// RemoveAt removes and returns the item at index i.
+// Panics if i is out of range.
+func (sl *{{.Name}}SortedList) RemoveAt(i int) {{.Pointer}}{{.Name}} {
+	if i < 0 || i >= sl.length {
+		panic("{{.Name}}SortedList: index out of range")
+	}
+	backPointer := sl.updateVector()
+	v := sl.seekIndex(i, backPointer).next[0].val
+	sl.unlink(backPointer)
+	return v
+}
+
+// At returns the item at index i, where index 0 is the smallest item.
+// Panics if i is out of range.
+func (sl *{{.Name}}SortedList) At(i int) {{.Pointer}}{{.Name}} {
+	if i < 0 || i >= sl.length {
+		panic("{{.Name}}SortedList: index out of range")
+	}
+	e := sl.head
+	pos := 0
+	for level := sl.level - 1; level >= 0; level-- {
+		for e.next[level] != nil && pos+e.width[level] <= i+1 {
+			pos += e.width[level]
+			e = e.next[level]
+		}
+	}
+	return e.val
+}
+
+// Rank returns how many items in the list are less than v.
+func (sl *{{.Name}}SortedList) Rank(v {{.Pointer}}{{.Name}}) int {
+	i, _ := sl.seek(v, false, nil, nil)
+	return i
+}
+
+// IndexOf returns the index of the earliest added copy of v, or -1 if
+// v is not in the list.
+func (sl *{{.Name}}SortedList) IndexOf(v {{.Pointer}}{{.Name}}) int {
+	i, b := sl.seek(v, false, nil, nil)
+	if e := b.next[0]; e == nil || !sl.equal(v, e.val) {
+		return -1
+	}
+	return i
+}
+
+// Determines if a given item is in the list.
+func (sl *{{.Name}}SortedList) Contains(v {{.Pointer}}{{.Name}}) bool {
+	return sl.IndexOf(v) >= 0
+}
+
+// Slice returns the items from index i up to but not including index j.
+// Panics if the indexes are out of range, like slicing a slice would.
+func (sl *{{.Name}}SortedList) Slice(i, j int) []{{.Pointer}}{{.Name}} {
+	if i < 0 || j < i || j > sl.length {
+		panic("{{.Name}}SortedList: slice bounds out of range")
+	}
+	s := make([]{{.Pointer}}{{.Name}}, 0, j-i)
+	if i == j {
+		return s
+	}
+	for e := sl.seekIndex(i, nil).next[0]; len(s) < j-i; e = e.next[0] {
+		s = append(s, e.val)
+	}
+	return s
+}
+
+// Len returns how many items are in the list.
+func (sl *{{.Name}}SortedList) Len() int {
+	return sl.length
+}
+
+// Clears the entire list.
+func (sl *{{.Name}}SortedList) Clear() {
+	*sl = *New{{.Name}}SortedList(sl.less)
+}
+
+// Each calls f with the index and value of every item in ascending order,
+// stopping early if f returns false.
+func (sl *{{.Name}}SortedList) Each(f func(i int, v {{.Pointer}}{{.Name}}) bool) {
+	i := 0
+	for e := sl.head.next[0]; e != nil; e = e.next[0] {
+		if !f(i, e.val) {
+			return
+		}
+		i++
+	}
+}
+`,
+}
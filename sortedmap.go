@@ -0,0 +1,180 @@
+package container
+
+import (
+	"github.com/clipperhouse/gen/typewriter"
+)
+
+var sortedMap = &typewriter.Template{
+	Text: `
+
+// A map from {{.Pointer}}{{.Name}} to {{.ValueType}} that keeps its keys sorted,
+// backed by a skiplist
+type {{.Name}}SortedMap struct {
+	less   func(a, b {{.Pointer}}{{.Name}}) bool
+	head   []*sortedMap{{.Name}}Element
+	length int
+	skiplist{{.Name}}Levels
+	update []*sortedMap{{.Name}}Element // scratch backPointer for Put and Delete
+}
+
+// the struct to hold entries of the skiplist
+type sortedMap{{.Name}}Element struct {
+	key   {{.Pointer}}{{.Name}}
+	value {{.ValueType}}
+	next  []*sortedMap{{.Name}}Element
+}
+
+// Creates and returns a reference to an empty map.
+func New{{.Name}}SortedMap(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) *{{.Name}}SortedMap {
+	sm := &{{.Name}}SortedMap{
+		less:                    less,
+		skiplist{{.Name}}Levels: newSkiplist{{.Name}}Levels(),
+	}
+	sm.head = make([]*sortedMap{{.Name}}Element, sm.maxLevels)
+	sm.update = make([]*sortedMap{{.Name}}Element, sm.maxLevels)
+	return sm
+}
+
+func newSortedMap{{.Name}}Element(k {{.Pointer}}{{.Name}}, v {{.ValueType}}, levels int) *sortedMap{{.Name}}Element {
+	return &sortedMap{{.Name}}Element{k, v, make([]*sortedMap{{.Name}}Element, levels)}
+}
+
+// updateVector returns the map's scratch backPointer, cleared, so that Put
+// and Delete don't allocate one each call. Only methods that change the map
+// may use it, so that many goroutines can read the map at once.
+func (sm *{{.Name}}SortedMap) updateVector() []*sortedMap{{.Name}}Element {
+	for i := range sm.update {
+		sm.update[i] = nil
+	}
+	return sm.update
+}
+
+// find descends the skiplist looking for k, recording in backPointer, unless
+// it is nil, the last element before k on every level. It returns the
+// element holding k, or nil if there is none.
+func (sm *{{.Name}}SortedMap) find(k {{.Pointer}}{{.Name}}, backPointer []*sortedMap{{.Name}}Element) *sortedMap{{.Name}}Element {
+	var pred, e *sortedMap{{.Name}}Element
+	for level := sm.level - 1; level >= 0; level-- {
+		if pred == nil {
+			e = sm.head[level]
+		} else {
+			e = pred.next[level]
+		}
+		// stop at the first key not less than k, then go down a level
+		for e != nil && sm.less(e.key, k) {
+			pred = e
+			e = e.next[level]
+		}
+		if backPointer != nil {
+			backPointer[level] = pred
+		}
+	}
+	if e != nil && !sm.less(k, e.key) {
+		return e
+	}
+	return nil
+}
+
+// Put associates v with k, replacing any value already stored for k.
+// Returns true if k was not in the map before.
+func (sm *{{.Name}}SortedMap) Put(k {{.Pointer}}{{.Name}}, v {{.ValueType}}) bool {
+	backPointer := sm.updateVector()
+	if e := sm.find(k, backPointer); e != nil {
+		e.value = v
+		return false
+	}
+	// create new element
+	e := newSortedMap{{.Name}}Element(k, v, sm.randomLevels(sm.length))
+
+	// connect new element up with backPointer
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			e.next[level] = sm.head[level]
+			sm.head[level] = e
+		} else {
+			e.next[level] = backPointer[level].next[level]
+			backPointer[level].next[level] = e
+		}
+	}
+	sm.raise(len(e.next))
+
+	sm.length++
+	return true
+}
+
+// Get returns the value stored for k, and whether k was found at all.
+func (sm *{{.Name}}SortedMap) Get(k {{.Pointer}}{{.Name}}) ({{.ValueType}}, bool) {
+	if e := sm.find(k, nil); e != nil {
+		return e.value, true
+	}
+	var zero {{.ValueType}}
+	return zero, false
+}
+
+// Determines if a given key is in the map.
+func (sm *{{.Name}}SortedMap) ContainsKey(k {{.Pointer}}{{.Name}}) bool {
+	return sm.find(k, nil) != nil
+}
+
+// Delete removes k and its value from the map.
+// Returns true if k was in the map.
+func (sm *{{.Name}}SortedMap) Delete(k {{.Pointer}}{{.Name}}) bool {
+	backPointer := sm.updateVector()
+	e := sm.find(k, backPointer)
+	if e == nil {
+		return false
+	}
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			sm.head[level] = e.next[level]
+		} else {
+			backPointer[level].next[level] = e.next[level]
+		}
+	}
+	// drop the height to the highest level that still has elements
+	for sm.level > 0 && sm.head[sm.level-1] == nil {
+		sm.level--
+	}
+	sm.length--
+	return true
+}
+
+// Len returns how many entries are currently in the map.
+func (sm *{{.Name}}SortedMap) Len() int {
+	return sm.length
+}
+
+// Clears the entire map.
+func (sm *{{.Name}}SortedMap) Clear() {
+	*sm = *New{{.Name}}SortedMap(sm.less)
+}
+
+// Keys returns all keys in ascending order.
+func (sm *{{.Name}}SortedMap) Keys() []{{.Pointer}}{{.Name}} {
+	keys := make([]{{.Pointer}}{{.Name}}, 0, sm.length)
+	for e := sm.head[0]; e != nil; e = e.next[0] {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+// Values returns all values in ascending order of their keys.
+func (sm *{{.Name}}SortedMap) Values() []{{.ValueType}} {
+	values := make([]{{.ValueType}}, 0, sm.length)
+	for e := sm.head[0]; e != nil; e = e.next[0] {
+		values = append(values, e.value)
+	}
+	return values
+}
+
+// Each calls f for every entry in ascending key order, stopping early
+// if f returns false.
+func (sm *{{.Name}}SortedMap) Each(f func(k {{.Pointer}}{{.Name}}, v {{.ValueType}}) bool) {
+	for e := sm.head[0]; e != nil; e = e.next[0] {
+		if !f(e.key, e.value) {
+			return
+		}
+	}
+}
+`,
+}
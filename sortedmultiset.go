@@ -0,0 +1,210 @@
+package container
+
+import (
+	"github.com/clipperhouse/gen/typewriter"
+)
+
+var sortedMultiSet = &typewriter.Template{
+	Text: `
+
+// A sorted bag of {{.Pointer}}{{.Name}} that, unlike {{.Name}}SortedSet, keeps
+// every copy of equal items, backed by a skiplist.
+// Equal items are kept in the order they were added.
+type {{.Name}}SortedMultiSet struct {
+	less   func(a, b {{.Pointer}}{{.Name}}) bool
+	head   []*sortedMultiSet{{.Name}}Element
+	length int
+	skiplist{{.Name}}Levels
+	update []*sortedMultiSet{{.Name}}Element // scratch backPointer for Add and the removes
+}
+
+// the struct to hold elements of the skiplist
+type sortedMultiSet{{.Name}}Element struct {
+	val  {{.Pointer}}{{.Name}}
+	next []*sortedMultiSet{{.Name}}Element
+}
+
+// Creates and returns a reference to an empty multiset.
+func New{{.Name}}SortedMultiSet(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) *{{.Name}}SortedMultiSet {
+	ms := &{{.Name}}SortedMultiSet{
+		less:                    less,
+		skiplist{{.Name}}Levels: newSkiplist{{.Name}}Levels(),
+	}
+	ms.head = make([]*sortedMultiSet{{.Name}}Element, ms.maxLevels)
+	ms.update = make([]*sortedMultiSet{{.Name}}Element, ms.maxLevels)
+	return ms
+}
+
+func newSortedMultiSet{{.Name}}Element(v {{.Pointer}}{{.Name}}, levels int) *sortedMultiSet{{.Name}}Element {
+	return &sortedMultiSet{{.Name}}Element{v, make([]*sortedMultiSet{{.Name}}Element, levels)}
+}
+
+// updateVector returns the multiset's scratch backPointer, cleared, so that
+// Add and the removes don't allocate one each call. Only methods that change
+// the multiset may use it, so that many goroutines can read it at once.
+func (ms *{{.Name}}SortedMultiSet) updateVector() []*sortedMultiSet{{.Name}}Element {
+	for i := range ms.update {
+		ms.update[i] = nil
+	}
+	return ms.update
+}
+
+// seek descends the skiplist recording in backPointer, unless it is nil, the
+// last element on every level that is less than v (or, if after is true, not
+// greater than v). It returns the element following that on the bottom level.
+func (ms *{{.Name}}SortedMultiSet) seek(v {{.Pointer}}{{.Name}}, after bool, backPointer []*sortedMultiSet{{.Name}}Element) *sortedMultiSet{{.Name}}Element {
+	var pred *sortedMultiSet{{.Name}}Element
+	e := ms.head[0]
+	for level := ms.level - 1; level >= 0; level-- {
+		if pred == nil {
+			e = ms.head[level]
+		} else {
+			e = pred.next[level]
+		}
+		for e != nil {
+			// if inspected val is past v, go back and down a level
+			if after && ms.less(v, e.val) || !after && !ms.less(e.val, v) {
+				break
+			}
+			pred = e
+			e = e.next[level]
+		}
+		if backPointer != nil {
+			backPointer[level] = pred
+		}
+	}
+	return e
+}
+
+// lower drops the height to the highest level that still has elements.
+func (ms *{{.Name}}SortedMultiSet) lower() {
+	for ms.level > 0 && ms.head[ms.level-1] == nil {
+		ms.level--
+	}
+}
+
+func (ms *{{.Name}}SortedMultiSet) equal(a, b {{.Pointer}}{{.Name}}) bool {
+	return !ms.less(a, b) && !ms.less(b, a)
+}
+
+// Adds a copy of an item to the multiset, after any equal items already in it.
+func (ms *{{.Name}}SortedMultiSet) Add(v {{.Pointer}}{{.Name}}) {
+	backPointer := ms.updateVector()
+	ms.seek(v, true, backPointer)
+
+	// create new element
+	e := newSortedMultiSet{{.Name}}Element(v, ms.randomLevels(ms.length))
+
+	// connect new element up with backPointer
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			e.next[level] = ms.head[level]
+			ms.head[level] = e
+		} else {
+			e.next[level] = backPointer[level].next[level]
+			backPointer[level].next[level] = e
+		}
+	}
+	ms.raise(len(e.next))
+
+	ms.length++
+}
+
+// Determines if at least one copy of a given item is in the multiset.
+func (ms *{{.Name}}SortedMultiSet) Contains(v {{.Pointer}}{{.Name}}) bool {
+	e := ms.seek(v, false, nil)
+	return e != nil && ms.equal(v, e.val)
+}
+
+// Count returns how many copies of a given item are in the multiset.
+func (ms *{{.Name}}SortedMultiSet) Count(v {{.Pointer}}{{.Name}}) int {
+	n := 0
+	for e := ms.seek(v, false, nil); e != nil && ms.equal(v, e.val); e = e.next[0] {
+		n++
+	}
+	return n
+}
+
+// RemoveOne removes the earliest added copy of an item.
+// Returns true if there was a copy to remove.
+func (ms *{{.Name}}SortedMultiSet) RemoveOne(v {{.Pointer}}{{.Name}}) bool {
+	backPointer := ms.updateVector()
+	e := ms.seek(v, false, backPointer)
+	if e == nil || !ms.equal(v, e.val) {
+		return false
+	}
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			ms.head[level] = e.next[level]
+		} else {
+			backPointer[level].next[level] = e.next[level]
+		}
+	}
+	ms.lower()
+	ms.length--
+	return true
+}
+
+// RemoveAll removes every copy of an item, returning how many were removed.
+func (ms *{{.Name}}SortedMultiSet) RemoveAll(v {{.Pointer}}{{.Name}}) int {
+	backPointer := ms.updateVector()
+	ms.seek(v, false, backPointer)
+	removed := 0
+	for level := 0; level < ms.level; level++ {
+		var e *sortedMultiSet{{.Name}}Element
+		if backPointer[level] == nil {
+			e = ms.head[level]
+		} else {
+			e = backPointer[level].next[level]
+		}
+		if e == nil || !ms.equal(v, e.val) {
+			// no copy reaches this level, so none reach the ones above
+			break
+		}
+		for e != nil && ms.equal(v, e.val) {
+			if level == 0 {
+				removed++
+			}
+			e = e.next[level]
+		}
+		if backPointer[level] == nil {
+			ms.head[level] = e
+		} else {
+			backPointer[level].next[level] = e
+		}
+	}
+	ms.lower()
+	ms.length -= removed
+	return removed
+}
+
+// Len returns how many items, counting every copy, are in the multiset.
+func (ms *{{.Name}}SortedMultiSet) Len() int {
+	return ms.length
+}
+
+// Clears the entire multiset.
+func (ms *{{.Name}}SortedMultiSet) Clear() {
+	*ms = *New{{.Name}}SortedMultiSet(ms.less)
+}
+
+// Each calls f for every item in ascending order, including every copy of
+// equal items in the order they were added, stopping early if f returns false.
+func (ms *{{.Name}}SortedMultiSet) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	for e := ms.head[0]; e != nil; e = e.next[0] {
+		if !f(e.val) {
+			return
+		}
+	}
+}
+
+// ToSlice returns every item, including copies, in ascending order.
+func (ms *{{.Name}}SortedMultiSet) ToSlice() []{{.Pointer}}{{.Name}} {
+	s := make([]{{.Pointer}}{{.Name}}, 0, ms.length)
+	for e := ms.head[0]; e != nil; e = e.next[0] {
+		s = append(s, e.val)
+	}
+	return s
+}
+`,
+}
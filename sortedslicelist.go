@@ -0,0 +1,370 @@
+package container
+
+import (
+	"github.com/clipperhouse/gen/typewriter"
+)
+
+var sortedSliceList = &typewriter.Template{
+	Text: `
+
+// A sorted list of {{.Pointer}}{{.Name}} kept as a list of short sorted
+// slices, after Grant Jenks' Python sortedcontainers. Items sit next to
+// each other in memory, so for small value types it is quicker than the
+// pointer-based containers and much smaller.
+// Equal items are all kept, in the order they were added.
+type {{.Name}}SortedSliceList struct {
+	less   func(a, b {{.Pointer}}{{.Name}}) bool
+	load   int
+	length int
+	lists  [][]{{.Pointer}}{{.Name}} // sorted, non-empty, and each no longer than twice load
+	maxes  []{{.Pointer}}{{.Name}}   // the last item of each list
+	// a Fenwick tree of the lengths of the lists, to find items by
+	// position; nil when it has to be rebuilt, as lists are split or merged
+	index []int
+}
+
+// Creates and returns a reference to an empty list, whose slices hold
+// about 1000 items each.
+func New{{.Name}}SortedSliceList(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) *{{.Name}}SortedSliceList {
+	return New{{.Name}}SortedSliceListWithLoad(less, 1000)
+}
+
+// Creates and returns a reference to an empty list whose slices hold
+// between load/2 and 2*load items each. A larger load makes adding and
+// removing slower, but the list smaller and quicker to walk.
+func New{{.Name}}SortedSliceListWithLoad(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, load int) *{{.Name}}SortedSliceList {
+	if load < 1 {
+		panic("{{.Name}}SortedSliceList: load must be at least 1")
+	}
+	return &{{.Name}}SortedSliceList{less: less, load: load}
+}
+
+// bisectLeft returns the index of the first item in s not less than v.
+func (sl *{{.Name}}SortedSliceList) bisectLeft(s []{{.Pointer}}{{.Name}}, v {{.Pointer}}{{.Name}}) int {
+	return sort.Search(len(s), func(i int) bool {
+		return !sl.less(s[i], v)
+	})
+}
+
+// bisectRight returns the index of the first item in s greater than v.
+func (sl *{{.Name}}SortedSliceList) bisectRight(s []{{.Pointer}}{{.Name}}, v {{.Pointer}}{{.Name}}) int {
+	return sort.Search(len(s), func(i int) bool {
+		return sl.less(v, s[i])
+	})
+}
+
+// Adds an item to the list, after any equal items already in it.
+func (sl *{{.Name}}SortedSliceList) Add(v {{.Pointer}}{{.Name}}) {
+	sl.length++
+	if len(sl.lists) == 0 {
+		sl.lists = append(sl.lists, []{{.Pointer}}{{.Name}}{v})
+		sl.maxes = append(sl.maxes, v)
+		sl.index = nil
+		return
+	}
+	k := sl.bisectRight(sl.maxes, v)
+	if k == len(sl.lists) {
+		// v goes after everything
+		k--
+		sl.lists[k] = append(sl.lists[k], v)
+		sl.maxes[k] = v
+	} else {
+		s := sl.lists[k]
+		j := sl.bisectRight(s, v)
+		s = append(s, v)
+		copy(s[j+1:], s[j:])
+		s[j] = v
+		sl.lists[k] = s
+	}
+	sl.expand(k)
+}
+
+// expand splits list k in two if it has grown too long.
+func (sl *{{.Name}}SortedSliceList) expand(k int) {
+	s := sl.lists[k]
+	if len(s) <= 2*sl.load {
+		sl.updateIndex(k, 1)
+		return
+	}
+	half := make([]{{.Pointer}}{{.Name}}, len(s)-sl.load, 2*sl.load+1)
+	copy(half, s[sl.load:])
+	var zero {{.Pointer}}{{.Name}}
+	for i := sl.load; i < len(s); i++ {
+		s[i] = zero
+	}
+	sl.lists[k] = s[:sl.load]
+	sl.maxes[k] = s[sl.load-1]
+
+	sl.lists = append(sl.lists, nil)
+	copy(sl.lists[k+2:], sl.lists[k+1:])
+	sl.lists[k+1] = half
+	sl.maxes = append(sl.maxes, zero)
+	copy(sl.maxes[k+2:], sl.maxes[k+1:])
+	sl.maxes[k+1] = half[len(half)-1]
+	sl.index = nil
+}
+
+// delete removes item j of list k, merging the list into a neighbour if
+// it has become too short.
+func (sl *{{.Name}}SortedSliceList) delete(k, j int) {{.Pointer}}{{.Name}} {
+	s := sl.lists[k]
+	v := s[j]
+	copy(s[j:], s[j+1:])
+	var zero {{.Pointer}}{{.Name}}
+	s[len(s)-1] = zero
+	s = s[:len(s)-1]
+	sl.lists[k] = s
+	sl.length--
+
+	switch {
+	case len(s) > sl.load/2:
+		sl.maxes[k] = s[len(s)-1]
+		sl.updateIndex(k, -1)
+	case len(sl.lists) > 1:
+		// merge with the list before, or after if this is the first
+		if k == 0 {
+			k = 1
+		}
+		merged := append(sl.lists[k-1], sl.lists[k]...)
+		sl.lists[k-1] = merged
+		sl.maxes[k-1] = merged[len(merged)-1]
+		copy(sl.lists[k:], sl.lists[k+1:])
+		sl.lists[len(sl.lists)-1] = nil
+		sl.lists = sl.lists[:len(sl.lists)-1]
+		copy(sl.maxes[k:], sl.maxes[k+1:])
+		sl.maxes[len(sl.maxes)-1] = zero
+		sl.maxes = sl.maxes[:len(sl.maxes)-1]
+		sl.index = nil
+		// the merged list may be too long now; expand counts it as grown
+		// by one, but the index is rebuilt anyway
+		sl.expand(k - 1)
+	case len(s) > 0:
+		sl.maxes[k] = s[len(s)-1]
+		sl.updateIndex(k, -1)
+	default:
+		// that was the last item
+		sl.lists = sl.lists[:0]
+		sl.maxes = sl.maxes[:0]
+		sl.index = nil
+	}
+	return v
+}
+
+// updateIndex adds delta to the length of list k in the index, if it is built.
+func (sl *{{.Name}}SortedSliceList) updateIndex(k, delta int) {
+	if sl.index == nil {
+		return
+	}
+	for i := k + 1; i < len(sl.index); i += i & -i {
+		sl.index[i] += delta
+	}
+}
+
+// buildIndex builds the index, if it isn't already, in O(number of lists).
+func (sl *{{.Name}}SortedSliceList) buildIndex() {
+	if sl.index != nil {
+		return
+	}
+	sl.index = make([]int, len(sl.lists)+1)
+	for k, s := range sl.lists {
+		i := k + 1
+		sl.index[i] += len(s)
+		if parent := i + i&-i; parent < len(sl.index) {
+			sl.index[parent] += sl.index[i]
+		}
+	}
+}
+
+// position returns how many items come before list k.
+func (sl *{{.Name}}SortedSliceList) position(k int) int {
+	if k == 0 {
+		return 0
+	}
+	sl.buildIndex()
+	n := 0
+	for i := k; i > 0; i -= i & -i {
+		n += sl.index[i]
+	}
+	return n
+}
+
+// locate returns the list holding the item at index i, and its index there.
+func (sl *{{.Name}}SortedSliceList) locate(i int) (k, j int) {
+	if i < len(sl.lists[0]) {
+		return 0, i
+	}
+	sl.buildIndex()
+	step := 1
+	for step*2 <= len(sl.lists) {
+		step *= 2
+	}
+	// find the last list with fewer than i items before it
+	for ; step > 0; step /= 2 {
+		if next := k + step; next <= len(sl.lists) && sl.index[next] <= i {
+			k = next
+			i -= sl.index[next]
+		}
+	}
+	return k, i
+}
+
+// Discard removes the earliest added copy of an item.
+// Returns true if there was a copy to remove.
+func (sl *{{.Name}}SortedSliceList) Discard(v {{.Pointer}}{{.Name}}) bool {
+	k := sl.bisectLeft(sl.maxes, v)
+	if k == len(sl.lists) {
+		return false
+	}
+	j := sl.bisectLeft(sl.lists[k], v)
+	if sl.less(v, sl.lists[k][j]) {
+		return false
+	}
+	sl.delete(k, j)
+	return true
+}
+
+// RemoveAt removes and returns the item at index i.
+// Panics if i is out of range.
+func (sl *{{.Name}}SortedSliceList) RemoveAt(i int) {{.Pointer}}{{.Name}} {
+	if i < 0 || i >= sl.length {
+		panic("{{.Name}}SortedSliceList: index out of range")
+	}
+	k, j := sl.locate(i)
+	return sl.delete(k, j)
+}
+
+// At returns the item at index i, where index 0 is the smallest item.
+// Panics if i is out of range.
+func (sl *{{.Name}}SortedSliceList) At(i int) {{.Pointer}}{{.Name}} {
+	if i < 0 || i >= sl.length {
+		panic("{{.Name}}SortedSliceList: index out of range")
+	}
+	k, j := sl.locate(i)
+	return sl.lists[k][j]
+}
+
+// BisectLeft returns the index where v would be added before any equal
+// items, which is how many items are less than v.
+func (sl *{{.Name}}SortedSliceList) BisectLeft(v {{.Pointer}}{{.Name}}) int {
+	k := sl.bisectLeft(sl.maxes, v)
+	if k == len(sl.lists) {
+		return sl.length
+	}
+	return sl.position(k) + sl.bisectLeft(sl.lists[k], v)
+}
+
+// BisectRight returns the index where v would be added after any equal
+// items, which is how many items are less than or equal to v.
+func (sl *{{.Name}}SortedSliceList) BisectRight(v {{.Pointer}}{{.Name}}) int {
+	k := sl.bisectRight(sl.maxes, v)
+	if k == len(sl.lists) {
+		return sl.length
+	}
+	return sl.position(k) + sl.bisectRight(sl.lists[k], v)
+}
+
+// Determines if a given item is in the list.
+func (sl *{{.Name}}SortedSliceList) Contains(v {{.Pointer}}{{.Name}}) bool {
+	k := sl.bisectLeft(sl.maxes, v)
+	if k == len(sl.lists) {
+		return false
+	}
+	j := sl.bisectLeft(sl.lists[k], v)
+	return !sl.less(v, sl.lists[k][j])
+}
+
+// Count returns how many copies of v are in the list.
+func (sl *{{.Name}}SortedSliceList) Count(v {{.Pointer}}{{.Name}}) int {
+	return sl.BisectRight(v) - sl.BisectLeft(v)
+}
+
+// Len returns how many items are in the list.
+func (sl *{{.Name}}SortedSliceList) Len() int {
+	return sl.length
+}
+
+// Clears the entire list.
+func (sl *{{.Name}}SortedSliceList) Clear() {
+	*sl = *New{{.Name}}SortedSliceListWithLoad(sl.less, sl.load)
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (sl *{{.Name}}SortedSliceList) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	for _, s := range sl.lists {
+		for _, v := range s {
+			if !f(v) {
+				return
+			}
+		}
+	}
+}
+
+// ToSlice returns the items in the list in ascending order.
+func (sl *{{.Name}}SortedSliceList) ToSlice() []{{.Pointer}}{{.Name}} {
+	s := make([]{{.Pointer}}{{.Name}}, 0, sl.length)
+	for _, l := range sl.lists {
+		s = append(s, l...)
+	}
+	return s
+}
+
+// A cursor over the items of a {{.Name}}SortedSliceList in ascending
+// order, up to an optional bound. It starts out positioned before its
+// first item; Value is only valid after Next has returned true.
+// Changing the list invalidates it.
+type {{.Name}}SortedSliceListIterator struct {
+	sl                  *{{.Name}}SortedSliceList
+	k, j                int // where the next item is
+	v                   {{.Pointer}}{{.Name}}
+	hi                  {{.Pointer}}{{.Name}}
+	hasHi, hiInclusive bool
+}
+
+// Iterator returns a cursor positioned before the first item of the list.
+func (sl *{{.Name}}SortedSliceList) Iterator() *{{.Name}}SortedSliceListIterator {
+	return &{{.Name}}SortedSliceListIterator{sl: sl}
+}
+
+// IRange returns a cursor over the items between lo and hi, each bound
+// being included or excluded as requested.
+func (sl *{{.Name}}SortedSliceList) IRange(lo, hi {{.Pointer}}{{.Name}}, loInclusive, hiInclusive bool) *{{.Name}}SortedSliceListIterator {
+	it := &{{.Name}}SortedSliceListIterator{sl: sl, hi: hi, hasHi: true, hiInclusive: hiInclusive}
+	if loInclusive {
+		it.k = sl.bisectLeft(sl.maxes, lo)
+		if it.k < len(sl.lists) {
+			it.j = sl.bisectLeft(sl.lists[it.k], lo)
+		}
+	} else {
+		it.k = sl.bisectRight(sl.maxes, lo)
+		if it.k < len(sl.lists) {
+			it.j = sl.bisectRight(sl.lists[it.k], lo)
+		}
+	}
+	return it
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *{{.Name}}SortedSliceListIterator) Next() bool {
+	if it.k >= len(it.sl.lists) {
+		return false
+	}
+	v := it.sl.lists[it.k][it.j]
+	if it.hasHi && (it.sl.less(it.hi, v) || !it.hiInclusive && !it.sl.less(v, it.hi)) {
+		it.k = len(it.sl.lists)
+		return false
+	}
+	it.v = v
+	it.j++
+	if it.j == len(it.sl.lists[it.k]) {
+		it.k, it.j = it.k+1, 0
+	}
+	return true
+}
+
+// Value returns the item at the cursor.
+func (it *{{.Name}}SortedSliceListIterator) Value() {{.Pointer}}{{.Name}} {
+	return it.v
+}
+`,
+}
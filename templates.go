@@ -4,6 +4,32 @@ import (
 	"github.com/clipperhouse/gen/typewriter"
 )
 
+// the packages each template's generated code imports
+var templateImports = map[string][]string{
+	"skiplistLevels": {"math", "math/bits", "math/rand"},
+	"SortedSet":      {"math/rand", "sort"},
+
+	"ConcurrentSortedSet": {"sync"},
+	"LockFreeSortedSet":   {"math", "math/bits", "math/rand", "sync/atomic"},
+	"ImmutableSortedSet":  {"math/rand"},
+	"SortedSliceList":     {"sort"},
+}
+
+// the templates whose generated code each template builds on
+var templateRequires = map[string][]string{
+	"SortedSet":      {"skiplistLevels"},
+	"SortedMap":      {"skiplistLevels"},
+	"SortedMultiSet": {"skiplistLevels"},
+	"SortedList":     {"skiplistLevels"},
+
+	"ConcurrentSortedSet": {"SortedSet"},
+}
+
+// the templates only generated as part of others, which a tag can't name
+var templateInternal = map[string]bool{
+	"skiplistLevels": true,
+}
+
 var templates = typewriter.TemplateSet{
 	"SortedSet": &typewriter.Template{
 		Text: `
@@ -12,82 +38,304 @@ var templates = typewriter.TemplateSet{
 // backed by a skiplist
 type {{.Name}}SortedSet struct {
 	less      func(a, b {{.Pointer}}{{.Name}}) bool
+	compare   func(a, b {{.Pointer}}{{.Name}}) int
 	head      []*sortedSet{{.Name}}Element
+	tail      *sortedSet{{.Name}}Element
 	length    int
-	maxLevels int
-	r         *rand.Rand
+	skiplist{{.Name}}Levels
+	options []{{.Name}}SortedSetOption // kept to make sets ordered and tuned the same way
+	update  []*sortedSet{{.Name}}Element // scratch backPointer for Add and Remove
+	arena   *sortedSet{{.Name}}Arena     // nil unless made with {{.Name}}SortedSetWithArena
 }
 
 // the struct to hold elements of the skiplist
 type sortedSet{{.Name}}Element struct {
 	val  {{.Pointer}}{{.Name}}
 	next []*sortedSet{{.Name}}Element
+	prev *sortedSet{{.Name}}Element // the previous element on level 0
 }
 
 // Creates and returns a reference to an empty set.
-func New{{.Name}}SortedSet(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) {{.Name}}SortedSet {
-	return {{.Name}}SortedSet{
-		less:      less,
-		maxLevels: 64,
-		head:      make([]*sortedSet{{.Name}}Element, 64),
-		r:         rand.New(rand.NewSource(123123)),
+func New{{.Name}}SortedSet(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) *{{.Name}}SortedSet {
+	return New{{.Name}}SortedSetWithOptions(less)
+}
+
+// Creates and returns a reference to an empty set ordered by a three-way
+// comparison, which returns a negative number when a is less than b, a
+// positive one when a is greater, and zero when they are equal.
+// Searching calls it once per element visited, where a less func has to
+// be called twice to tell equal items apart.
+func New{{.Name}}SortedSetWithCompare(compare func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) int) *{{.Name}}SortedSet {
+	less := func(a, b {{.Pointer}}{{.Name}}) bool {
+		return compare(a, b) < 0
+	}
+	return new{{.Name}}SortedSet(less, compare)
+}
+{{if .Natural}}
+{{if eq .Natural "Compare"}}// Creates and returns a reference to an empty set ordered by
+// {{.Pointer}}{{.Name}}'s Compare method.
+func New{{.Name}}SortedSetNatural() *{{.Name}}SortedSet {
+	return New{{.Name}}SortedSetWithCompare(func(a, b {{.Pointer}}{{.Name}}) int {
+		return a.Compare(b)
+	})
+}
+{{else if eq .Natural "Less"}}// Creates and returns a reference to an empty set ordered by
+// {{.Pointer}}{{.Name}}'s Less method.
+func New{{.Name}}SortedSetNatural() *{{.Name}}SortedSet {
+	return New{{.Name}}SortedSet(func(a, b {{.Pointer}}{{.Name}}) bool {
+		return a.Less(b)
+	})
+}
+{{else}}// Creates and returns a reference to an empty set ordered by the < operator.
+func New{{.Name}}SortedSetNatural() *{{.Name}}SortedSet {
+	return New{{.Name}}SortedSetWithCompare(func(a, b {{.Name}}) int {
+		switch {
+		case a < b:
+			return -1
+		case b < a:
+			return 1
+		}
+		return 0
+	})
+}
+{{end}}{{end}}
+// A {{.Name}}SortedSetOption tunes the skiplist behind a set made by
+// New{{.Name}}SortedSetWithOptions.
+type {{.Name}}SortedSetOption func(ss *{{.Name}}SortedSet)
+
+// {{.Name}}SortedSetWithMaxLevel caps how many levels an element can reach,
+// 64 by default. A skiplist with p = 1/2 stays fast up to about 2^maxLevel
+// items, and a lower cap saves memory in every search.
+func {{.Name}}SortedSetWithMaxLevel(maxLevel int) {{.Name}}SortedSetOption {
+	if maxLevel < 1 {
+		panic("{{.Name}}SortedSet: max level must be at least 1")
+	}
+	return func(ss *{{.Name}}SortedSet) {
+		ss.maxLevels = maxLevel
+	}
+}
+
+// {{.Name}}SortedSetWithP sets the chance that an element reaching one level
+// reaches the next, 1/2 by default. A lower p, e.g. 1/4, gives elements
+// fewer links, using less memory for slightly longer searches.
+func {{.Name}}SortedSetWithP(p float64) {{.Name}}SortedSetOption {
+	if !(p > 0 && p < 1) {
+		panic("{{.Name}}SortedSet: p must be between 0 and 1")
+	}
+	return func(ss *{{.Name}}SortedSet) {
+		ss.p = p
+	}
+}
+
+// {{.Name}}SortedSetWithSeed seeds the random levels, 123123 by default.
+// Sets with the same seed given the same items in the same order end up
+// with the same layout.
+func {{.Name}}SortedSetWithSeed(seed int64) {{.Name}}SortedSetOption {
+	return func(ss *{{.Name}}SortedSet) {
+		ss.r = rand.New(rand.NewSource(seed))
+	}
+}
+
+// {{.Name}}SortedSetWithRand draws the random levels from r, e.g. one seeded
+// from the clock for a layout that differs from run to run. r is shared by
+// every set made from this one, such as by Union or Clone, so none of them
+// can be added to from another goroutine without locking r as well. Making
+// those sets doesn't draw from r, as their levels are spread evenly.
+func {{.Name}}SortedSetWithRand(r *rand.Rand) {{.Name}}SortedSetOption {
+	return func(ss *{{.Name}}SortedSet) {
+		ss.r = r
+	}
+}
+
+// {{.Name}}SortedSetWithArena allocates elements chunkSize at a time, with
+// their links, rather than making two allocations for every item, and
+// reuses the elements of removed items for items added later. This eases
+// the load on the garbage collector for very large sets.
+// Since elements are reused, an Iterator must not be moved on from an
+// item once that item has been removed.
+func {{.Name}}SortedSetWithArena(chunkSize int) {{.Name}}SortedSetOption {
+	if chunkSize < 1 {
+		panic("{{.Name}}SortedSet: arena chunk size must be at least 1")
+	}
+	return func(ss *{{.Name}}SortedSet) {
+		ss.arena = &sortedSet{{.Name}}Arena{chunkSize: chunkSize}
+	}
+}
+
+// Creates and returns a reference to an empty set ordered by less and
+// tuned by the given options.
+func New{{.Name}}SortedSetWithOptions(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, options ...{{.Name}}SortedSetOption) *{{.Name}}SortedSet {
+	compare := func(a, b {{.Pointer}}{{.Name}}) int {
+		if less(a, b) {
+			return -1
+		}
+		if less(b, a) {
+			return 1
+		}
+		return 0
+	}
+	return new{{.Name}}SortedSet(less, compare, options...)
+}
+
+func new{{.Name}}SortedSet(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, compare func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) int, options ...{{.Name}}SortedSetOption) *{{.Name}}SortedSet {
+	ss := &{{.Name}}SortedSet{
+		less:                    less,
+		compare:                 compare,
+		skiplist{{.Name}}Levels: newSkiplist{{.Name}}Levels(),
+		options:                 options,
+	}
+	for _, option := range options {
+		option(ss)
+	}
+	ss.head = make([]*sortedSet{{.Name}}Element, ss.maxLevels)
+	ss.update = make([]*sortedSet{{.Name}}Element, ss.maxLevels)
+	if ss.arena != nil {
+		ss.arena.free = make([]*sortedSet{{.Name}}Element, ss.maxLevels)
 	}
+	return ss
+}
+
+// empty returns a new, empty set ordered and tuned the same way as this one.
+func (ss *{{.Name}}SortedSet) empty() *{{.Name}}SortedSet {
+	return new{{.Name}}SortedSet(ss.less, ss.compare, ss.options...)
 }
 
 func newSortedSet{{.Name}}Element(v {{.Pointer}}{{.Name}}, levels int) *sortedSet{{.Name}}Element {
-	return &sortedSet{{.Name}}Element{v, make([]*sortedSet{{.Name}}Element, levels)}
+	return &sortedSet{{.Name}}Element{val: v, next: make([]*sortedSet{{.Name}}Element, levels)}
 }
 
-// Creates and returns a reference to a set from an existing slice
-func New{{.Name}}SortedSetFromSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) {{.Name}}SortedSet {
-	a := New{{.Name}}SortedSet(less)
-	for _, item := range s {
-		a.Add(item)
+// newElement returns an element for v on the given number of levels, from
+// the set's arena if it has one.
+func (ss *{{.Name}}SortedSet) newElement(v {{.Pointer}}{{.Name}}, levels int) *sortedSet{{.Name}}Element {
+	if ss.arena != nil {
+		return ss.arena.element(v, levels)
 	}
-	return a
+	return newSortedSet{{.Name}}Element(v, levels)
+}
+
+// the chunks a set made with {{.Name}}SortedSetWithArena takes its elements
+// from; elements given back by recycle are kept on a free list for each
+// number of levels, chained through next[0]
+type sortedSet{{.Name}}Arena struct {
+	chunkSize int
+	elems     []sortedSet{{.Name}}Element  // the unused part of the current chunk of elements
+	links     []*sortedSet{{.Name}}Element // the unused part of the current chunk of links
+	free      []*sortedSet{{.Name}}Element
 }
 
-func (ss {{.Name}}SortedSet) randomLevels() int {
-	level := int(math.Log(1.0-ss.r.Float64()) / math.Log(0.5))
-	if level >= ss.maxLevels {
-		level = ss.maxLevels
+func (a *sortedSet{{.Name}}Arena) element(v {{.Pointer}}{{.Name}}, levels int) *sortedSet{{.Name}}Element {
+	if e := a.free[levels-1]; e != nil {
+		a.free[levels-1] = e.next[0]
+		e.next[0] = nil
+		e.val = v
+		return e
 	}
-	if level == 0 {
-		level++
+	if len(a.elems) == 0 {
+		a.elems = make([]sortedSet{{.Name}}Element, a.chunkSize)
+	}
+	if len(a.links) < levels {
+		// elements are on two levels on average with p = 1/2
+		n := 2 * a.chunkSize
+		if n < levels {
+			n = levels
+		}
+		a.links = make([]*sortedSet{{.Name}}Element, n)
+	}
+	e := &a.elems[0]
+	a.elems = a.elems[1:]
+	e.val = v
+	e.next = a.links[:levels:levels]
+	a.links = a.links[levels:]
+	return e
+}
+
+// recycle puts e, which must no longer be linked into the set, on the free list.
+func (a *sortedSet{{.Name}}Arena) recycle(e *sortedSet{{.Name}}Element) {
+	// drop what e refers to, so the garbage collector can have it
+	var zero {{.Pointer}}{{.Name}}
+	e.val = zero
+	e.prev = nil
+	for level := range e.next {
+		e.next[level] = nil
 	}
-	return level
+	e.next[0] = a.free[len(e.next)-1]
+	a.free[len(e.next)-1] = e
+}
+
+// Creates and returns a reference to a set from an existing slice.
+// The slice is copied and sorted, then built up as by
+// New{{.Name}}SortedSetFromSortedSlice; the first of any equal items is kept.
+func New{{.Name}}SortedSetFromSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) *{{.Name}}SortedSet {
+	sorted := make([]{{.Pointer}}{{.Name}}, len(s))
+	copy(sorted, s)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return New{{.Name}}SortedSetFromSortedSlice(less, sorted)
+}
+
+// Creates and returns a reference to a set from a slice already sorted by
+// less, in O(n). Runs of equal items are only added once.
+// Rather than being random, levels are spread evenly: every 2^k-th item
+// reaches level k, as in a perfectly balanced skiplist.
+func New{{.Name}}SortedSetFromSortedSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) *{{.Name}}SortedSet {
+	a := New{{.Name}}SortedSet(less)
+	ap := a.appender()
+	for i, v := range s {
+		if i > 0 && !less(s[i-1], v) {
+			// equal to the item before it
+			continue
+		}
+		ap.push(v)
+	}
+	ap.finish()
+	return a
 }
 
 // Adds an item to the current set if it doesn't already exist in the set.
-func (ss {{.Name}}SortedSet) Add(v {{.Pointer}}{{.Name}}) bool {
-	var backPointer = make([]*sortedSet{{.Name}}Element, 64)
-	// zeroing this causes the compiler to not allocate memory each time
-	// for a 20-30% boost in speed
-	for i := 0; i < 64; i++ {
-		backPointer[i] = nil
-	}
-	for level := ss.maxLevels - 1; level >= 0; level-- {
+func (ss *{{.Name}}SortedSet) Add(v {{.Pointer}}{{.Name}}) bool {
+	backPointer := ss.updateVector()
+	for level := ss.level - 1; level >= 0; level-- {
 		var e *sortedSet{{.Name}}Element = nil
-		if level+1 == ss.maxLevels || backPointer[level+1] == nil {
+		if level+1 == ss.level || backPointer[level+1] == nil {
 			e = ss.head[level]
 		} else {
 			e = backPointer[level+1]
 		}
 		for e != nil {
+			c := ss.compare(v, e.val)
 			// if they are equal, overwrite?
-			if ss.less(v, e.val) == ss.less(e.val, v) {
+			if c == 0 {
 				return false
 			}
 			// if inspected val is greater than k, go back and down a level
-			if ss.less(v, e.val) {
+			if c < 0 {
 				break
 			}
 			backPointer[level] = e
 			e = e.next[level]
 		}
 	}
+	ss.insert(v, backPointer)
+	return true
+}
+
+// updateVector returns the set's scratch backPointer, cleared, so that
+// Add and Remove don't allocate one each call. Only methods that change
+// the set may use it: methods that only read can be called from many
+// goroutines at once, e.g. under {{.Name}}ConcurrentSortedSet's read lock.
+func (ss *{{.Name}}SortedSet) updateVector() []*sortedSet{{.Name}}Element {
+	for i := range ss.update {
+		ss.update[i] = nil
+	}
+	return ss.update
+}
+
+// insert creates an element for v and connects it up after backPointer,
+// which must hold the last element before v on every level.
+func (ss *{{.Name}}SortedSet) insert(v {{.Pointer}}{{.Name}}, backPointer []*sortedSet{{.Name}}Element) *sortedSet{{.Name}}Element {
 	// create new element
-	e := newSortedSet{{.Name}}Element(v, ss.randomLevels())
+	e := ss.newElement(v, ss.randomLevels(ss.length))
 
 	// connect new element up with backPointer
 	for level := 0; level < len(e.next); level++ {
@@ -99,44 +347,354 @@ func (ss {{.Name}}SortedSet) Add(v {{.Pointer}}{{.Name}}) bool {
 			backPointer[level].next[level] = e
 		}
 	}
+	e.prev = backPointer[0]
+	if e.next[0] != nil {
+		e.next[0].prev = e
+	} else {
+		ss.tail = e
+	}
+	ss.raise(len(e.next))
 
 	ss.length++
-	return true
+	return e
 }
 
-// Determines if a given item is already in the set.
-func (ss {{.Name}}SortedSet) Contains(v {{.Pointer}}{{.Name}}) bool {
-	var backPointer = make([]*sortedSet{{.Name}}Element, 64)
-	// zeroing this causes the compiler to not allocate memory each time
-	// for a 20-30% boost in speed
-	for i := 0; i < 64; i++ {
-		backPointer[i] = nil
-	}
-	for level := ss.maxLevels - 1; level >= 0; level-- {
-		var e *sortedSet{{.Name}}Element = nil
-		if level+1 == ss.maxLevels || backPointer[level+1] == nil {
-			e = ss.head[level]
+// unlink disconnects e, given backPointer holding the last element before
+// it on every level.
+func (ss *{{.Name}}SortedSet) unlink(e *sortedSet{{.Name}}Element, backPointer []*sortedSet{{.Name}}Element) {
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			ss.head[level] = e.next[level]
 		} else {
-			e = backPointer[level+1]
+			backPointer[level].next[level] = e.next[level]
 		}
-		for e != nil {
+	}
+	if e.next[0] != nil {
+		e.next[0].prev = backPointer[0]
+	} else {
+		ss.tail = backPointer[0]
+	}
+
+	ss.length--
+	ss.lower()
+	if ss.arena != nil {
+		ss.arena.recycle(e)
+	}
+}
+
+// drop hands e, which a bulk removal has left out of the set, back to the
+// arena if the set has one.
+func (ss *{{.Name}}SortedSet) drop(e *sortedSet{{.Name}}Element) {
+	if ss.arena != nil {
+		ss.arena.recycle(e)
+	}
+}
+
+// dropFrom drops e and every element after it on level 0.
+func (ss *{{.Name}}SortedSet) dropFrom(e *sortedSet{{.Name}}Element) {
+	if ss.arena == nil {
+		return
+	}
+	for e != nil {
+		next := e.next[0]
+		ss.arena.recycle(e)
+		e = next
+	}
+}
+
+// lower drops the height to the highest level that still has elements.
+func (ss *{{.Name}}SortedSet) lower() {
+	for ss.level > 0 && ss.head[ss.level-1] == nil {
+		ss.level--
+	}
+}
+
+// seek fills backPointer with the last element before v on every level,
+// returning the element holding v, or nil if v is not in the set.
+// Whatever backPointer already holds is used as a finger to start each
+// level from, so it must be nil or elements before v; a run of seeks for
+// ascending items then walks each level only once.
+func (ss *{{.Name}}SortedSet) seek(v {{.Pointer}}{{.Name}}, backPointer []*sortedSet{{.Name}}Element) *sortedSet{{.Name}}Element {
+	var next *sortedSet{{.Name}}Element
+	for level := ss.level - 1; level >= 0; level-- {
+		// start from the finger or from where the level above stopped,
+		// whichever is further along
+		e := backPointer[level]
+		if level+1 < ss.level && backPointer[level+1] != nil {
+			if e == nil || ss.compare(e.val, backPointer[level+1].val) < 0 {
+				e = backPointer[level+1]
+			}
+		}
+		if e == nil {
+			next = ss.head[level]
+		} else {
+			next = e.next[level]
+		}
+		for next != nil && ss.compare(next.val, v) < 0 {
+			e = next
+			next = e.next[level]
+		}
+		backPointer[level] = e
+	}
+	if next != nil && ss.compare(v, next.val) == 0 {
+		return next
+	}
+	return nil
+}
+
+// sortedBatch returns a copy of items sorted by less, so that they can be
+// sought one after another with a finger.
+func (ss *{{.Name}}SortedSet) sortedBatch(items []{{.Pointer}}{{.Name}}) []{{.Pointer}}{{.Name}} {
+	sorted := make([]{{.Pointer}}{{.Name}}, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return ss.less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// AddAll adds every given item not already in the set, returning how many
+// were added. The items are sorted first so the search for each one starts
+// where the last left off.
+func (ss *{{.Name}}SortedSet) AddAll(items ...{{.Pointer}}{{.Name}}) int {
+	var backPointer = make([]*sortedSet{{.Name}}Element, ss.maxLevels)
+	added := 0
+	for _, v := range ss.sortedBatch(items) {
+		if ss.seek(v, backPointer) != nil {
+			continue
+		}
+		ss.insert(v, backPointer)
+		added++
+	}
+	return added
+}
+
+// RemoveAll removes every given item that is in the set, returning how many
+// were removed. The items are sorted first so the search for each one starts
+// where the last left off.
+func (ss *{{.Name}}SortedSet) RemoveAll(items ...{{.Pointer}}{{.Name}}) int {
+	var backPointer = make([]*sortedSet{{.Name}}Element, ss.maxLevels)
+	removed := 0
+	for _, v := range ss.sortedBatch(items) {
+		if e := ss.seek(v, backPointer); e != nil {
+			ss.unlink(e, backPointer)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RetainAll removes every item that is not in the other set, returning how
+// many were removed.
+func (ss *{{.Name}}SortedSet) RetainAll(other *{{.Name}}SortedSet) int {
+	before := ss.length
+	ss.IntersectWith(other)
+	return before - ss.length
+}
+
+// RemoveIf removes every item for which pred returns true, returning how
+// many were removed. The set is relinked in a single pass.
+func (ss *{{.Name}}SortedSet) RemoveIf(pred func(v {{.Pointer}}{{.Name}}) bool) int {
+	before := ss.length
+	e := ss.head[0]
+	a := ss.appender()
+	for e != nil {
+		// recycling e clears its links, so step past it first
+		next := e.next[0]
+		if pred(e.val) {
+			ss.drop(e)
+		} else {
+			a.link(e)
+		}
+		e = next
+	}
+	a.finish()
+	return before - ss.length
+}
+
+// Determines if a given item is already in the set.
+func (ss *{{.Name}}SortedSet) Contains(v {{.Pointer}}{{.Name}}) bool {
+	// only the last element before v matters, so no backPointer is kept
+	var prev *sortedSet{{.Name}}Element
+	for level := ss.level - 1; level >= 0; level-- {
+		e := ss.head[level]
+		if prev != nil {
+			e = prev.next[level]
+		}
+		for ; e != nil; e = e.next[level] {
+			c := ss.compare(v, e.val)
 			// if they are equal, return val
-			if ss.less(v, e.val) == ss.less(e.val, v) {
+			if c == 0 {
 				return true
 			}
 			// if inspected val is greater than v, go back and down a level
-			if ss.less(v, e.val) {
+			if c < 0 {
 				break
 			}
-			backPointer[level] = e
-			e = e.next[level]
+			prev = e
 		}
 	}
 	return false
 }
 
+// neighbors descends the skiplist the same way Add does, returning the last
+// element less than v and the first element not less than v. Either is nil
+// if there is no such element.
+func (ss *{{.Name}}SortedSet) neighbors(v {{.Pointer}}{{.Name}}) (lower, ceiling *sortedSet{{.Name}}Element) {
+	for level := ss.level - 1; level >= 0; level-- {
+		e := ss.head[level]
+		if lower != nil {
+			e = lower.next[level]
+		}
+		for ; e != nil; e = e.next[level] {
+			// if inspected val is not less than v, go back and down a level
+			if ss.compare(e.val, v) >= 0 {
+				break
+			}
+			lower = e
+		}
+	}
+	if lower == nil {
+		return nil, ss.head[0]
+	}
+	return lower, lower.next[0]
+}
+
+// Floor returns the greatest item in the set less than or equal to v.
+// ok is false if there is no such item.
+func (ss *{{.Name}}SortedSet) Floor(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	lower, ceiling := ss.neighbors(v)
+	if ceiling != nil && !ss.less(v, ceiling.val) {
+		return ceiling.val, true
+	}
+	if lower != nil {
+		return lower.val, true
+	}
+	return
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+// ok is false if there is no such item.
+func (ss *{{.Name}}SortedSet) Ceiling(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	_, ceiling := ss.neighbors(v)
+	if ceiling != nil {
+		return ceiling.val, true
+	}
+	return
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+// ok is false if there is no such item.
+func (ss *{{.Name}}SortedSet) Lower(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	lower, _ := ss.neighbors(v)
+	if lower != nil {
+		return lower.val, true
+	}
+	return
+}
+
+// Higher returns the least item in the set strictly greater than v.
+// ok is false if there is no such item.
+func (ss *{{.Name}}SortedSet) Higher(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	_, e := ss.neighbors(v)
+	if e != nil && !ss.less(v, e.val) {
+		e = e.next[0]
+	}
+	if e != nil {
+		return e.val, true
+	}
+	return
+}
+
+// A read-only view of the items of a {{.Name}}SortedSet between two bounds.
+// The view is evaluated lazily, seeking to its lower bound each time it
+// is iterated, so it reflects changes made to the set after it was created.
+type {{.Name}}SortedSetView struct {
+	ss                       *{{.Name}}SortedSet
+	lo, hi                   {{.Pointer}}{{.Name}}
+	hasLo, hasHi             bool
+	loInclusive, hiInclusive bool
+}
+
+// Range returns a view of the items between lo and hi, each bound being
+// included or excluded as requested.
+func (ss *{{.Name}}SortedSet) Range(lo, hi {{.Pointer}}{{.Name}}, loInclusive, hiInclusive bool) {{.Name}}SortedSetView {
+	return {{.Name}}SortedSetView{ss: ss, lo: lo, hi: hi, hasLo: true, hasHi: true, loInclusive: loInclusive, hiInclusive: hiInclusive}
+}
+
+// HeadSet returns a view of the items strictly less than hi.
+func (ss *{{.Name}}SortedSet) HeadSet(hi {{.Pointer}}{{.Name}}) {{.Name}}SortedSetView {
+	return {{.Name}}SortedSetView{ss: ss, hi: hi, hasHi: true}
+}
+
+// TailSet returns a view of the items greater than or equal to lo.
+func (ss *{{.Name}}SortedSet) TailSet(lo {{.Pointer}}{{.Name}}) {{.Name}}SortedSetView {
+	return {{.Name}}SortedSetView{ss: ss, lo: lo, hasLo: true, loInclusive: true}
+}
+
+// first returns the first element inside the lower bound of the view.
+func (sv {{.Name}}SortedSetView) first() *sortedSet{{.Name}}Element {
+	if !sv.hasLo {
+		return sv.ss.head[0]
+	}
+	_, e := sv.ss.neighbors(sv.lo)
+	if e != nil && !sv.loInclusive && !sv.ss.less(sv.lo, e.val) {
+		e = e.next[0]
+	}
+	return e
+}
+
+// inside determines if v is inside the upper bound of the view.
+func (sv {{.Name}}SortedSetView) inside(v {{.Pointer}}{{.Name}}) bool {
+	if !sv.hasHi {
+		return true
+	}
+	if sv.hiInclusive {
+		return !sv.ss.less(sv.hi, v)
+	}
+	return sv.ss.less(v, sv.hi)
+}
+
+// Each calls f for every item in the view in ascending order, stopping
+// early if f returns false.
+func (sv {{.Name}}SortedSetView) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		if !f(e.val) {
+			return
+		}
+	}
+}
+
+// Cardinality returns how many items are currently in the view.
+// Unlike the set's, this walks every item in the view.
+func (sv {{.Name}}SortedSetView) Cardinality() int {
+	n := 0
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		n++
+	}
+	return n
+}
+
+// ToSlice returns the items in the view in ascending order.
+func (sv {{.Name}}SortedSetView) ToSlice() []{{.Pointer}}{{.Name}} {
+	var s []{{.Pointer}}{{.Name}}
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		s = append(s, e.val)
+	}
+	return s
+}
+
+// ToSet copies the items in the view into a new set.
+func (sv {{.Name}}SortedSetView) ToSet() *{{.Name}}SortedSet {
+	set := sv.ss.empty()
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		set.Add(e.val)
+	}
+	return set
+}
+
 // Determines if the given items are all in the set
-func (ss {{.Name}}SortedSet) ContainsAll(i ...{{.Pointer}}{{.Name}}) bool {
+func (ss *{{.Name}}SortedSet) ContainsAll(i ...{{.Pointer}}{{.Name}}) bool {
 	for _, elem := range i {
 		if !ss.Contains(elem) {
 			return false
@@ -145,155 +703,526 @@ func (ss {{.Name}}SortedSet) ContainsAll(i ...{{.Pointer}}{{.Name}}) bool {
 	return true
 }
 
-// Determines if every item in the other set is in this set.
-func (ss {{.Name}}SortedSet) IsSubset(other {{.Name}}SortedSet) bool {
-	e := ss.head[0]
+// IsSubset determines if every item in this set is in the other set.
+// An empty set is a subset of every set, and every set is a subset of itself.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *{{.Name}}SortedSet) IsSubset(other *{{.Name}}SortedSet) bool {
+	if ss.length > other.length {
+		return false
+	}
+	e, o := ss.head[0], other.head[0]
 	for e != nil {
-		if !other.Contains(e.val) {
+		if o == nil {
 			return false
 		}
-		e = e.next[0]
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			// e.val was skipped over in the other set
+			return false
+		case c > 0:
+			o = o.next[0]
+		default:
+			e, o = e.next[0], o.next[0]
+		}
 	}
 	return true
 }
 
-// Determines if every item of this set is in the other set.
-func (ss {{.Name}}SortedSet) IsSuperset(other {{.Name}}SortedSet) bool {
+// IsSuperset determines if every item in the other set is in this set.
+func (ss *{{.Name}}SortedSet) IsSuperset(other *{{.Name}}SortedSet) bool {
 	return other.IsSubset(ss)
 }
 
-// Returns a new set with all items in both sets.
-func (ss {{.Name}}SortedSet) Union(other {{.Name}}SortedSet) {{.Name}}SortedSet {
-	unionedSet := New{{.Name}}SortedSet(ss.less)
+// IsProperSubset determines if this set is a subset of the other set and
+// the other set has at least one item this one does not.
+func (ss *{{.Name}}SortedSet) IsProperSubset(other *{{.Name}}SortedSet) bool {
+	return ss.length < other.length && ss.IsSubset(other)
+}
 
-	e := ss.head[0]
-	for e != nil {
-		unionedSet.Add(e.val)
-		e = e.next[0]
+// IsProperSuperset determines if the other set is a proper subset of this one.
+func (ss *{{.Name}}SortedSet) IsProperSuperset(other *{{.Name}}SortedSet) bool {
+	return other.IsProperSubset(ss)
+}
+
+// IsDisjoint determines if the two sets have no items in common.
+// An empty set is disjoint with every set, itself included.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *{{.Name}}SortedSet) IsDisjoint(other *{{.Name}}SortedSet) bool {
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			return false
+		}
 	}
-	e = other.head[0]
-	for e != nil {
-		unionedSet.Add(e.val)
-		e = e.next[0]
+	return true
+}
+
+// Overlaps determines if the two sets have at least one item in common.
+func (ss *{{.Name}}SortedSet) Overlaps(other *{{.Name}}SortedSet) bool {
+	return !ss.IsDisjoint(other)
+}
+
+// sortedSet{{.Name}}Appender refills a set with elements given in ascending
+// order, linking each one after the last element appended on every level
+// instead of searching for its place.
+type sortedSet{{.Name}}Appender struct {
+	ss   *{{.Name}}SortedSet
+	ends []*sortedSet{{.Name}}Element
+}
+
+// appender empties the set, leaving its elements alone so they can be
+// linked back in, and returns an appender to refill it.
+func (ss *{{.Name}}SortedSet) appender() *sortedSet{{.Name}}Appender {
+	ss.tail = nil
+	ss.length = 0
+	ss.level = 0
+	return &sortedSet{{.Name}}Appender{ss, make([]*sortedSet{{.Name}}Element, ss.maxLevels)}
+}
+
+// push appends a new element holding v. Its levels are spread evenly
+// rather than drawn at random: with p = 1/2, every 2^k-th item appended
+// reaches level k. So Clone and the set algebra never touch the set's
+// rand, which sets copied under a read lock may share.
+func (a *sortedSet{{.Name}}Appender) push(v {{.Pointer}}{{.Name}}) {
+	step := int(1/a.ss.p + 0.5)
+	if step < 2 {
+		step = 2
 	}
-	return unionedSet
+	n := a.ss.length + 1
+	levels := 1
+	for span := step; levels < a.ss.maxLevels && n%span == 0; span *= step {
+		levels++
+	}
+	a.link(a.ss.newElement(v, levels))
 }
 
-// Returns a new set with items that exist only in both sets.
-func (ss {{.Name}}SortedSet) Intersect(other {{.Name}}SortedSet) {{.Name}}SortedSet {
-	intersection := New{{.Name}}SortedSet(ss.less)
-	// loop over smaller set
-	if ss.Cardinality() < other.Cardinality() {
-		e := ss.head[0]
-		for e != nil {
-			if other.Contains(e.val) {
-				intersection.Add(e.val)
-			}
+// link appends e, which must not be less than anything appended before it.
+func (a *sortedSet{{.Name}}Appender) link(e *sortedSet{{.Name}}Element) {
+	for level := 0; level < len(e.next); level++ {
+		if a.ends[level] == nil {
+			a.ss.head[level] = e
+		} else {
+			a.ends[level].next[level] = e
+		}
+		a.ends[level] = e
+	}
+	e.prev = a.ss.tail
+	a.ss.tail = e
+	a.ss.length++
+}
+
+// finish terminates every level after the last element appended to it.
+func (a *sortedSet{{.Name}}Appender) finish() {
+	for level, e := range a.ends {
+		if e == nil {
+			a.ss.head[level] = nil
+		} else {
+			e.next[level] = nil
+			a.ss.level = level + 1
+		}
+	}
+}
+
+// Returns a new set with all items in both sets.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *{{.Name}}SortedSet) Union(other *{{.Name}}SortedSet) *{{.Name}}SortedSet {
+	unionedSet := ss.empty()
+	a := unionedSet.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.push(e.val)
 			e = e.next[0]
+		case c > 0:
+			a.push(o.val)
+			o = o.next[0]
+		default:
+			a.push(e.val)
+			e, o = e.next[0], o.next[0]
 		}
-	} else {
-		e := other.head[0]
-		for e != nil {
-			if ss.Contains(e.val) {
-				intersection.Add(e.val)
-			}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	for ; o != nil; o = o.next[0] {
+		a.push(o.val)
+	}
+	a.finish()
+	return unionedSet
+}
+
+// Returns a new set with items that exist only in both sets.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *{{.Name}}SortedSet) Intersect(other *{{.Name}}SortedSet) *{{.Name}}SortedSet {
+	intersection := ss.empty()
+	a := intersection.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
 			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			a.push(e.val)
+			e, o = e.next[0], o.next[0]
 		}
 	}
+	a.finish()
 	return intersection
 }
 
-// Returns a new set with items in the current set but not in the other set
-func (ss {{.Name}}SortedSet) Difference(other {{.Name}}SortedSet) {{.Name}}SortedSet {
-	differencedSet := New{{.Name}}SortedSet(ss.less)
-	e := ss.head[0]
-	for e != nil {
-		if !other.Contains(e.val) {
-			differencedSet.Add(e.val)
+// Returns a new set with items in the current set but not in the other set.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *{{.Name}}SortedSet) Difference(other *{{.Name}}SortedSet) *{{.Name}}SortedSet {
+	differencedSet := ss.empty()
+	a := differencedSet.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.push(e.val)
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			e, o = e.next[0], o.next[0]
 		}
-		e = e.next[0]
 	}
+	for ; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	a.finish()
 	return differencedSet
 }
 
 // Returns a new set with items in the current set or the other set but not in both.
-func (ss {{.Name}}SortedSet) SymmetricDifference(other {{.Name}}SortedSet) {{.Name}}SortedSet {
-	aDiff := ss.Difference(other)
-	bDiff := other.Difference(ss)
-	return aDiff.Union(bDiff)
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *{{.Name}}SortedSet) SymmetricDifference(other *{{.Name}}SortedSet) *{{.Name}}SortedSet {
+	symmetricDifference := ss.empty()
+	a := symmetricDifference.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.push(e.val)
+			e = e.next[0]
+		case c > 0:
+			a.push(o.val)
+			o = o.next[0]
+		default:
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	for ; o != nil; o = o.next[0] {
+		a.push(o.val)
+	}
+	a.finish()
+	return symmetricDifference
+}
+
+// UnionWith adds every item of the other set to this one, in O(n+m).
+// Elements already in this set are relinked rather than copied.
+func (ss *{{.Name}}SortedSet) UnionWith(other *{{.Name}}SortedSet) {
+	if other == ss {
+		return
+	}
+	e, o := ss.head[0], other.head[0]
+	a := ss.appender()
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.link(e)
+			e = e.next[0]
+		case c > 0:
+			a.push(o.val)
+			o = o.next[0]
+		default:
+			a.link(e)
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.link(e)
+	}
+	for ; o != nil; o = o.next[0] {
+		a.push(o.val)
+	}
+	a.finish()
+}
+
+// IntersectWith removes every item that is not also in the other set, in O(n+m).
+func (ss *{{.Name}}SortedSet) IntersectWith(other *{{.Name}}SortedSet) {
+	if other == ss {
+		return
+	}
+	e, o := ss.head[0], other.head[0]
+	a := ss.appender()
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			next := e.next[0]
+			ss.drop(e)
+			e = next
+		case c > 0:
+			o = o.next[0]
+		default:
+			a.link(e)
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	ss.dropFrom(e)
+	a.finish()
+}
+
+// DifferenceWith removes every item that is also in the other set, in O(n+m).
+func (ss *{{.Name}}SortedSet) DifferenceWith(other *{{.Name}}SortedSet) {
+	if other == ss {
+		ss.Clear()
+		return
+	}
+	e, o := ss.head[0], other.head[0]
+	a := ss.appender()
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.link(e)
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			next := e.next[0]
+			ss.drop(e)
+			e, o = next, o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.link(e)
+	}
+	a.finish()
 }
 
 // Clears the entire set to be the empty set.
 func (ss *{{.Name}}SortedSet) Clear() {
-	*ss = {{.Name}}SortedSet{
-		less:      ss.less,
-		maxLevels: 64,
-		head:      make([]*sortedSet{{.Name}}Element, 64),
-		r:         rand.New(rand.NewSource(123123)),
-	}
+	*ss = *ss.empty()
 }
 
 // Allows the removal of a single item in the set.
-func (ss {{.Name}}SortedSet) Remove(v {{.Pointer}}{{.Name}}) {
-	var backPointer = make([]*sortedSet{{.Name}}Element, 64)
-	// zeroing this causes the compiler to not allocate memory each time
-	// for a 20-30% boost in speed
-	for i := 0; i < 64; i++ {
-		backPointer[i] = nil
-	}
-	for level := ss.maxLevels - 1; level >= 0; level-- {
+// Returns true if the item was in the set.
+func (ss *{{.Name}}SortedSet) Remove(v {{.Pointer}}{{.Name}}) bool {
+	backPointer := ss.updateVector()
+	for level := ss.level - 1; level >= 0; level-- {
 		var e *sortedSet{{.Name}}Element = nil
-		if level+1 == ss.maxLevels || backPointer[level+1] == nil {
+		if level+1 == ss.level || backPointer[level+1] == nil {
 			e = ss.head[level]
 		} else {
 			e = backPointer[level+1]
 		}
 		for e != nil {
+			c := ss.compare(v, e.val)
 			// if they are equal, remove
-			if level == 0 && ss.less(v, e.val) == ss.less(e.val, v) {
-				for level := 0; level < len(e.next); level++ {
-					if backPointer[level] == nil {
-						ss.head[level] = e.next[level]
-					} else {
-						backPointer[level].next[level] = e.next[level]
-					}
-				}
-
-				ss.length--
-			}
-			if ss.less(v, e.val) == ss.less(e.val, v) {
-				break
+			if level == 0 && c == 0 {
+				ss.unlink(e, backPointer)
+				return true
 			}
-			// if inspected val is greater than k, go back and down a level
-			if ss.less(v, e.val) {
+			// if inspected val is greater than or equal to k, go back and down a level
+			if c <= 0 {
 				break
 			}
 			backPointer[level] = e
 			e = e.next[level]
 		}
 	}
+	return false
 }
 
 // Cardinality returns how many items are currently in the set.
-func (ss {{.Name}}SortedSet) Cardinality() int {
+func (ss *{{.Name}}SortedSet) Cardinality() int {
+	return ss.length
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (ss *{{.Name}}SortedSet) Len() int {
+	return ss.length
+}
+
+// A cursor over the items of a {{.Name}}SortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next, Prev or Seek has returned true.
+type {{.Name}}SortedSetIterator struct {
+	ss      *{{.Name}}SortedSet
+	e       *sortedSet{{.Name}}Element
+	started bool // with a nil e, whether the cursor is past the last item rather than before the first
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (ss *{{.Name}}SortedSet) Iterator() *{{.Name}}SortedSetIterator {
+	return &{{.Name}}SortedSetIterator{ss: ss}
+}
+
+// ReverseIterator returns a cursor positioned after the last item of the
+// set, to be walked from largest to smallest with Prev.
+func (ss *{{.Name}}SortedSet) ReverseIterator() *{{.Name}}SortedSetIterator {
+	return &{{.Name}}SortedSetIterator{ss: ss, started: true}
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *{{.Name}}SortedSetIterator) Next() bool {
+	if !it.started {
+		it.e = it.ss.head[0]
+		it.started = true
+	} else if it.e != nil {
+		it.e = it.e.next[0]
+	}
+	return it.e != nil
+}
+
+// Prev moves the cursor to the previous item, returning false once it has
+// moved before the first one.
+func (it *{{.Name}}SortedSetIterator) Prev() bool {
+	if !it.started {
+		return false
+	}
+	if it.e == nil {
+		it.e = it.ss.last()
+	} else {
+		it.e = it.e.prev
+	}
+	if it.e == nil {
+		it.started = false
+	}
+	return it.e != nil
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *{{.Name}}SortedSetIterator) Seek(v {{.Pointer}}{{.Name}}) bool {
+	_, it.e = it.ss.neighbors(v)
+	it.started = true
+	return it.e != nil
+}
+
+// Value returns the item at the cursor.
+func (it *{{.Name}}SortedSetIterator) Value() {{.Pointer}}{{.Name}} {
+	return it.e.val
+}
+
+// last returns the element holding the greatest item, or nil if the set is empty.
+func (ss *{{.Name}}SortedSet) last() *sortedSet{{.Name}}Element {
+	return ss.tail
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (ss *{{.Name}}SortedSet) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	for e := ss.head[0]; e != nil; e = e.next[0] {
+		if !f(e.val) {
+			return
+		}
+	}
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+func (ss *{{.Name}}SortedSet) ReverseEach(f func(v {{.Pointer}}{{.Name}}) bool) {
+	for e := ss.last(); e != nil; e = e.prev {
+		if !f(e.val) {
+			return
+		}
+	}
+}
+
+// First returns the least item in the set.
+// ok is false if the set is empty.
+func (ss *{{.Name}}SortedSet) First() (val {{.Pointer}}{{.Name}}, ok bool) {
+	if e := ss.head[0]; e != nil {
+		return e.val, true
+	}
+	return
+}
+
+// PopFirst removes and returns the least item in the set.
+// ok is false if the set is empty.
+func (ss *{{.Name}}SortedSet) PopFirst() (val {{.Pointer}}{{.Name}}, ok bool) {
 	e := ss.head[0]
-	ret := 0
-	for e != nil {
-		ret++
-		e = e.next[0]
+	if e == nil {
+		return
+	}
+	// the first element comes straight after the head on every level it is on
+	for level := 0; level < len(e.next); level++ {
+		ss.head[level] = e.next[level]
+	}
+	if e.next[0] != nil {
+		e.next[0].prev = nil
+	} else {
+		ss.tail = nil
+	}
+	ss.length--
+	ss.lower()
+	val = e.val
+	if ss.arena != nil {
+		ss.arena.recycle(e)
+	}
+	return val, true
+}
+
+// PopN removes and returns the k least items in the set in ascending
+// order, or every item if there are fewer than k.
+func (ss *{{.Name}}SortedSet) PopN(k int) []{{.Pointer}}{{.Name}} {
+	var popped []{{.Pointer}}{{.Name}}
+	for len(popped) < k {
+		v, ok := ss.PopFirst()
+		if !ok {
+			break
+		}
+		popped = append(popped, v)
+	}
+	return popped
+}
+
+// Last returns the greatest item in the set.
+// ok is false if the set is empty.
+func (ss *{{.Name}}SortedSet) Last() (val {{.Pointer}}{{.Name}}, ok bool) {
+	if e := ss.last(); e != nil {
+		return e.val, true
+	}
+	return
+}
+
+// PopLast removes and returns the greatest item in the set, like PopMax.
+// ok is false if the set is empty.
+func (ss *{{.Name}}SortedSet) PopLast() (val {{.Pointer}}{{.Name}}, ok bool) {
+	return ss.PopMax()
+}
+
+// PopMax removes and returns the greatest item in the set.
+// ok is false if the set is empty.
+func (ss *{{.Name}}SortedSet) PopMax() (val {{.Pointer}}{{.Name}}, ok bool) {
+	val, ok = ss.Last()
+	if ok {
+		ss.Remove(val)
 	}
-	return ret
+	return
 }
 
 // Iter() returns a channel of type {{.Pointer}}{{.Name}} that you can range over.
-func (ss {{.Name}}SortedSet) Iter() <-chan {{.Pointer}}{{.Name}} {
+// It is kept for compatibility: breaking out of the range loop leaks the
+// goroutine feeding the channel, so prefer Iterator or Each.
+func (ss *{{.Name}}SortedSet) Iter() <-chan {{.Pointer}}{{.Name}} {
 	ch := make(chan {{.Pointer}}{{.Name}})
 	go func() {
-		e := ss.head[0]
-		for e != nil {
-			ch <- e.val
-			e = e.next[0]
-		}
+		ss.Each(func(v {{.Pointer}}{{.Name}}) bool {
+			ch <- v
+			return true
+		})
 		close(ch)
 	}()
 
@@ -303,32 +1232,42 @@ func (ss {{.Name}}SortedSet) Iter() <-chan {{.Pointer}}{{.Name}} {
 // Equal determines if two sets are equal to each other.
 // If they both are the same size and have the same items they are considered equal.
 // Order of items is not relevent for sets to be equal.
-func (ss {{.Name}}SortedSet) Equal(other {{.Name}}SortedSet) bool {
+func (ss *{{.Name}}SortedSet) Equal(other *{{.Name}}SortedSet) bool {
 	if ss.Cardinality() != other.Cardinality() {
 		return false
 	}
-	e := ss.head[0]
-	for e != nil {
-		if !other.Contains(e.val) {
+	// both sets are in order, so walk them side by side in O(n)
+	for e, o := ss.head[0], other.head[0]; e != nil; e, o = e.next[0], o.next[0] {
+		if ss.compare(e.val, o.val) != 0 {
 			return false
 		}
-		e = e.next[0]
 	}
 	return true
 }
 
-// Returns a clone of the set.
+// Returns a clone of the set, in O(n).
 // Does NOT clone the underlying elements.
-func (ss {{.Name}}SortedSet) Clone() {{.Name}}SortedSet {
-	clonedSet := New{{.Name}}SortedSet(ss.less)
-	e := ss.head[0]
-	for e != nil {
-		clonedSet.Add(e.val)
-		e = e.next[0]
+func (ss *{{.Name}}SortedSet) Clone() *{{.Name}}SortedSet {
+	clonedSet := ss.empty()
+	a := clonedSet.appender()
+	for e := ss.head[0]; e != nil; e = e.next[0] {
+		a.push(e.val)
 	}
+	a.finish()
 	return clonedSet
 }
 `,
 		RequiresComparable: true,
 	},
+	"skiplistLevels": skiplistLevels,
+	"SortedMap":      sortedMap,
+	"SortedMultiSet": sortedMultiSet,
+	"SortedList":     sortedList,
+
+	"ConcurrentSortedSet": concurrentSortedSet,
+	"LockFreeSortedSet":   lockFreeSortedSet,
+	"ImmutableSortedSet":  immutableSortedSet,
+	"BTreeSortedSet":      btreeSortedSet,
+	"SortedSliceList":     sortedSliceList,
+	"TreeSortedSet":       treeSortedSet,
 }
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func makeBTreeSortedSet(ints []int) *ThingBTreeSortedSet {
+	set := NewThingBTreeSortedSetNatural()
+	for _, i := range ints {
+		set.Add(Thing(i))
+	}
+	return set
+}
+
+// checkBTreeSortedSet checks the set's items both ways round, and that the
+// tree is balanced with every node but the root between half and fully full.
+func checkBTreeSortedSet(t *testing.T, name string, s *ThingBTreeSortedSet, expected ...Thing) {
+	got := []Thing{}
+	s.Each(func(v Thing) bool {
+		got = append(got, v)
+		return true
+	})
+	var reversed []Thing
+	s.ReverseEach(func(v Thing) bool {
+		reversed = append(reversed, v)
+		return true
+	})
+	ok := len(got) == len(expected) && len(reversed) == len(expected) && s.Len() == len(expected)
+	for i := 0; ok && i < len(expected); i++ {
+		ok = got[i] == expected[i] && reversed[len(expected)-1-i] == expected[i] && s.Contains(expected[i])
+	}
+	if !ok {
+		t.Errorf("%s should be %v, got %v (reversed %v, Len %d)", name, expected, got, reversed, s.Len())
+	}
+
+	if s.root == nil {
+		return
+	}
+	depth := -1
+	var walk func(n *btreeSortedSetThingNode, level int)
+	walk = func(n *btreeSortedSetThingNode, level int) {
+		if n != s.root && (len(n.items) < btreeSortedSetThingMinItems || len(n.items) > btreeSortedSetThingMaxItems) {
+			t.Errorf("%s has a node with %d items", name, len(n.items))
+		}
+		if len(n.items) == 0 {
+			t.Errorf("%s has an empty node", name)
+		}
+		if n.leaf() {
+			if depth >= 0 && depth != level {
+				t.Errorf("%s has leaves at depths %d and %d", name, depth, level)
+			}
+			depth = level
+			return
+		}
+		if len(n.children) != len(n.items)+1 {
+			t.Errorf("%s has a node with %d items and %d children", name, len(n.items), len(n.children))
+		}
+		for _, c := range n.children {
+			walk(c, level+1)
+		}
+	}
+	walk(s.root, 0)
+}
+
+func Test_BTreeSortedSetAddRemove(t *testing.T) {
+	a := makeBTreeSortedSet(nil)
+	checkBTreeSortedSet(t, "empty", a)
+	if a.Remove(1) {
+		t.Error("an empty set has nothing to remove")
+	}
+
+	r := rand.New(rand.NewSource(3))
+	present := map[int]bool{}
+	for i := 0; i < 5000; i++ {
+		v := r.Intn(500)
+		if r.Intn(3) == 0 {
+			if a.Remove(Thing(v)) != present[v] {
+				t.Fatalf("Remove(%d) should return %v", v, present[v])
+			}
+			delete(present, v)
+		} else {
+			if a.Add(Thing(v)) == present[v] {
+				t.Fatalf("Add(%d) should return %v", v, !present[v])
+			}
+			present[v] = true
+		}
+	}
+	var expected []Thing
+	for v := range present {
+		expected = append(expected, Thing(v))
+	}
+	sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+	checkBTreeSortedSet(t, "after random changes", a, expected...)
+
+	for len(expected) > 0 {
+		if r.Intn(2) == 0 {
+			if v, ok := a.PopFirst(); !ok || v != expected[0] {
+				t.Fatalf("PopFirst should return %v, got %v", expected[0], v)
+			}
+			expected = expected[1:]
+		} else {
+			if v, ok := a.PopLast(); !ok || v != expected[len(expected)-1] {
+				t.Fatalf("PopLast should return %v, got %v", expected[len(expected)-1], v)
+			}
+			expected = expected[:len(expected)-1]
+		}
+	}
+	checkBTreeSortedSet(t, "after popping everything", a)
+	if _, ok := a.PopFirst(); ok {
+		t.Error("an empty set has nothing to pop")
+	}
+}
+
+func Test_BTreeSortedSetFromSortedSlice(t *testing.T) {
+	for n := 0; n < 300; n++ {
+		var s []Thing
+		for i := 0; i < n; i++ {
+			// every item twice
+			s = append(s, Thing(i), Thing(i))
+		}
+		a := NewThingBTreeSortedSetFromSortedSlice(func(a, b Thing) bool { return a < b }, s)
+		var expected []Thing
+		for i := 0; i < n; i++ {
+			expected = append(expected, Thing(i))
+		}
+		checkBTreeSortedSet(t, "FromSortedSlice", a, expected...)
+	}
+
+	a := NewThingBTreeSortedSetFromSlice(func(a, b Thing) bool { return a < b }, []Thing{5, 1, 3, 1})
+	checkBTreeSortedSet(t, "FromSlice", a, 1, 3, 5)
+}
+
+func Test_BTreeSortedSetNeighbors(t *testing.T) {
+	a := makeBTreeSortedSet([]int{10, 20, 30, 40, 50, 60, 70, 80, 90})
+	for _, c := range []struct {
+		v                             Thing
+		floor, ceiling, lower, higher Thing // 0 for none
+	}{
+		{5, 0, 10, 0, 10},
+		{10, 10, 10, 0, 20},
+		{45, 40, 50, 40, 50},
+		{50, 50, 50, 40, 60},
+		{90, 90, 90, 80, 0},
+		{95, 90, 0, 90, 0},
+	} {
+		for _, f := range []struct {
+			name string
+			got  func(Thing) (Thing, bool)
+			want Thing
+		}{
+			{"Floor", a.Floor, c.floor},
+			{"Ceiling", a.Ceiling, c.ceiling},
+			{"Lower", a.Lower, c.lower},
+			{"Higher", a.Higher, c.higher},
+		} {
+			v, ok := f.got(c.v)
+			if ok != (f.want != 0) || ok && v != f.want {
+				t.Errorf("%s(%v) should be %v, got %v, %v", f.name, c.v, f.want, v, ok)
+			}
+		}
+	}
+}
+
+func Test_BTreeSortedSetIterator(t *testing.T) {
+	var ints []int
+	for i := 0; i < 200; i += 2 {
+		ints = append(ints, i)
+	}
+	a := makeBTreeSortedSet(ints)
+
+	it := a.Iterator()
+	for _, v := range ints {
+		if !it.Next() || it.Value() != Thing(v) {
+			t.Fatalf("Next should move to %d", v)
+		}
+	}
+	if it.Next() {
+		t.Error("Next should stop after the last item")
+	}
+	for i := len(ints) - 1; i >= 0; i-- {
+		if !it.Prev() || it.Value() != Thing(ints[i]) {
+			t.Fatalf("Prev should move back to %d", ints[i])
+		}
+	}
+	if it.Prev() {
+		t.Error("Prev should stop before the first item")
+	}
+	if !it.Next() || it.Value() != 0 {
+		t.Error("Next should move from before the first item to it")
+	}
+
+	rit := a.ReverseIterator()
+	for i := len(ints) - 1; i >= 0; i-- {
+		if !rit.Prev() || rit.Value() != Thing(ints[i]) {
+			t.Fatalf("Prev should move to %d", ints[i])
+		}
+	}
+
+	for v := -1; v < 199; v++ {
+		want := v + v%2
+		if v < 0 {
+			want = 0
+		}
+		if !it.Seek(Thing(v)) || it.Value() != Thing(want) {
+			t.Fatalf("Seek(%d) should move to %d", v, want)
+		}
+		// and walk on both ways from there
+		if want > 0 && (!it.Prev() || it.Value() != Thing(want-2) || !it.Next()) {
+			t.Fatalf("Prev after Seek(%d) should move to %d", v, want-2)
+		}
+		if want < 198 && (!it.Next() || it.Value() != Thing(want+2)) {
+			t.Fatalf("Next after Seek(%d) should move to %d", v, want+2)
+		}
+	}
+	if it.Seek(199) {
+		t.Error("Seek past the last item should return false")
+	}
+	if !it.Prev() || it.Value() != 198 {
+		t.Error("Prev after seeking past the end should move to the last item")
+	}
+
+	if makeBTreeSortedSet(nil).Iterator().Next() {
+		t.Error("an empty set has nothing to iterate")
+	}
+}
+
+func Test_BTreeSortedSetIter(t *testing.T) {
+	var ints []int
+	for i := 0; i < 200; i += 2 {
+		ints = append(ints, i)
+	}
+	a := makeBTreeSortedSet(ints)
+
+	i := 0
+	for v := range a.Iter() {
+		if i >= len(ints) || v != Thing(ints[i]) {
+			t.Fatalf("Iter should yield %v in order, got %d at %d", ints, v, i)
+		}
+		i++
+	}
+	if i != len(ints) {
+		t.Errorf("Iter should yield %d items, yielded %d", len(ints), i)
+	}
+
+	for range makeBTreeSortedSet(nil).Iter() {
+		t.Error("an empty set has nothing to iterate")
+	}
+}
+
+func Test_BTreeSortedSetAlgebra(t *testing.T) {
+	a := makeBTreeSortedSet([]int{1, 3, 5, 7, 9, 11})
+	b := makeBTreeSortedSet([]int{0, 3, 4, 9, 12})
+	empty := makeBTreeSortedSet(nil)
+
+	checkBTreeSortedSet(t, "a.Union(b)", a.Union(b), 0, 1, 3, 4, 5, 7, 9, 11, 12)
+	checkBTreeSortedSet(t, "a.Intersect(b)", a.Intersect(b), 3, 9)
+	checkBTreeSortedSet(t, "a.Difference(b)", a.Difference(b), 1, 5, 7, 11)
+	checkBTreeSortedSet(t, "a.SymmetricDifference(b)", a.SymmetricDifference(b), 0, 1, 4, 5, 7, 11, 12)
+	checkBTreeSortedSet(t, "a.Union(empty)", a.Union(empty), 1, 3, 5, 7, 9, 11)
+	checkBTreeSortedSet(t, "empty.Intersect(a)", empty.Intersect(a))
+
+	c := a.Clone()
+	c.UnionWith(b)
+	checkBTreeSortedSet(t, "UnionWith", c, 0, 1, 3, 4, 5, 7, 9, 11, 12)
+	c.DifferenceWith(b)
+	checkBTreeSortedSet(t, "DifferenceWith", c, 1, 5, 7, 11)
+	c.IntersectWith(a)
+	checkBTreeSortedSet(t, "IntersectWith", c, 1, 5, 7, 11)
+	checkBTreeSortedSet(t, "a is unchanged", a, 1, 3, 5, 7, 9, 11)
+
+	if !c.IsSubset(a) || !c.IsProperSubset(a) || !a.IsSuperset(c) || !a.IsProperSuperset(c) {
+		t.Error("c should be a proper subset of a")
+	}
+	if a.IsSubset(c) || !a.IsSubset(a) || a.IsProperSubset(a) {
+		t.Error("a should only be a subset of itself")
+	}
+	if a.IsDisjoint(b) || !a.Overlaps(b) || !c.IsDisjoint(b) || !empty.IsDisjoint(empty) {
+		t.Error("a and b overlap, c and b don't")
+	}
+	if !a.Equal(a.Clone()) || a.Equal(c) || !empty.Equal(makeBTreeSortedSet(nil)) {
+		t.Error("only sets with the same items are equal")
+	}
+
+	if n := c.AddAll(1, 2, 3); n != 2 {
+		t.Errorf("AddAll should add 2 new items, added %d", n)
+	}
+	if n := c.RemoveAll(2, 3, 4); n != 2 {
+		t.Errorf("RemoveAll should remove 2 items, removed %d", n)
+	}
+	if n := c.RemoveIf(func(v Thing) bool { return v > 6 }); n != 2 {
+		t.Errorf("RemoveIf should remove 2 items, removed %d", n)
+	}
+	checkBTreeSortedSet(t, "after RemoveIf", c, 1, 5)
+	if n := a.RetainAll(b); n != 4 {
+		t.Errorf("RetainAll should remove 4 items, removed %d", n)
+	}
+	checkBTreeSortedSet(t, "after RetainAll", a, 3, 9)
+	if popped := b.PopN(2); len(popped) != 2 || popped[0] != 0 || popped[1] != 3 {
+		t.Errorf("PopN(2) should return [0 3], got %v", popped)
+	}
+	b.Clear()
+	checkBTreeSortedSet(t, "after Clear", b)
+}
+
+func Test_BTreeSortedSetViews(t *testing.T) {
+	a := makeBTreeSortedSet([]int{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	check := func(name string, v ThingBTreeSortedSetView, expected ...Thing) {
+		if v.Cardinality() != len(expected) {
+			t.Errorf("%s should have %d items, has %d", name, len(expected), v.Cardinality())
+		}
+		checkBTreeSortedSet(t, name, v.ToSet(), expected...)
+	}
+	check("Range[3,6]", a.Range(3, 6, true, true), 3, 4, 5, 6)
+	check("Range(3,6)", a.Range(3, 6, false, false), 4, 5)
+	check("HeadSet(4)", a.HeadSet(4), 1, 2, 3)
+	check("TailSet(7)", a.TailSet(7), 7, 8, 9)
+	check("Range(9,10)", a.Range(9, 10, false, true))
+}
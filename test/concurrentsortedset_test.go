@@ -0,0 +1,221 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func Test_ConcurrentSortedSet(t *testing.T) {
+	a := NewThingConcurrentSortedSet(func(a, b Thing) bool { return a < b })
+
+	if !a.Add(3) || a.Add(3) || a.AddAll(1, 2, 5) != 3 {
+		t.Error("concurrent set should add items like a SortedSet")
+	}
+
+	if !a.Contains(2) || a.Len() != 4 {
+		t.Error("concurrent set should have 1, 2, 3, 5")
+	}
+
+	if v, ok := a.Ceiling(4); !ok || v != 5 {
+		t.Error("Ceiling(4) should be 5")
+	}
+
+	if v, ok := a.PopFirst(); !ok || v != 1 {
+		t.Error("PopFirst should be 1")
+	}
+
+	b := NewThingConcurrentSortedSetFromSlice(func(a, b Thing) bool { return a < b }, []Thing{2, 9})
+	if u := a.Union(b); u.Len() != 4 || !u.ContainsAll(2, 3, 5, 9) {
+		t.Error("Union should have 2, 3, 5, 9")
+	}
+
+	a.UnionWith(b)
+	a.UnionWith(a)
+	if a.Len() != 4 || !b.IsSubset(a) || !a.IsSuperset(b) {
+		t.Error("UnionWith should leave b a subset of a")
+	}
+}
+
+func Test_ConcurrentSortedSetIteratorIsSnapshot(t *testing.T) {
+	a := NewThingConcurrentSortedSetFromSlice(func(a, b Thing) bool { return a < b }, []Thing{1, 2, 3})
+
+	it := a.Iterator()
+	a.Add(4)
+	a.Remove(1)
+
+	var got []Thing
+	for it.Next() {
+		got = append(got, it.Value())
+	}
+	if len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Error("iterator should see the set as it was when created, got", got)
+	}
+
+	snapshot := a.Snapshot()
+	checkSortedSet(t, "Snapshot", snapshot, 2, 3, 4)
+}
+
+func Test_ConcurrentSortedSetFullAPI(t *testing.T) {
+	a := NewThingConcurrentSortedSetNatural()
+	a.AddAll(1, 2, 3, 4, 5, 6)
+	b := NewThingConcurrentSortedSetFromSortedSlice(func(a, b Thing) bool { return a < b }, []Thing{2, 2, 4, 6})
+	c := NewThingConcurrentSortedSetWithOptions(func(a, b Thing) bool { return a < b }, ThingSortedSetWithSeed(7), ThingSortedSetWithArena(8))
+	c.AddAll(7, 8)
+
+	if !b.IsProperSubset(a) || !a.IsProperSuperset(b) || a.IsProperSubset(a) {
+		t.Error("b should be a proper subset of a")
+	}
+	if a.IsDisjoint(b) || !a.Overlaps(b) || !a.IsDisjoint(c) || c.Overlaps(a) {
+		t.Error("a and b overlap, a and c don't")
+	}
+
+	var reversed []Thing
+	a.ReverseEach(func(v Thing) bool {
+		reversed = append(reversed, v)
+		return v > 4
+	})
+	if len(reversed) != 3 || reversed[0] != 6 || reversed[2] != 4 {
+		t.Error("ReverseEach should visit 6, 5, 4 and stop, got", reversed)
+	}
+
+	i := Thing(1)
+	for v := range a.Iter() {
+		if v != i {
+			t.Error("Iter should return the items in order")
+		}
+		i++
+	}
+
+	view := a.Range(2, 5, true, false)
+	a.Remove(3)
+	if got := view.ToSlice(); len(got) != 3 || got[0] != 2 || got[1] != 3 || got[2] != 4 {
+		t.Error("Range should be a view of the set when it was called, got", got)
+	}
+	a.Add(3)
+	if got := a.HeadSet(3).ToSlice(); len(got) != 2 || got[1] != 2 {
+		t.Error("HeadSet(3) should be 1, 2, got", got)
+	}
+	if got := a.TailSet(5).ToSlice(); len(got) != 2 || got[0] != 5 {
+		t.Error("TailSet(5) should be 5, 6, got", got)
+	}
+
+	d := a.Clone()
+	if v, ok := d.PopMax(); !ok || v != 6 || !a.Contains(6) {
+		t.Error("PopMax on a clone should return 6 and leave a alone")
+	}
+	if d.Equal(a) || d.Len() != 5 {
+		t.Error("the clone should have lost 6")
+	}
+
+	if n := a.RetainAll(b); n != 3 {
+		t.Error("RetainAll should remove 3 items, removed", n)
+	}
+	if a.RetainAll(a) != 0 || !a.Equal(b) {
+		t.Error("a should be left with b's items")
+	}
+	checkSortedSet(t, "with options", c.Snapshot(), 7, 8)
+}
+
+func Test_ConcurrentSortedSetWrapsEveryMethod(t *testing.T) {
+	set, wrapper := reflect.TypeOf(&ThingSortedSet{}), reflect.TypeOf(&ThingConcurrentSortedSet{})
+	for i := 0; i < set.NumMethod(); i++ {
+		if name := set.Method(i).Name; !hasMethod(wrapper, name) {
+			t.Errorf("ConcurrentSortedSet should wrap SortedSet's %s", name)
+		}
+	}
+}
+
+func hasMethod(t reflect.Type, name string) bool {
+	_, ok := t.MethodByName(name)
+	return ok
+}
+
+func Test_ConcurrentSortedSetParallel(t *testing.T) {
+	a := NewThingConcurrentSortedSet(func(a, b Thing) bool { return a < b })
+	b := NewThingConcurrentSortedSet(func(a, b Thing) bool { return a < b })
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				v := Thing(g*1000 + i)
+				a.Add(v)
+				b.Add(v)
+				a.Contains(v)
+				if i%10 == 0 {
+					a.Remove(v)
+				}
+				if i%100 == 0 {
+					// opposite orders must not deadlock
+					a.UnionWith(b)
+					b.IntersectWith(a)
+					a.RetainAll(b)
+					b.IsDisjoint(a)
+					a.ReverseEach(func(Thing) bool { return true })
+					a.TailSet(v).ToSlice()
+					it := a.Iterator()
+					for it.Next() {
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	a.UnionWith(b)
+	if !b.IsSubset(a) {
+		t.Error("b should be a subset of a after UnionWith")
+	}
+
+	n := 0
+	prev := Thing(-1)
+	a.Each(func(v Thing) bool {
+		if v <= prev {
+			t.Error("concurrent set doesn't iterate in order")
+		}
+		prev = v
+		n++
+		return true
+	})
+	if n != a.Len() {
+		t.Error("Len should agree with the number of items iterated")
+	}
+}
+
+func Test_ConcurrentSortedSetParallelReadsWithRand(t *testing.T) {
+	// readers only take the read lock, so copying the set for a snapshot
+	// must not draw from its rand, which every copy shares
+	less := func(a, b Thing) bool { return a < b }
+	a := NewThingConcurrentSortedSetWithOptions(less, ThingSortedSetWithRand(rand.New(rand.NewSource(1))))
+	b := NewThingConcurrentSortedSet(less)
+	for i := 0; i < 200; i++ {
+		a.Add(Thing(2 * i))
+		b.Add(Thing(3 * i))
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				it := a.Iterator()
+				for it.Next() {
+				}
+				a.Snapshot()
+				a.Union(b)
+				a.SymmetricDifference(b)
+				a.Clone()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if a.Len() != 200 || a.Union(b).Len() != 200+200-67 {
+		t.Error("parallel reads should leave the set as it was")
+	}
+}
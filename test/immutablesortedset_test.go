@@ -0,0 +1,283 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func checkImmutableSortedSet(t *testing.T, name string, s ThingImmutableSortedSet, expected ...Thing) {
+	got := s.ToSlice()
+	var reversed []Thing
+	s.ReverseEach(func(v Thing) bool {
+		reversed = append(reversed, v)
+		return true
+	})
+	ok := len(got) == len(expected) && len(reversed) == len(expected) && s.Len() == len(expected)
+	for i := 0; ok && i < len(expected); i++ {
+		ok = got[i] == expected[i] && reversed[len(expected)-1-i] == expected[i] && s.Contains(expected[i])
+	}
+	if !ok {
+		t.Errorf("%s should be %v, got %v (reversed %v, Len %d)", name, expected, got, reversed, s.Len())
+	}
+}
+
+func Test_ImmutableSortedSetWithWithout(t *testing.T) {
+	empty := NewThingImmutableSortedSet(func(a, b Thing) bool { return a < b })
+	a := empty.With(3).With(1).With(2)
+	b := a.With(5)
+	c := b.Without(1)
+	d := c.Without(7)
+
+	checkImmutableSortedSet(t, "empty", empty)
+	checkImmutableSortedSet(t, "a", a, 1, 2, 3)
+	checkImmutableSortedSet(t, "b", b, 1, 2, 3, 5)
+	checkImmutableSortedSet(t, "c", c, 2, 3, 5)
+	checkImmutableSortedSet(t, "d", d, 2, 3, 5)
+
+	if a.With(2).root != a.root {
+		t.Error("With an item already in the set should return the same set")
+	}
+	if d.root != c.root {
+		t.Error("Without an item not in the set should return the same set")
+	}
+
+	if v, ok := b.First(); !ok || v != 1 {
+		t.Error("First should be 1")
+	}
+	if v, ok := b.Last(); !ok || v != 5 {
+		t.Error("Last should be 5")
+	}
+	if _, ok := empty.First(); ok {
+		t.Error("an empty set has no first item")
+	}
+}
+
+func Test_ImmutableSortedSetVersions(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	s := NewThingImmutableSortedSet(func(a, b Thing) bool { return a < b })
+	present := map[int]bool{}
+
+	var versions []ThingImmutableSortedSet
+	var expected [][]Thing
+	for i := 0; i < 2000; i++ {
+		v := r.Intn(300)
+		if r.Intn(3) == 0 {
+			s = s.Without(Thing(v))
+			delete(present, v)
+		} else {
+			s = s.With(Thing(v))
+			present[v] = true
+		}
+		if i%100 == 0 {
+			var ints []int
+			for k := range present {
+				ints = append(ints, k)
+			}
+			sort.Ints(ints)
+			var items []Thing
+			for _, k := range ints {
+				items = append(items, Thing(k))
+			}
+			versions = append(versions, s)
+			expected = append(expected, items)
+		}
+	}
+
+	// every old version still holds exactly what it held when it was taken
+	for i := range versions {
+		checkImmutableSortedSet(t, "old version", versions[i], expected[i]...)
+	}
+}
+
+func Test_ImmutableSortedSetFromSlice(t *testing.T) {
+	a := NewThingImmutableSortedSetFromSortedSlice(func(a, b Thing) bool { return a < b }, []Thing{1, 2, 2, 4, 8, 8})
+	checkImmutableSortedSet(t, "FromSortedSlice", a, 1, 2, 4, 8)
+	checkImmutableSortedSet(t, "FromSortedSlice then With", a.With(3).Without(8), 1, 2, 3, 4)
+
+	b := NewThingImmutableSortedSetFromSlice(func(a, b Thing) bool { return a < b }, []Thing{9, 3, 3, 6})
+	checkImmutableSortedSet(t, "FromSlice", b, 3, 6, 9)
+}
+
+func Test_ImmutableSortedSetBuilder(t *testing.T) {
+	b := NewThingImmutableSortedSetBuilder(func(a, b Thing) bool { return a < b })
+	b.Add(2)
+	b.Add(1)
+	snapshot := b.Snapshot()
+
+	if b.Add(2) || !b.Add(3) || !b.Remove(1) || b.Remove(1) {
+		t.Error("builder should add and remove like a set")
+	}
+
+	checkImmutableSortedSet(t, "snapshot", snapshot, 1, 2)
+	checkImmutableSortedSet(t, "builder", b.Snapshot(), 2, 3)
+
+	if b.Len() != 2 || !b.Contains(3) {
+		t.Error("builder should hold 2 and 3")
+	}
+
+	c := snapshot.ToBuilder()
+	c.Add(7)
+	checkImmutableSortedSet(t, "ToBuilder", c.Snapshot(), 1, 2, 7)
+	checkImmutableSortedSet(t, "snapshot after ToBuilder", snapshot, 1, 2)
+}
+
+func Test_ImmutableSortedSetNeighbors(t *testing.T) {
+	a := NewThingImmutableSortedSetFromSortedSlice(func(a, b Thing) bool { return a < b }, []Thing{10, 20, 30})
+	cases := []struct {
+		v                             Thing
+		floor, ceiling, lower, higher Thing
+		hasFloor, hasCeiling          bool
+		hasLower, hasHigher           bool
+	}{
+		{5, 0, 10, 0, 10, false, true, false, true},
+		{10, 10, 10, 0, 20, true, true, false, true},
+		{15, 10, 20, 10, 20, true, true, true, true},
+		{30, 30, 30, 20, 0, true, true, true, false},
+		{35, 30, 0, 30, 0, true, false, true, false},
+	}
+	for _, c := range cases {
+		if v, ok := a.Floor(c.v); ok != c.hasFloor || ok && v != c.floor {
+			t.Errorf("Floor(%d) should be %d, %v; got %d, %v", c.v, c.floor, c.hasFloor, v, ok)
+		}
+		if v, ok := a.Ceiling(c.v); ok != c.hasCeiling || ok && v != c.ceiling {
+			t.Errorf("Ceiling(%d) should be %d, %v; got %d, %v", c.v, c.ceiling, c.hasCeiling, v, ok)
+		}
+		if v, ok := a.Lower(c.v); ok != c.hasLower || ok && v != c.lower {
+			t.Errorf("Lower(%d) should be %d, %v; got %d, %v", c.v, c.lower, c.hasLower, v, ok)
+		}
+		if v, ok := a.Higher(c.v); ok != c.hasHigher || ok && v != c.higher {
+			t.Errorf("Higher(%d) should be %d, %v; got %d, %v", c.v, c.higher, c.hasHigher, v, ok)
+		}
+	}
+}
+
+func Test_ImmutableSortedSetIterator(t *testing.T) {
+	var s []Thing
+	for i := 0; i < 200; i += 2 {
+		s = append(s, Thing(i))
+	}
+	a := NewThingImmutableSortedSetFromSlice(func(a, b Thing) bool { return a < b }, s)
+	it := a.Iterator()
+
+	for v := -1; v < 199; v++ {
+		want := v + v%2
+		if v < 0 {
+			want = 0
+		}
+		if !it.Seek(Thing(v)) || it.Value() != Thing(want) {
+			t.Fatalf("Seek(%d) should move to %d", v, want)
+		}
+		// and walk on both ways from there
+		if want > 0 && (!it.Prev() || it.Value() != Thing(want-2) || !it.Next()) {
+			t.Fatalf("Prev after Seek(%d) should move to %d", v, want-2)
+		}
+		if want < 198 && (!it.Next() || it.Value() != Thing(want+2)) {
+			t.Fatalf("Next after Seek(%d) should move to %d", v, want+2)
+		}
+	}
+	if it.Seek(199) {
+		t.Error("Seek past the last item should return false")
+	}
+	if !it.Prev() || it.Value() != 198 {
+		t.Error("Prev after seeking past the end should move to the last item")
+	}
+
+	rit := a.ReverseIterator()
+	for i := len(s) - 1; i >= 0; i-- {
+		if !rit.Prev() || rit.Value() != s[i] {
+			t.Fatalf("ReverseIterator should move to %d", s[i])
+		}
+	}
+	if rit.Prev() || !rit.Next() || rit.Value() != 0 {
+		t.Error("ReverseIterator should stop before the first item and walk on from there")
+	}
+}
+
+func Test_ImmutableSortedSetViews(t *testing.T) {
+	a := NewThingImmutableSortedSetFromSortedSlice(func(a, b Thing) bool { return a < b }, []Thing{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	check := func(name string, v ThingImmutableSortedSetView, expected ...Thing) {
+		if v.Cardinality() != len(expected) {
+			t.Errorf("%s should have %d items, has %d", name, len(expected), v.Cardinality())
+		}
+		checkImmutableSortedSet(t, name, v.ToSet(), expected...)
+	}
+	check("Range[3,6]", a.Range(3, 6, true, true), 3, 4, 5, 6)
+	check("Range(3,6)", a.Range(3, 6, false, false), 4, 5)
+	check("HeadSet(4)", a.HeadSet(4), 1, 2, 3)
+	check("TailSet(7)", a.TailSet(7), 7, 8, 9)
+	check("Range(9,10)", a.Range(9, 10, false, true))
+
+	// a view of an old version doesn't change with newer ones
+	v := a.TailSet(7)
+	a = a.Without(8).With(10)
+	check("TailSet(7) of the old version", v, 7, 8, 9)
+	check("TailSet(7) of the new version", a.TailSet(7), 7, 9, 10)
+}
+
+func Test_ImmutableSortedSetBuilderAsSet(t *testing.T) {
+	b := NewThingImmutableSortedSetBuilder(func(a, b Thing) bool { return a < b })
+	for _, v := range []Thing{5, 1, 9, 3, 7} {
+		b.Add(v)
+	}
+	if v, ok := b.First(); !ok || v != 1 {
+		t.Error("First should be 1")
+	}
+	if v, ok := b.Last(); !ok || v != 9 {
+		t.Error("Last should be 9")
+	}
+	if v, ok := b.Floor(4); !ok || v != 3 {
+		t.Error("Floor(4) should be 3")
+	}
+	if v, ok := b.Ceiling(4); !ok || v != 5 {
+		t.Error("Ceiling(4) should be 5")
+	}
+	if v, ok := b.Lower(5); !ok || v != 3 {
+		t.Error("Lower(5) should be 3")
+	}
+	if v, ok := b.Higher(5); !ok || v != 7 {
+		t.Error("Higher(5) should be 7")
+	}
+	if got := b.Range(3, 7, true, false).ToSlice(); len(got) != 2 || got[0] != 3 || got[1] != 5 {
+		t.Errorf("Range[3,7) should be [3 5], got %v", got)
+	}
+	if b.Cardinality() != 5 || b.HeadSet(5).Cardinality() != 2 || b.TailSet(5).Cardinality() != 3 {
+		t.Error("the builder should hold 5 items, 2 below 5")
+	}
+
+	// the builder can be changed while it is being walked
+	it := b.Iterator()
+	var got []Thing
+	b.Each(func(v Thing) bool {
+		got = append(got, v)
+		b.Remove(v)
+		return true
+	})
+	if len(got) != 5 || b.Len() != 0 {
+		t.Errorf("Each should visit the 5 items it started with and remove them all, got %v", got)
+	}
+	var walked []Thing
+	for it.Next() {
+		walked = append(walked, it.Value())
+	}
+	if len(walked) != 5 || walked[0] != 1 || walked[4] != 9 {
+		t.Errorf("an iterator should walk the items as they were when it was made, got %v", walked)
+	}
+
+	b.Add(2)
+	snapshot := b.Snapshot()
+	b.Clear()
+	b.Add(4)
+	var reversed []Thing
+	b.ReverseEach(func(v Thing) bool {
+		reversed = append(reversed, v)
+		return true
+	})
+	if rit := b.ReverseIterator(); !rit.Prev() || rit.Value() != 4 || len(reversed) != 1 || reversed[0] != 4 {
+		t.Error("after Clear the builder should hold only 4")
+	}
+	checkImmutableSortedSet(t, "snapshot before Clear", snapshot, 2)
+	if got := b.ToSlice(); len(got) != 1 || got[0] != 4 {
+		t.Errorf("ToSlice should be [4], got %v", got)
+	}
+}
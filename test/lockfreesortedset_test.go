@@ -0,0 +1,199 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_LockFreeSortedSet(t *testing.T) {
+	a := NewThingLockFreeSortedSet(func(a, b Thing) bool { return a < b })
+
+	if a.Len() != 0 || a.Contains(1) {
+		t.Error("NewThingLockFreeSortedSet should start out as an empty set")
+	}
+
+	for _, v := range []Thing{5, 1, 4, 2, 3} {
+		if !a.Add(v) {
+			t.Error("Add should add", v)
+		}
+	}
+	if a.Add(3) {
+		t.Error("Add should not add a duplicate")
+	}
+
+	if !a.Remove(4) || a.Remove(4) || a.Contains(4) {
+		t.Error("Remove should remove 4 once")
+	}
+
+	got := a.ToSlice()
+	if a.Len() != 4 || len(got) != 4 || got[0] != 1 || got[1] != 2 || got[2] != 3 || got[3] != 5 {
+		t.Error("set should be [1 2 3 5], got", got)
+	}
+
+	it := a.Iterator()
+	if !it.Seek(4) || it.Value() != 5 || it.Next() {
+		t.Error("Seek(4) should land on 5, the last item")
+	}
+}
+
+// Test_LockFreeSortedSetStress is meant to be run with -race.
+func Test_LockFreeSortedSetStress(t *testing.T) {
+	const goroutines = 8
+	const n = 2000
+	a := NewThingLockFreeSortedSet(func(a, b Thing) bool { return a < b })
+
+	// every goroutine adds every item, but each item is only added once
+	var added int64
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				if a.Add(Thing((i*7 + g*13) % n)) {
+					atomic.AddInt64(&added, 1)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if added != n || a.Len() != n {
+		t.Fatalf("%d items should have been added once each, got %d adds and Len %d", n, added, a.Len())
+	}
+
+	// every goroutine removes the even items while others read the odd ones
+	var removed int64
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				v := Thing((i*11 + g*17) % n)
+				if v%2 == 0 {
+					if a.Remove(v) {
+						atomic.AddInt64(&removed, 1)
+					}
+				} else if !a.Contains(v) {
+					t.Errorf("odd item %d should stay in the set", v)
+				}
+			}
+			it := a.Iterator()
+			prev := Thing(-1)
+			for it.Next() {
+				if it.Value() <= prev {
+					t.Error("lock-free set doesn't iterate in order")
+				}
+				prev = it.Value()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if removed != n/2 || a.Len() != n/2 {
+		t.Fatalf("%d items should have been removed once each, got %d removes and Len %d", n/2, removed, a.Len())
+	}
+
+	i := Thing(1)
+	a.Each(func(v Thing) bool {
+		if v != i {
+			t.Fatalf("expected %d, got %d", i, v)
+		}
+		i += 2
+		return true
+	})
+
+	// add and remove the same items from every goroutine at once
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				v := Thing(n + i%50)
+				if g%2 == 0 {
+					a.Add(v)
+				} else {
+					a.Remove(v)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	count := 0
+	a.Each(func(v Thing) bool {
+		count++
+		return true
+	})
+	if count != a.Len() {
+		t.Errorf("Len %d should agree with the %d items iterated", a.Len(), count)
+	}
+}
+
+func Test_LockFreeSortedSetSearchDoesNotAllocate(t *testing.T) {
+	a := NewThingLockFreeSortedSet(func(a, b Thing) bool { return a < b })
+	for i := 0; i < 1000; i += 2 {
+		a.Add(Thing(i))
+	}
+	it := a.Iterator()
+	for name, f := range map[string]func(){
+		"Contains":         func() { a.Contains(500) },
+		"Contains missing": func() { a.Contains(501) },
+		"Add existing":     func() { a.Add(500) },
+		"Remove missing":   func() { a.Remove(501) },
+		"Seek":             func() { it.Seek(501) },
+	} {
+		if allocs := testing.AllocsPerRun(100, f); allocs > 0 {
+			t.Errorf("%s should not allocate, made %v allocations", name, allocs)
+		}
+	}
+}
+
+func Test_LockFreeSortedSetHeight(t *testing.T) {
+	// searches start at the highest level in use, which stays near log2(n)
+	a := NewThingLockFreeSortedSet(func(a, b Thing) bool { return a < b })
+	if l := a.level.Load(); l != 1 {
+		t.Errorf("an empty set should search 1 level, searches %d", l)
+	}
+	for i := 0; i < 10; i++ {
+		a.Add(Thing(i))
+	}
+	if l := a.level.Load(); l > 8 {
+		t.Errorf("a set of 10 items should search at most 8 levels, searches %d", l)
+	}
+}
+
+func benchmarkLockFreeSortedSetContains(b *testing.B, n int) {
+	a := NewThingLockFreeSortedSet(func(a, b Thing) bool { return a < b })
+	for i := 0; i < n; i++ {
+		a.Add(Thing(2 * i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.Contains(Thing(i % (2 * n)))
+	}
+}
+
+func Benchmark_LockFreeSortedSetContains10(b *testing.B)     { benchmarkLockFreeSortedSetContains(b, 10) }
+func Benchmark_LockFreeSortedSetContains1000(b *testing.B)   { benchmarkLockFreeSortedSetContains(b, 1000) }
+func Benchmark_LockFreeSortedSetContains100000(b *testing.B) { benchmarkLockFreeSortedSetContains(b, 100000) }
+
+func benchmarkLockFreeSortedSetAddRemove(b *testing.B, n int) {
+	a := NewThingLockFreeSortedSet(func(a, b Thing) bool { return a < b })
+	for i := 0; i < n; i++ {
+		a.Add(Thing(2 * i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := Thing(2*(i%n) + 1)
+		a.Add(v)
+		a.Remove(v)
+	}
+}
+
+func Benchmark_LockFreeSortedSetAddRemove10(b *testing.B)     { benchmarkLockFreeSortedSetAddRemove(b, 10) }
+func Benchmark_LockFreeSortedSetAddRemove1000(b *testing.B)   { benchmarkLockFreeSortedSetAddRemove(b, 1000) }
+func Benchmark_LockFreeSortedSetAddRemove100000(b *testing.B) { benchmarkLockFreeSortedSetAddRemove(b, 100000) }
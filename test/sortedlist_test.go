@@ -0,0 +1,202 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func makeSortedList(ints []int) *ThingSortedList {
+	sl := NewThingSortedList(func(a, b Thing) bool { return a < b })
+	for _, i := range ints {
+		sl.Add(Thing(i))
+	}
+	return sl
+}
+
+func Test_NewSortedList(t *testing.T) {
+	sl := NewThingSortedList(func(a, b Thing) bool { return a < b })
+
+	if sl.Len() != 0 {
+		t.Error("NewThingSortedList should start out empty")
+	}
+
+	if sl.Rank(5) != 0 || sl.IndexOf(5) != -1 {
+		t.Error("an empty list has no ranks or indexes")
+	}
+}
+
+func Test_SortedListAt(t *testing.T) {
+	sl := makeSortedList([]int{50, 10, 40, 20, 30, 20})
+
+	expected := []Thing{10, 20, 20, 30, 40, 50}
+	for i, v := range expected {
+		if sl.At(i) != v {
+			t.Errorf("At(%d) should be %d, got %d", i, v, sl.At(i))
+		}
+	}
+}
+
+func Test_SortedListAtOutOfRange(t *testing.T) {
+	sl := makeSortedList([]int{1, 2})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("At should panic when the index is out of range")
+		}
+	}()
+	sl.At(2)
+}
+
+func Test_SortedListRankIndexOf(t *testing.T) {
+	sl := makeSortedList([]int{10, 20, 20, 30})
+
+	if sl.Rank(5) != 0 || sl.Rank(20) != 1 || sl.Rank(25) != 3 || sl.Rank(99) != 4 {
+		t.Error("Rank should count the items less than v")
+	}
+
+	if sl.IndexOf(20) != 1 || sl.IndexOf(30) != 3 || sl.IndexOf(25) != -1 {
+		t.Error("IndexOf should return the index of the first copy or -1")
+	}
+
+	if !sl.Contains(10) || sl.Contains(11) {
+		t.Error("list should contain 10 and not 11")
+	}
+}
+
+func Test_SortedListRemove(t *testing.T) {
+	sl := makeSortedList([]int{3, 1, 2, 2})
+
+	if !sl.Remove(2) || sl.Len() != 3 || sl.IndexOf(2) != 1 {
+		t.Error("Remove should remove a single copy of 2")
+	}
+
+	if sl.Remove(7) {
+		t.Error("Remove should return false for an item not in the list")
+	}
+
+	if v := sl.RemoveAt(0); v != 1 {
+		t.Error("RemoveAt(0) should remove the smallest item, got", v)
+	}
+
+	if sl.Len() != 2 || sl.At(0) != 2 || sl.At(1) != 3 {
+		t.Error("list should be [2 3] after removals")
+	}
+}
+
+func Test_SortedListSlice(t *testing.T) {
+	sl := makeSortedList([]int{5, 3, 9, 1, 7})
+
+	s := sl.Slice(1, 4)
+	if len(s) != 3 || s[0] != 3 || s[1] != 5 || s[2] != 7 {
+		t.Error("Slice(1, 4) should be [3 5 7], got", s)
+	}
+
+	if len(sl.Slice(2, 2)) != 0 {
+		t.Error("Slice(2, 2) should be empty")
+	}
+
+	if len(sl.Slice(0, sl.Len())) != 5 {
+		t.Error("Slice(0, Len()) should return every item")
+	}
+}
+
+func Test_SortedListEach(t *testing.T) {
+	sl := makeSortedList([]int{4, 3, 2, 1})
+
+	sl.Each(func(i int, v Thing) bool {
+		if Thing(i+1) != v {
+			t.Error("sorted list doesn't iterate in order")
+		}
+		return true
+	})
+}
+
+func Test_SortedListMatchesSortedSlice(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	sl := NewThingSortedList(func(a, b Thing) bool { return a < b })
+	var ref []int
+
+	for n := 0; n < 3000; n++ {
+		if len(ref) > 0 && r.Intn(3) == 0 {
+			i := r.Intn(len(ref))
+			if v := sl.RemoveAt(i); int(v) != ref[i] {
+				t.Fatalf("RemoveAt(%d) returned %d, expected %d", i, v, ref[i])
+			}
+			ref = append(ref[:i], ref[i+1:]...)
+		} else {
+			v := r.Intn(500)
+			sl.Add(Thing(v))
+			ref = append(ref, v)
+			sort.Ints(ref)
+		}
+	}
+
+	if sl.Len() != len(ref) {
+		t.Fatal("list has the wrong length")
+	}
+	for i, v := range ref {
+		if int(sl.At(i)) != v {
+			t.Fatalf("At(%d) is %d, expected %d", i, sl.At(i), v)
+		}
+	}
+	for v := 0; v < 500; v++ {
+		if sl.Rank(Thing(v)) != sort.SearchInts(ref, v) {
+			t.Fatalf("Rank(%d) is wrong", v)
+		}
+	}
+}
+
+func Test_SortedListClear(t *testing.T) {
+	sl := makeSortedList([]int{2, 5, 9})
+
+	sl.Clear()
+
+	if sl.Len() != 0 || sl.Contains(5) {
+		t.Error("list should be empty after Clear")
+	}
+
+	sl.Add(1)
+	if sl.At(0) != 1 {
+		t.Error("list should be usable after Clear")
+	}
+}
+
+func Test_SortedListRegrows(t *testing.T) {
+	// levels emptied by removals come back into use with the right widths
+	sl := makeSortedList(nil)
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 200; i++ {
+			sl.Add(Thing(i))
+		}
+		for i := 0; i < 200; i++ {
+			if sl.At(i) != Thing(i) || sl.Rank(Thing(i)) != i {
+				t.Fatalf("round %d: At(%d) is %d and Rank(%d) is %d", round, i, sl.At(i), i, sl.Rank(Thing(i)))
+			}
+		}
+		for sl.Len() > 0 {
+			sl.RemoveAt(sl.Len() / 2)
+		}
+		if sl.level != 1 {
+			t.Fatalf("an empty list should search 1 level, searches %d", sl.level)
+		}
+	}
+}
+
+func Test_SortedListSearchDoesNotAllocate(t *testing.T) {
+	sl := makeSortedList(nil)
+	for i := 0; i < 1000; i += 2 {
+		sl.Add(Thing(i))
+	}
+	for name, f := range map[string]func(){
+		"At":             func() { sl.At(250) },
+		"Rank":           func() { sl.Rank(501) },
+		"IndexOf":        func() { sl.IndexOf(500) },
+		"Contains":       func() { sl.Contains(501) },
+		"Remove missing": func() { sl.Remove(501) },
+	} {
+		if allocs := testing.AllocsPerRun(100, f); allocs > 0 {
+			t.Errorf("%s should not allocate, made %v allocations", name, allocs)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func makeSortedMap(ints []int) *ThingSortedMap {
+	m := NewThingSortedMap(func(a, b Thing) bool { return a < b })
+	for _, i := range ints {
+		m.Put(Thing(i), string(rune('a'+i)))
+	}
+	return m
+}
+
+func Test_NewSortedMap(t *testing.T) {
+	m := NewThingSortedMap(func(a, b Thing) bool { return a < b })
+
+	if m.Len() != 0 {
+		t.Error("NewThingSortedMap should start out as an empty map")
+	}
+}
+
+func Test_SortedMapPutGet(t *testing.T) {
+	m := makeSortedMap([]int{5, 1, 3})
+
+	if m.Len() != 3 {
+		t.Error("map should have 3 entries")
+	}
+
+	if v, ok := m.Get(3); !ok || v != "d" {
+		t.Error("map should have \"d\" stored for 3")
+	}
+
+	if _, ok := m.Get(4); ok {
+		t.Error("map should not have an entry for 4")
+	}
+
+	if m.Put(3, "three") {
+		t.Error("Put should return false when overwriting an existing key")
+	}
+
+	if v, _ := m.Get(3); v != "three" {
+		t.Error("Put should overwrite the value stored for 3")
+	}
+
+	if m.Len() != 3 {
+		t.Error("overwriting a key should not change the length")
+	}
+}
+
+func Test_SortedMapContainsKey(t *testing.T) {
+	m := makeSortedMap([]int{2, 4})
+
+	if !(m.ContainsKey(2) && m.ContainsKey(4)) {
+		t.Error("map should contain keys 2 and 4")
+	}
+
+	if m.ContainsKey(3) {
+		t.Error("map should not contain key 3")
+	}
+}
+
+func Test_SortedMapDelete(t *testing.T) {
+	m := makeSortedMap([]int{6, 3, 1})
+
+	if !m.Delete(3) {
+		t.Error("Delete should return true for a key in the map")
+	}
+
+	if m.Delete(3) {
+		t.Error("Delete should return false for a key no longer in the map")
+	}
+
+	if m.Len() != 2 || m.ContainsKey(3) {
+		t.Error("map should only have keys 1 and 6")
+	}
+
+	m.Delete(1)
+	m.Delete(6)
+
+	if m.Len() != 0 {
+		t.Error("map should be empty after deleting every key")
+	}
+}
+
+func Test_SortedMapKeysValues(t *testing.T) {
+	m := makeSortedMap([]int{4, 0, 2, 9, 7})
+
+	keys := m.Keys()
+	values := m.Values()
+	expected := []Thing{0, 2, 4, 7, 9}
+
+	if len(keys) != len(expected) || len(values) != len(expected) {
+		t.Fatal("Keys and Values should have one item per entry")
+	}
+
+	for i, k := range expected {
+		if keys[i] != k {
+			t.Error("Keys should be in ascending order")
+		}
+		if values[i] != string(rune('a'+int(k))) {
+			t.Error("Values should be in ascending order of their keys")
+		}
+	}
+}
+
+func Test_SortedMapEach(t *testing.T) {
+	m := makeSortedMap([]int{3, 1, 2, 5, 4})
+
+	i := Thing(1)
+	m.Each(func(k Thing, v string) bool {
+		if k != i {
+			t.Error("sorted map doesn't iterate in order")
+		}
+		i++
+		return k < 3
+	})
+
+	if i != 4 {
+		t.Error("Each should stop once the callback returns false")
+	}
+}
+
+func Test_SortedMapClear(t *testing.T) {
+	m := makeSortedMap([]int{2, 5, 9, 10})
+
+	m.Clear()
+
+	if m.Len() != 0 || m.ContainsKey(5) {
+		t.Error("map should be empty after Clear")
+	}
+}
+
+func Test_SortedMapAgreesWithMap(t *testing.T) {
+	m := makeSortedMap(nil)
+	expected := make(map[Thing]string)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		k := Thing(r.Intn(200))
+		if r.Intn(2) == 0 {
+			_, found := expected[k]
+			if m.Delete(k) != found {
+				t.Fatalf("Delete(%v) should return %v", k, found)
+			}
+			delete(expected, k)
+		} else {
+			_, found := expected[k]
+			if m.Put(k, string(rune('a'+i%26))) == found {
+				t.Fatalf("Put(%v) should return %v", k, !found)
+			}
+			expected[k] = string(rune('a' + i%26))
+		}
+	}
+	if m.Len() != len(expected) {
+		t.Fatalf("map should have %d entries, has %d", len(expected), m.Len())
+	}
+	for k, v := range expected {
+		if got, ok := m.Get(k); !ok || got != v {
+			t.Errorf("Get(%v) should return %q, got %q", k, v, got)
+		}
+	}
+}
+
+func Test_SortedMapSearchDoesNotAllocate(t *testing.T) {
+	m := makeSortedMap(nil)
+	for i := 0; i < 1000; i += 2 {
+		m.Put(Thing(i), "a")
+	}
+	for name, f := range map[string]func(){
+		"Get":            func() { m.Get(500) },
+		"Get missing":    func() { m.Get(501) },
+		"ContainsKey":    func() { m.ContainsKey(500) },
+		"Put existing":   func() { m.Put(500, "b") },
+		"Delete missing": func() { m.Delete(501) },
+	} {
+		if allocs := testing.AllocsPerRun(100, f); allocs > 0 {
+			t.Errorf("%s should not allocate, made %v allocations", name, allocs)
+		}
+	}
+}
+
+func Test_SortedMapHeight(t *testing.T) {
+	// searches start at the highest level in use, which stays near log2(n)
+	m := makeSortedMap([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	if m.level > 8 {
+		t.Errorf("a map of 10 entries should search at most 8 levels, searches %d", m.level)
+	}
+	for i := 0; i < 10; i++ {
+		m.Delete(Thing(i))
+	}
+	if m.level != 0 {
+		t.Errorf("an empty map should search no levels, searches %d", m.level)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"testing"
+)
+
+// byTens treats items as equal when they share a tens digit, so tests can
+// tell equal copies apart.
+func byTens(a, b Thing) bool { return a/10 < b/10 }
+
+func makeSortedMultiSet(ints []int) *ThingSortedMultiSet {
+	ms := NewThingSortedMultiSet(func(a, b Thing) bool { return a < b })
+	for _, i := range ints {
+		ms.Add(Thing(i))
+	}
+	return ms
+}
+
+func Test_NewSortedMultiSet(t *testing.T) {
+	ms := NewThingSortedMultiSet(func(a, b Thing) bool { return a < b })
+
+	if ms.Len() != 0 {
+		t.Error("NewThingSortedMultiSet should start out empty")
+	}
+}
+
+func Test_SortedMultiSetKeepsDuplicates(t *testing.T) {
+	ms := makeSortedMultiSet([]int{7, 5, 3, 7, 7})
+
+	if ms.Len() != 5 {
+		t.Error("multiset should keep all 5 items, duplicates included")
+	}
+
+	if ms.Count(7) != 3 || ms.Count(5) != 1 || ms.Count(4) != 0 {
+		t.Error("multiset should have three 7s, one 5 and no 4s")
+	}
+
+	if !ms.Contains(3) || ms.Contains(4) {
+		t.Error("multiset should contain 3 and not 4")
+	}
+}
+
+func Test_SortedMultiSetInsertionOrder(t *testing.T) {
+	ms := NewThingSortedMultiSet(byTens)
+	for _, i := range []Thing{15, 31, 12, 3, 18, 11} {
+		ms.Add(i)
+	}
+
+	expected := []Thing{3, 15, 12, 18, 11, 31}
+	got := ms.ToSlice()
+	if len(got) != len(expected) {
+		t.Fatal("ToSlice should return every copy")
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("expected %v but got %v", expected, got)
+			break
+		}
+	}
+
+	i := 0
+	ms.Each(func(v Thing) bool {
+		if v != expected[i] {
+			t.Error("multiset doesn't iterate equal items in insertion order")
+		}
+		i++
+		return true
+	})
+	if i != len(expected) {
+		t.Error("Each should visit every copy")
+	}
+}
+
+func Test_SortedMultiSetRemoveOne(t *testing.T) {
+	ms := NewThingSortedMultiSet(byTens)
+	for _, i := range []Thing{15, 12, 18, 3} {
+		ms.Add(i)
+	}
+
+	if !ms.RemoveOne(10) {
+		t.Error("RemoveOne should remove a copy")
+	}
+
+	got := ms.ToSlice()
+	if len(got) != 3 || got[0] != 3 || got[1] != 12 || got[2] != 18 {
+		t.Error("RemoveOne should remove the earliest added copy, got", got)
+	}
+
+	if ms.RemoveOne(40) {
+		t.Error("RemoveOne should return false when there is no copy")
+	}
+}
+
+func Test_SortedMultiSetRemoveAll(t *testing.T) {
+	ms := makeSortedMultiSet([]int{1, 4, 4, 2, 4, 9, 4, 3})
+
+	if n := ms.RemoveAll(4); n != 4 {
+		t.Error("RemoveAll should remove 4 copies, removed", n)
+	}
+
+	if ms.Len() != 4 || ms.Contains(4) {
+		t.Error("multiset should have 4 items and no 4s left")
+	}
+
+	if n := ms.RemoveAll(4); n != 0 {
+		t.Error("RemoveAll should remove nothing the second time")
+	}
+
+	if !(ms.Contains(1) && ms.Contains(2) && ms.Contains(3) && ms.Contains(9)) {
+		t.Error("RemoveAll should leave the other items alone")
+	}
+}
+
+func Test_SortedMultiSetManyDuplicates(t *testing.T) {
+	ms := NewThingSortedMultiSet(func(a, b Thing) bool { return a < b })
+	for i := 0; i < 1000; i++ {
+		ms.Add(Thing(i % 10))
+	}
+
+	for i := Thing(0); i < 10; i++ {
+		if ms.Count(i) != 100 {
+			t.Error("multiset should have 100 copies of", i)
+		}
+	}
+
+	ms.RemoveAll(5)
+	for i := 0; i < 50; i++ {
+		ms.RemoveOne(7)
+	}
+
+	if ms.Len() != 850 || ms.Count(7) != 50 || ms.Count(6) != 100 {
+		t.Error("multiset has the wrong counts after removals")
+	}
+
+	prev := Thing(-1)
+	ms.Each(func(v Thing) bool {
+		if v < prev {
+			t.Error("multiset doesn't iterate in order")
+		}
+		prev = v
+		return true
+	})
+}
+
+func Test_SortedMultiSetClear(t *testing.T) {
+	ms := makeSortedMultiSet([]int{2, 2, 9})
+
+	ms.Clear()
+
+	if ms.Len() != 0 || ms.Contains(2) {
+		t.Error("multiset should be empty after Clear")
+	}
+}
+
+func Test_SortedMultiSetSearchDoesNotAllocate(t *testing.T) {
+	ms := makeSortedMultiSet(nil)
+	for i := 0; i < 1000; i += 2 {
+		ms.Add(Thing(i))
+	}
+	for name, f := range map[string]func(){
+		"Contains":          func() { ms.Contains(500) },
+		"Contains missing":  func() { ms.Contains(501) },
+		"Count":             func() { ms.Count(500) },
+		"RemoveOne missing": func() { ms.RemoveOne(501) },
+		"RemoveAll missing": func() { ms.RemoveAll(501) },
+	} {
+		if allocs := testing.AllocsPerRun(100, f); allocs > 0 {
+			t.Errorf("%s should not allocate, made %v allocations", name, allocs)
+		}
+	}
+}
+
+func Test_SortedMultiSetHeight(t *testing.T) {
+	// searches start at the highest level in use, which stays near log2(n)
+	ms := makeSortedMultiSet([]int{3, 3, 3, 3, 3, 7, 7, 7, 7, 7})
+	if ms.level > 8 {
+		t.Errorf("a multiset of 10 items should search at most 8 levels, searches %d", ms.level)
+	}
+	ms.RemoveAll(3)
+	for ms.RemoveOne(7) {
+	}
+	if ms.level != 0 {
+		t.Errorf("an empty multiset should search no levels, searches %d", ms.level)
+	}
+}
@@ -27,10 +27,12 @@ package main
 
 import (
 	"fmt"
+	"math/bits"
+	"math/rand"
 	"testing"
 )
 
-func makeSortedSet(ints []int) ThingSortedSet {
+func makeSortedSet(ints []int) *ThingSortedSet {
 	set := NewThingSortedSet(func(a, b Thing) bool { return a < b })
 	for _, i := range ints {
 		set.Add(Thing(i))
@@ -38,131 +40,275 @@ func makeSortedSet(ints []int) ThingSortedSet {
 	return set
 }
 
-func Test_NewSortedSet(t *testing.T) {
-	a := NewThingSortedSet(func(a, b Thing) bool { return a < b })
+// sortedThingSet is the part of the method set the sorted sets share that
+// doesn't take another set, so the tests that only use it run against all
+// of them.
+type sortedThingSet interface {
+	Add(v Thing) bool
+	AddAll(items ...Thing) int
+	Remove(v Thing) bool
+	RemoveAll(items ...Thing) int
+	RemoveIf(pred func(v Thing) bool) int
+	Clear()
+	Contains(v Thing) bool
+	ContainsAll(i ...Thing) bool
+	Floor(v Thing) (Thing, bool)
+	Ceiling(v Thing) (Thing, bool)
+	Lower(v Thing) (Thing, bool)
+	Higher(v Thing) (Thing, bool)
+	Cardinality() int
+	Len() int
+	Each(f func(v Thing) bool)
+	ReverseEach(f func(v Thing) bool)
+	First() (Thing, bool)
+	Last() (Thing, bool)
+	PopFirst() (Thing, bool)
+	PopLast() (Thing, bool)
+	PopMax() (Thing, bool)
+	PopN(k int) []Thing
+	Iter() <-chan Thing
+}
+
+// sortedThingCursor is the method set of the sets' iterators.
+type sortedThingCursor interface {
+	Next() bool
+	Prev() bool
+	Seek(v Thing) bool
+	Value() Thing
+}
+
+// sortedThingSetImpl is a set under test. Its iterators and invariant
+// check are funcs, as each set has its own types for them.
+type sortedThingSetImpl struct {
+	name            string
+	new             func() sortedThingSet
+	iterator        func(s sortedThingSet) sortedThingCursor
+	reverseIterator func(s sortedThingSet) sortedThingCursor
+	check           func(t *testing.T, name string, s sortedThingSet, expected ...Thing)
+}
 
-	if a.Cardinality() != 0 {
-		t.Error("NewThingSortedSet should start out as an empty set")
+func (impl sortedThingSetImpl) make(ints []int) sortedThingSet {
+	set := impl.new()
+	for _, i := range ints {
+		set.Add(Thing(i))
 	}
+	return set
 }
 
-func Test_AddSortedSet(t *testing.T) {
-	a := makeSortedSet([]int{1, 2, 3})
+var sortedThingSetImpls = []sortedThingSetImpl{
+	{
+		name: "SortedSet",
+		new: func() sortedThingSet {
+			return NewThingSortedSet(func(a, b Thing) bool { return a < b })
+		},
+		iterator: func(s sortedThingSet) sortedThingCursor {
+			return s.(*ThingSortedSet).Iterator()
+		},
+		reverseIterator: func(s sortedThingSet) sortedThingCursor {
+			return s.(*ThingSortedSet).ReverseIterator()
+		},
+		check: func(t *testing.T, name string, s sortedThingSet, expected ...Thing) {
+			checkSortedSet(t, name, s.(*ThingSortedSet), expected...)
+		},
+	},
+	{
+		name: "BTreeSortedSet",
+		new: func() sortedThingSet {
+			return NewThingBTreeSortedSet(func(a, b Thing) bool { return a < b })
+		},
+		iterator: func(s sortedThingSet) sortedThingCursor {
+			return s.(*ThingBTreeSortedSet).Iterator()
+		},
+		reverseIterator: func(s sortedThingSet) sortedThingCursor {
+			return s.(*ThingBTreeSortedSet).ReverseIterator()
+		},
+		check: func(t *testing.T, name string, s sortedThingSet, expected ...Thing) {
+			checkBTreeSortedSet(t, name, s.(*ThingBTreeSortedSet), expected...)
+		},
+	},
+	{
+		name: "TreeSortedSet",
+		new: func() sortedThingSet {
+			return NewThingTreeSortedSet(func(a, b Thing) bool { return a < b })
+		},
+		iterator: func(s sortedThingSet) sortedThingCursor {
+			return s.(*ThingTreeSortedSet).Iterator()
+		},
+		reverseIterator: func(s sortedThingSet) sortedThingCursor {
+			return s.(*ThingTreeSortedSet).ReverseIterator()
+		},
+		check: func(t *testing.T, name string, s sortedThingSet, expected ...Thing) {
+			checkTreeSortedSet(t, name, s.(*ThingTreeSortedSet), expected...)
+		},
+	},
+	{
+		name: "ConcurrentSortedSet",
+		new: func() sortedThingSet {
+			return NewThingConcurrentSortedSet(func(a, b Thing) bool { return a < b })
+		},
+		iterator: func(s sortedThingSet) sortedThingCursor {
+			return s.(*ThingConcurrentSortedSet).Iterator()
+		},
+		reverseIterator: func(s sortedThingSet) sortedThingCursor {
+			return s.(*ThingConcurrentSortedSet).ReverseIterator()
+		},
+		check: func(t *testing.T, name string, s sortedThingSet, expected ...Thing) {
+			checkSortedSet(t, name, s.(*ThingConcurrentSortedSet).Snapshot(), expected...)
+		},
+	},
+}
 
-	if a.Cardinality() != 3 {
-		t.Error("AddSet does not have a size of 3 even though 3 items were added to a new set")
+// eachSortedThingSet runs test against every set in sortedThingSetImpls, as a subtest of t.
+func eachSortedThingSet(t *testing.T, test func(t *testing.T, impl sortedThingSetImpl)) {
+	for _, impl := range sortedThingSetImpls {
+		impl := impl
+		t.Run(impl.name, func(t *testing.T) {
+			test(t, impl)
+		})
 	}
 }
 
+func Test_NewSortedSet(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.new()
+
+		if a.Cardinality() != 0 {
+			t.Error("a new set should start out empty")
+		}
+	})
+}
+
+func Test_AddSortedSet(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{1, 2, 3})
+
+		if a.Cardinality() != 3 {
+			t.Error("AddSet does not have a size of 3 even though 3 items were added to a new set")
+		}
+	})
+}
+
 func Test_AddSetNoDuplicate(t *testing.T) {
-	a := makeSortedSet([]int{7, 5, 3, 7})
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{7, 5, 3, 7})
 
-	if a.Cardinality() != 3 {
-		t.Error("AddSetNoDuplicate set should have 3 elements since 7 is a duplicate")
-	}
+		if a.Cardinality() != 3 {
+			t.Error("AddSetNoDuplicate set should have 3 elements since 7 is a duplicate")
+		}
 
-	if !(a.Contains(7) && a.Contains(5) && a.Contains(3)) {
-		t.Error("AddSetNoDuplicate set should have a 7, 5, and 3 in it.")
-	}
+		if !(a.Contains(7) && a.Contains(5) && a.Contains(3)) {
+			t.Error("AddSetNoDuplicate set should have a 7, 5, and 3 in it.")
+		}
+	})
 }
 
 func Test_RemoveSet(t *testing.T) {
-	a := makeSortedSet([]int{6, 3, 1})
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{6, 3, 1})
 
-	a.Remove(3)
+		a.Remove(3)
 
-	if a.Cardinality() != 2 {
-		t.Error("RemoveSet should only have 2 items in the set")
-	}
+		if a.Cardinality() != 2 {
+			t.Error("RemoveSet should only have 2 items in the set")
+		}
 
-	if !(a.Contains(6) && a.Contains(1)) {
-		t.Error("RemoveSet should have only items 6 and 1 in the set")
-	}
+		if !(a.Contains(6) && a.Contains(1)) {
+			t.Error("RemoveSet should have only items 6 and 1 in the set")
+		}
 
-	a.Remove(6)
-	a.Remove(1)
+		a.Remove(6)
+		a.Remove(1)
 
-	if a.Cardinality() != 0 {
-		t.Error("RemoveSet should be an empty set after removing 6 and 1")
-	}
+		if a.Cardinality() != 0 {
+			t.Error("RemoveSet should be an empty set after removing 6 and 1")
+		}
+	})
 }
 
 func Test_ContainsSortedSet(t *testing.T) {
-	a := NewThingSortedSet(func(a, b Thing) bool { return a < b })
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.new()
 
-	a.Add(71)
+		a.Add(71)
 
-	if !a.Contains(71) {
-		t.Error("ContainsSet should contain 71")
-	}
+		if !a.Contains(71) {
+			t.Error("ContainsSet should contain 71")
+		}
 
-	a.Remove(71)
+		a.Remove(71)
 
-	if a.Contains(71) {
-		t.Error("ContainsSet should not contain 71")
-	}
+		if a.Contains(71) {
+			t.Error("ContainsSet should not contain 71")
+		}
 
-	a.Add(13)
-	a.Add(7)
-	a.Add(1)
+		a.Add(13)
+		a.Add(7)
+		a.Add(1)
 
-	if !(a.Contains(13) && a.Contains(7) && a.Contains(1)) {
-		t.Error("ContainsSet should contain 13, 7, 1")
-	}
+		if !(a.Contains(13) && a.Contains(7) && a.Contains(1)) {
+			t.Error("ContainsSet should contain 13, 7, 1")
+		}
+	})
 }
 
 func Test_ContainsAllSet(t *testing.T) {
-	a := makeSortedSet([]int{8, 6, 7, 5, 3, 0, 9})
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{8, 6, 7, 5, 3, 0, 9})
 
-	if !a.ContainsAll(8, 6, 7, 5, 3, 0, 9) {
-		t.Error("ContainsAll should contain Jenny's phone number")
-	}
+		if !a.ContainsAll(8, 6, 7, 5, 3, 0, 9) {
+			t.Error("ContainsAll should contain Jenny's phone number")
+		}
 
-	if a.ContainsAll(8, 6, 11, 5, 3, 0, 9) {
-		t.Error("ContainsAll should not have all of these numbers")
-	}
+		if a.ContainsAll(8, 6, 11, 5, 3, 0, 9) {
+			t.Error("ContainsAll should not have all of these numbers")
+		}
+	})
 }
 
 func Test_ClearSet(t *testing.T) {
-	a := makeSortedSet([]int{2, 5, 9, 10})
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{2, 5, 9, 10})
 
-	a.Clear()
+		a.Clear()
 
-	if a.Cardinality() != 0 {
-		t.Error("ClearSet should be an empty set")
-	}
+		if a.Cardinality() != 0 {
+			t.Error("ClearSet should be an empty set")
+		}
+	})
 }
 
 func Test_CardinalitySortedSet(t *testing.T) {
-	a := NewThingSortedSet(func(a, b Thing) bool { return a < b })
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.new()
 
-	if a.Cardinality() != 0 {
-		t.Error("set should be an empty set")
-	}
+		if a.Cardinality() != 0 {
+			t.Error("set should be an empty set")
+		}
 
-	a.Add(1)
+		a.Add(1)
 
-	if a.Cardinality() != 1 {
-		t.Error("set should have a size of 1")
-	}
+		if a.Cardinality() != 1 {
+			t.Error("set should have a size of 1")
+		}
 
-	a.Remove(1)
+		a.Remove(1)
 
-	if a.Cardinality() != 0 {
-		t.Error("set should be an empty set")
-	}
+		if a.Cardinality() != 0 {
+			t.Error("set should be an empty set")
+		}
 
-	a.Add(9)
+		a.Add(9)
 
-	if a.Cardinality() != 1 {
-		t.Error("set should have a size of 1")
-	}
+		if a.Cardinality() != 1 {
+			t.Error("set should have a size of 1")
+		}
 
-	a.Clear()
+		a.Clear()
 
-	if a.Cardinality() != 0 {
-		t.Error("set should have a size of 1")
-	}
+		if a.Cardinality() != 0 {
+			t.Error("set should have a size of 1")
+		}
+	})
 }
 
 func Test_SetIsSubset(t *testing.T) {
@@ -386,41 +532,1020 @@ func Test_SortedSetClone(t *testing.T) {
 	if a.Equal(c) {
 		t.Error("C contains one element less, they should not be equal")
 	}
+
+	c.Add(4)
+	if a.Equal(c) || c.Equal(a) {
+		t.Error("sets of the same size with different items should not be equal")
+	}
+
+	d := NewThingSortedSetWithOptions(func(a, b Thing) bool { return a < b }, ThingSortedSetWithArena(4))
+	d.AddAll(5, 1, 9, 3, 7)
+	e := d.Clone()
+	checkSortedSet(t, "clone of a set with an arena", e, 1, 3, 5, 7, 9)
+	if e.arena == nil || e.arena == d.arena {
+		t.Error("a clone should have an arena of its own")
+	}
+	if !e.Equal(d) {
+		t.Error("a set with an arena and its clone should be equal")
+	}
 }
 
 func Test_SortedSetIterator(t *testing.T) {
-	a := NewThingSortedSet(func(a, b Thing) bool { return a < b })
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{4, 3, 2, 1})
+
+		i := Thing(1)
+		for val := range a.Iter() {
+			if val != i {
+				t.Error("sorted set doesn't iterate in order")
+			}
+			i++
+		}
 
-	a.Add(4)
-	a.Add(3)
-	a.Add(2)
-	a.Add(1)
+		b := impl.new()
+		for val := range a.Iter() {
+			b.Add(val)
+		}
+
+		impl.check(t, "the set filled from Iter", b, 1, 2, 3, 4)
+	})
+}
 
-	i := Thing(1)
-	for val := range a.Iter() {
-		if val != i {
-			t.Error("sorted set doesn't iterate in order")
+func Test_SortedSetFromSlice(t *testing.T) {
+	slc := []Thing{3, 2, 1, 4}
+	a := NewThingSortedSetFromSlice(func(a, b Thing) bool { return a < b }, slc)
+
+	for i := Thing(1); i <= 4; i++ {
+		if !a.Contains(i) {
+			t.Error(fmt.Sprintf("the set didn't contain %d", i))
 		}
-		i++
 	}
+}
 
+func Test_SortedSetFloorCeiling(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{10, 20, 30})
+
+		cases := []struct {
+			v                                         Thing
+			floor, ceiling, lower, higher             Thing
+			hasFloor, hasCeiling, hasLower, hasHigher bool
+		}{
+			{5, 0, 10, 0, 10, false, true, false, true},
+			{10, 10, 10, 0, 20, true, true, false, true},
+			{15, 10, 20, 10, 20, true, true, true, true},
+			{20, 20, 20, 10, 30, true, true, true, true},
+			{30, 30, 30, 20, 0, true, true, true, false},
+			{35, 30, 0, 30, 0, true, false, true, false},
+		}
+
+		for _, c := range cases {
+			if v, ok := a.Floor(c.v); ok != c.hasFloor || ok && v != c.floor {
+				t.Errorf("Floor(%d) = %d, %v", c.v, v, ok)
+			}
+			if v, ok := a.Ceiling(c.v); ok != c.hasCeiling || ok && v != c.ceiling {
+				t.Errorf("Ceiling(%d) = %d, %v", c.v, v, ok)
+			}
+			if v, ok := a.Lower(c.v); ok != c.hasLower || ok && v != c.lower {
+				t.Errorf("Lower(%d) = %d, %v", c.v, v, ok)
+			}
+			if v, ok := a.Higher(c.v); ok != c.hasHigher || ok && v != c.higher {
+				t.Errorf("Higher(%d) = %d, %v", c.v, v, ok)
+			}
+		}
+
+		b := impl.new()
+		if _, ok := b.Floor(1); ok {
+			t.Error("an empty set has no floor")
+		}
+		if _, ok := b.Higher(1); ok {
+			t.Error("an empty set has nothing higher")
+		}
+	})
+}
+
+func Test_SortedSetRange(t *testing.T) {
+	a := makeSortedSet([]int{1, 2, 3, 4, 5, 6, 7, 8, 9})
+
+	check := func(name string, got []Thing, expected ...Thing) {
+		if len(got) != len(expected) {
+			t.Errorf("%s should be %v, got %v", name, expected, got)
+			return
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("%s should be %v, got %v", name, expected, got)
+				return
+			}
+		}
+	}
+
+	check("Range(3, 6, true, true)", a.Range(3, 6, true, true).ToSlice(), 3, 4, 5, 6)
+	check("Range(3, 6, false, true)", a.Range(3, 6, false, true).ToSlice(), 4, 5, 6)
+	check("Range(3, 6, true, false)", a.Range(3, 6, true, false).ToSlice(), 3, 4, 5)
+	check("Range(3, 6, false, false)", a.Range(3, 6, false, false).ToSlice(), 4, 5)
+	check("Range(0, 100, true, true)", a.Range(0, 100, true, true).ToSlice(), 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	check("Range(6, 3, true, true)", a.Range(6, 3, true, true).ToSlice())
+	check("HeadSet(4)", a.HeadSet(4).ToSlice(), 1, 2, 3)
+	check("TailSet(7)", a.TailSet(7).ToSlice(), 7, 8, 9)
+
+	view := a.Range(2, 5, true, true)
+	if view.Cardinality() != 4 {
+		t.Error("view should have 4 items")
+	}
+
+	a.Remove(3)
+	a.Add(10)
+	check("view after Remove(3)", view.ToSlice(), 2, 4, 5)
+
+	b := view.ToSet()
+	if !(b.Cardinality() == 3 && b.ContainsAll(2, 4, 5)) {
+		t.Error("ToSet should copy the items in the view")
+	}
+
+	n := 0
+	a.TailSet(2).Each(func(v Thing) bool {
+		n++
+		return v < 5
+	})
+	if n != 3 {
+		t.Error("Each should stop once the callback returns false")
+	}
+}
+
+func Test_SortedSetCursor(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{4, 2, 8, 6})
+
+		it := impl.iterator(a)
+		if it.Prev() {
+			t.Error("Prev before the first item should return false")
+		}
+
+		var got []Thing
+		for it.Next() {
+			got = append(got, it.Value())
+		}
+		if len(got) != 4 || got[0] != 2 || got[1] != 4 || got[2] != 6 || got[3] != 8 {
+			t.Error("iterator should visit 2, 4, 6, 8 in order, got", got)
+		}
+		if it.Next() {
+			t.Error("Next past the last item should keep returning false")
+		}
+
+		got = got[:0]
+		for it.Prev() {
+			got = append(got, it.Value())
+		}
+		if len(got) != 4 || got[0] != 8 || got[3] != 2 {
+			t.Error("Prev from the end should visit 8, 6, 4, 2, got", got)
+		}
+		if !it.Next() || it.Value() != 2 {
+			t.Error("Next after moving before the first item should land on the first item")
+		}
+
+		if !it.Seek(5) || it.Value() != 6 {
+			t.Error("Seek(5) should land on 6")
+		}
+		if !it.Seek(4) || it.Value() != 4 {
+			t.Error("Seek(4) should land on 4")
+		}
+		if !it.Prev() || it.Value() != 2 {
+			t.Error("Prev after Seek(4) should land on 2")
+		}
+		if it.Seek(9) {
+			t.Error("Seek(9) should be past the last item")
+		}
+		if !it.Prev() || it.Value() != 8 {
+			t.Error("Prev after seeking past the end should land on the last item")
+		}
+
+		if impl.iterator(impl.new()).Next() {
+			t.Error("an empty set's iterator should have no items")
+		}
+	})
+}
+
+func Test_SortedSetEach(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{3, 1, 2, 5, 4})
+
+		i := Thing(1)
+		a.Each(func(v Thing) bool {
+			if v != i {
+				t.Error("sorted set doesn't iterate in order")
+			}
+			i++
+			return v < 3
+		})
+
+		if i != 4 {
+			t.Error("Each should stop once the callback returns false")
+		}
+	})
+}
+
+func Test_SortedSetReverse(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{5, 1, 4, 2, 3})
+		a.Remove(4)
+		a.Add(9)
+
+		expected := []Thing{9, 5, 3, 2, 1}
+		i := 0
+		a.ReverseEach(func(v Thing) bool {
+			if v != expected[i] {
+				t.Error("sorted set doesn't iterate in reverse order")
+			}
+			i++
+			return true
+		})
+		if i != len(expected) {
+			t.Error("ReverseEach should visit every item")
+		}
+
+		it := impl.reverseIterator(a)
+		i = 0
+		for it.Prev() {
+			if it.Value() != expected[i] {
+				t.Error("reverse iterator doesn't iterate in reverse order")
+			}
+			i++
+		}
+		if i != len(expected) {
+			t.Error("reverse iterator should visit every item")
+		}
+	})
+}
+
+func Test_SortedSetLastPopMax(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{3, 7, 1})
+
+		if v, ok := a.Last(); !ok || v != 7 {
+			t.Error("Last should be 7")
+		}
+
+		for _, expected := range []Thing{7, 3, 1} {
+			if v, ok := a.PopMax(); !ok || v != expected {
+				t.Errorf("PopMax should return %d, got %d", expected, v)
+			}
+		}
+
+		if a.Contains(1) || a.Contains(3) || a.Contains(7) {
+			t.Error("PopMax should remove the items it returns")
+		}
+
+		if _, ok := a.PopMax(); ok {
+			t.Error("PopMax on an empty set should return false")
+		}
+		if _, ok := a.Last(); ok {
+			t.Error("Last on an empty set should return false")
+		}
+	})
+}
+
+func Test_SortedSetFirstPopFirst(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{5, 3, 8, 1})
+
+		if v, ok := a.First(); !ok || v != 1 {
+			t.Error("First should be 1")
+		}
+
+		for _, expected := range []Thing{1, 3} {
+			if v, ok := a.PopFirst(); !ok || v != expected {
+				t.Errorf("PopFirst should return %d, got %d", expected, v)
+			}
+		}
+
+		if v, ok := a.PopLast(); !ok || v != 8 {
+			t.Error("PopLast should return 8, got", v)
+		}
+
+		if v, ok := a.First(); !ok || v != 5 || !a.Contains(5) || a.Contains(3) {
+			t.Error("only 5 should be left in the set")
+		}
+
+		a.PopFirst()
+		if _, ok := a.PopFirst(); ok {
+			t.Error("PopFirst on an empty set should return false")
+		}
+		if _, ok := a.First(); ok {
+			t.Error("First on an empty set should return false")
+		}
+	})
+}
+
+func Test_SortedSetPopN(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{9, 2, 7, 4, 5})
+
+		popped := a.PopN(3)
+		if len(popped) != 3 || popped[0] != 2 || popped[1] != 4 || popped[2] != 5 {
+			t.Error("PopN(3) should return 2, 4, 5, got", popped)
+		}
+
+		it := impl.reverseIterator(a)
+		if !it.Prev() || it.Value() != 9 || !it.Prev() || it.Value() != 7 || it.Prev() {
+			t.Error("7 and 9 should be left after PopN(3)")
+		}
+
+		if popped = a.PopN(5); len(popped) != 2 {
+			t.Error("PopN should return every item when there are fewer than k")
+		}
+
+		if popped = a.PopN(1); len(popped) != 0 {
+			t.Error("PopN on an empty set should return nothing")
+		}
+	})
+}
+
+func Test_SortedSetLengthTracking(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.new()
+
+		for i := 0; i < 100; i++ {
+			a.Add(Thing(i))
+			a.Add(Thing(i)) // duplicates must not be counted
+		}
+		if a.Len() != 100 || a.Cardinality() != 100 {
+			t.Error("set should have 100 items, has", a.Len())
+		}
+
+		for i := 0; i < 100; i += 2 {
+			a.Remove(Thing(i))
+		}
+		a.Remove(1000) // removing a missing item must not be counted
+		if a.Len() != 50 {
+			t.Error("set should have 50 items after removing the even ones, has", a.Len())
+		}
+
+		a.PopFirst()
+		a.PopMax()
+		if a.Len() != 48 {
+			t.Error("set should have 48 items after popping both ends, has", a.Len())
+		}
+
+		a.Clear()
+		if a.Len() != 0 {
+			t.Error("set should be empty after Clear")
+		}
+
+		a.Add(3)
+		a.Add(1)
+		if a.Len() != 2 {
+			t.Error("set should have 2 items after adding to a cleared set")
+		}
+
+		n := 0
+		a.Each(func(Thing) bool {
+			n++
+			return true
+		})
+		if n != a.Len() {
+			t.Error("Len should agree with the number of items iterated")
+		}
+	})
+}
+
+func Test_SortedSetSharedBetweenCallers(t *testing.T) {
+	a := makeSortedSet([]int{1, 2, 3})
+
+	addTo := func(s *ThingSortedSet) {
+		s.Add(4)
+		s.Add(5)
+	}
+	addTo(a)
+
+	if a.Len() != 5 {
+		t.Error("items added through another reference should be counted")
+	}
+
+	if v, ok := a.Last(); !ok || v != 5 {
+		t.Error("Last should see items added through another reference")
+	}
+}
+
+// checkSortedSet verifies a set's links on every level against the items
+// it is expected to hold.
+func checkSortedSet(t *testing.T, name string, s *ThingSortedSet, expected ...Thing) {
+	got := []Thing{}
+	s.Each(func(v Thing) bool {
+		got = append(got, v)
+		return true
+	})
+	ok := len(got) == len(expected) && s.Len() == len(expected)
+	for i := 0; ok && i < len(expected); i++ {
+		ok = got[i] == expected[i] && s.Contains(expected[i])
+	}
+	var reversed []Thing
+	s.ReverseEach(func(v Thing) bool {
+		reversed = append(reversed, v)
+		return true
+	})
+	ok = ok && len(reversed) == len(expected)
+	for i := 0; ok && i < len(expected); i++ {
+		ok = reversed[len(expected)-1-i] == expected[i]
+	}
+	if !ok {
+		t.Errorf("%s should be %v, got %v (reversed %v, Len %d)", name, expected, got, reversed, s.Len())
+	}
+	height := 0
+	for level, e := range s.head {
+		if e != nil {
+			height = level + 1
+		}
+	}
+	if s.level != height {
+		t.Errorf("%s should be %d levels high, got %d", name, height, s.level)
+	}
+}
+
+func Test_SortedSetMergeAlgebra(t *testing.T) {
+	a := makeSortedSet([]int{1, 3, 5, 7, 9, 11})
+	b := makeSortedSet([]int{0, 3, 4, 9, 12})
+	empty := NewThingSortedSet(func(a, b Thing) bool { return a < b })
+
+	checkSortedSet(t, "a.Union(b)", a.Union(b), 0, 1, 3, 4, 5, 7, 9, 11, 12)
+	checkSortedSet(t, "a.Intersect(b)", a.Intersect(b), 3, 9)
+	checkSortedSet(t, "a.Difference(b)", a.Difference(b), 1, 5, 7, 11)
+	checkSortedSet(t, "b.Difference(a)", b.Difference(a), 0, 4, 12)
+	checkSortedSet(t, "a.SymmetricDifference(b)", a.SymmetricDifference(b), 0, 1, 4, 5, 7, 11, 12)
+	checkSortedSet(t, "a.Union(empty)", a.Union(empty), 1, 3, 5, 7, 9, 11)
+	checkSortedSet(t, "empty.Intersect(a)", empty.Intersect(a))
+
+	// the inputs are left alone
+	checkSortedSet(t, "a", a, 1, 3, 5, 7, 9, 11)
+	checkSortedSet(t, "b", b, 0, 3, 4, 9, 12)
+}
+
+func Test_SortedSetInPlaceAlgebra(t *testing.T) {
+	b := makeSortedSet([]int{0, 3, 4, 9, 12})
+
+	a := makeSortedSet([]int{1, 3, 5, 7, 9, 11})
+	a.UnionWith(b)
+	checkSortedSet(t, "UnionWith", a, 0, 1, 3, 4, 5, 7, 9, 11, 12)
+	a.Add(6)
+	a.Remove(0)
+	checkSortedSet(t, "UnionWith then Add/Remove", a, 1, 3, 4, 5, 6, 7, 9, 11, 12)
+
+	a = makeSortedSet([]int{1, 3, 5, 7, 9, 11})
+	a.IntersectWith(b)
+	checkSortedSet(t, "IntersectWith", a, 3, 9)
+	a.Add(4)
+	checkSortedSet(t, "IntersectWith then Add", a, 3, 4, 9)
+
+	a = makeSortedSet([]int{1, 3, 5, 7, 9, 11})
+	a.DifferenceWith(b)
+	checkSortedSet(t, "DifferenceWith", a, 1, 5, 7, 11)
+	a.Remove(11)
+	checkSortedSet(t, "DifferenceWith then Remove", a, 1, 5, 7)
+
+	a.UnionWith(a)
+	checkSortedSet(t, "UnionWith itself", a, 1, 5, 7)
+	a.IntersectWith(a)
+	checkSortedSet(t, "IntersectWith itself", a, 1, 5, 7)
+	a.DifferenceWith(a)
+	checkSortedSet(t, "DifferenceWith itself", a)
+
+	checkSortedSet(t, "b", b, 0, 3, 4, 9, 12)
+}
+
+func Test_SortedSetLargeMerge(t *testing.T) {
+	a := NewThingSortedSet(func(a, b Thing) bool { return a < b })
 	b := NewThingSortedSet(func(a, b Thing) bool { return a < b })
-	for val := range a.Iter() {
-		b.Add(val)
+	var union, intersection []Thing
+	for i := 0; i < 3000; i++ {
+		if i%2 == 0 {
+			a.Add(Thing(i))
+		}
+		if i%3 == 0 {
+			b.Add(Thing(i))
+		}
+		if i%2 == 0 || i%3 == 0 {
+			union = append(union, Thing(i))
+		}
+		if i%6 == 0 {
+			intersection = append(intersection, Thing(i))
+		}
 	}
 
-	if !a.Equal(b) {
-		t.Error("The sets are not equal after iterating through the first set")
+	checkSortedSet(t, "large Union", a.Union(b), union...)
+	checkSortedSet(t, "large Intersect", a.Intersect(b), intersection...)
+
+	a.UnionWith(b)
+	checkSortedSet(t, "large UnionWith", a, union...)
+}
+
+func Test_SortedSetFromSortedSlice(t *testing.T) {
+	slc := []Thing{1, 2, 2, 3, 5, 5, 5, 8}
+	a := NewThingSortedSetFromSortedSlice(func(a, b Thing) bool { return a < b }, slc)
+
+	checkSortedSet(t, "FromSortedSlice", a, 1, 2, 3, 5, 8)
+
+	a.Add(4)
+	a.Remove(1)
+	a.Add(9)
+	checkSortedSet(t, "FromSortedSlice then Add/Remove", a, 2, 3, 4, 5, 8, 9)
+
+	checkSortedSet(t, "FromSortedSlice of nothing", NewThingSortedSetFromSortedSlice(func(a, b Thing) bool { return a < b }, nil))
+
+	var big []Thing
+	for i := 0; i < 5000; i++ {
+		big = append(big, Thing(i))
 	}
+	b := NewThingSortedSetFromSortedSlice(func(a, b Thing) bool { return a < b }, big)
+	checkSortedSet(t, "large FromSortedSlice", b, big...)
 }
 
-func Test_SortedSetFromSlice(t *testing.T) {
-	slc := []Thing{3, 2, 1, 4}
+func Test_SortedSetFromSliceUnsorted(t *testing.T) {
+	slc := []Thing{9, 3, 7, 3, 1, 9}
 	a := NewThingSortedSetFromSlice(func(a, b Thing) bool { return a < b }, slc)
 
-	for i := Thing(1); i <= 4; i++ {
-		if !a.Contains(i) {
-			t.Error(fmt.Sprintf("the set didn't contain %d", i))
+	checkSortedSet(t, "FromSlice", a, 1, 3, 7, 9)
+
+	if slc[0] != 9 || slc[5] != 9 {
+		t.Error("FromSlice should not reorder the caller's slice")
+	}
+}
+
+func Test_SortedSetRemoveReturnsBool(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{1, 2})
+
+		if !a.Remove(1) {
+			t.Error("Remove should return true for an item in the set")
+		}
+		if a.Remove(1) {
+			t.Error("Remove should return false for an item no longer in the set")
+		}
+		impl.check(t, "after Remove", a, 2)
+	})
+}
+
+func Test_SortedSetAddAllRemoveAll(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{2, 4, 6})
+
+		if n := a.AddAll(9, 1, 4, 3, 9, 10); n != 4 {
+			t.Error("AddAll should add 4 new items, added", n)
+		}
+		impl.check(t, "AddAll", a, 1, 2, 3, 4, 6, 9, 10)
+
+		if n := a.RemoveAll(10, 3, 5, 1, 3); n != 3 {
+			t.Error("RemoveAll should remove 3 items, removed", n)
+		}
+		impl.check(t, "RemoveAll", a, 2, 4, 6, 9)
+
+		if a.AddAll() != 0 || a.RemoveAll() != 0 {
+			t.Error("empty batches should do nothing")
+		}
+
+		b := impl.new()
+		var batch, expected []Thing
+		for i := 2999; i >= 0; i-- {
+			batch = append(batch, Thing(i))
+		}
+		for i := 0; i < 3000; i++ {
+			expected = append(expected, Thing(i))
+		}
+		if b.AddAll(batch...) != 3000 {
+			t.Error("AddAll should add every item of a large batch")
+		}
+		impl.check(t, "large AddAll", b, expected...)
+
+		var odds, evens []Thing
+		for i := 0; i < 3000; i++ {
+			if i%2 == 1 {
+				odds = append(odds, Thing(i))
+			} else {
+				evens = append(evens, Thing(i))
+			}
+		}
+		if b.RemoveAll(odds...) != 1500 {
+			t.Error("RemoveAll should remove every odd item of a large set")
+		}
+		impl.check(t, "large RemoveAll", b, evens...)
+	})
+}
+
+func Test_SortedSetRetainAll(t *testing.T) {
+	a := makeSortedSet([]int{1, 2, 3, 4, 5})
+	b := makeSortedSet([]int{2, 4, 8})
+
+	if n := a.RetainAll(b); n != 3 {
+		t.Error("RetainAll should remove 3 items, removed", n)
+	}
+	checkSortedSet(t, "RetainAll", a, 2, 4)
+}
+
+func Test_SortedSetRemoveIf(t *testing.T) {
+	eachSortedThingSet(t, func(t *testing.T, impl sortedThingSetImpl) {
+		a := impl.make([]int{1, 2, 3, 4, 5, 6, 7})
+
+		if n := a.RemoveIf(func(v Thing) bool { return v%3 != 0 }); n != 5 {
+			t.Error("RemoveIf should remove 5 items, removed", n)
+		}
+		impl.check(t, "RemoveIf", a, 3, 6)
+
+		a.Add(4)
+		impl.check(t, "RemoveIf then Add", a, 3, 4, 6)
+	})
+}
+
+func Test_SetSubsetSemantics(t *testing.T) {
+	empty := NewThingSortedSet(func(a, b Thing) bool { return a < b })
+	a := makeSortedSet([]int{1, 2, 3, 4})
+	b := makeSortedSet([]int{2, 4})
+	c := makeSortedSet([]int{1, 2, 3, 4})
+	d := makeSortedSet([]int{4, 5})
+	e := makeSortedSet([]int{7, 8})
+	f := makeSortedSet([]int{0, 2})
+
+	cases := []struct {
+		name                                                     string
+		x, y                                                     *ThingSortedSet
+		subset, superset, properSubset, properSuperset, disjoint bool
+	}{
+		{"b, a", b, a, true, false, true, false, false},
+		{"a, b", a, b, false, true, false, true, false},
+		{"a, c (equal)", a, c, true, true, false, false, false},
+		{"a, a (itself)", a, a, true, true, false, false, false},
+		{"a, d (overlapping)", a, d, false, false, false, false, false},
+		{"a, e (disjoint)", a, e, false, false, false, false, true},
+		{"f, a (smaller, not contained)", f, a, false, false, false, false, false},
+		{"empty, a", empty, a, true, false, true, false, true},
+		{"a, empty", a, empty, false, true, false, true, true},
+		{"empty, empty", empty, empty, true, true, false, false, true},
+	}
+
+	for _, c := range cases {
+		if c.x.IsSubset(c.y) != c.subset {
+			t.Errorf("IsSubset(%s) should be %v", c.name, c.subset)
+		}
+		if c.x.IsSuperset(c.y) != c.superset {
+			t.Errorf("IsSuperset(%s) should be %v", c.name, c.superset)
+		}
+		if c.x.IsProperSubset(c.y) != c.properSubset {
+			t.Errorf("IsProperSubset(%s) should be %v", c.name, c.properSubset)
+		}
+		if c.x.IsProperSuperset(c.y) != c.properSuperset {
+			t.Errorf("IsProperSuperset(%s) should be %v", c.name, c.properSuperset)
+		}
+		if c.x.IsDisjoint(c.y) != c.disjoint {
+			t.Errorf("IsDisjoint(%s) should be %v", c.name, c.disjoint)
+		}
+		if c.x.Overlaps(c.y) == c.disjoint {
+			t.Errorf("Overlaps(%s) should be %v", c.name, !c.disjoint)
+		}
+	}
+}
+
+func compareThings(a, b Thing) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func Test_SortedSetWithCompare(t *testing.T) {
+	a := NewThingSortedSetWithCompare(compareThings)
+	for _, v := range []Thing{5, 3, 9, 3, 1} {
+		a.Add(v)
+	}
+	checkSortedSet(t, "WithCompare", a, 1, 3, 5, 9)
+
+	if !a.Remove(3) || a.Remove(4) || a.Contains(3) {
+		t.Error("Remove should work on a set made with a compare func")
+	}
+
+	b := NewThingSortedSetWithCompare(compareThings)
+	b.AddAll(1, 2, 9)
+	checkSortedSet(t, "Union", a.Union(b), 1, 2, 5, 9)
+	checkSortedSet(t, "Intersect", a.Intersect(b), 1, 9)
+
+	// sets made from a compare func make more of the same
+	a.Clear()
+	a.Add(4)
+	checkSortedSet(t, "after Clear", a, 4)
+
+	// a reversed order works too
+	c := NewThingSortedSetWithCompare(func(a, b Thing) int { return compareThings(b, a) })
+	c.AddAll(1, 3, 2)
+	checkSortedSet(t, "reversed", c, 3, 2, 1)
+}
+
+func Test_SortedSetCompareCallsOncePerElement(t *testing.T) {
+	lessCalls, compareCalls := 0, 0
+	a := NewThingSortedSet(func(a, b Thing) bool {
+		lessCalls++
+		return a < b
+	})
+	b := NewThingSortedSetWithCompare(func(a, b Thing) int {
+		compareCalls++
+		return compareThings(a, b)
+	})
+
+	// both sets use the same seed, so they end up with the same levels
+	for i := 0; i < 1000; i++ {
+		v := Thing((i * 7919) % 1000)
+		a.Add(v)
+		b.Add(v)
+	}
+	lessCalls, compareCalls = 0, 0
+	for i := 0; i < 1000; i++ {
+		a.Contains(Thing(i))
+		b.Contains(Thing(i))
+	}
+
+	if compareCalls >= lessCalls {
+		t.Errorf("searching should call compare (%d calls) less often than less (%d calls)", compareCalls, lessCalls)
+	}
+}
+
+func Test_SortedSetNatural(t *testing.T) {
+	a := NewThingSortedSetNatural()
+	a.AddAll(5, 3, 9, 3, -1)
+	checkSortedSet(t, "Natural", a, -1, 3, 5, 9)
+
+	// Version orders itself with its Compare method
+	b := NewVersionSortedSetNatural()
+	for _, v := range []Version{{1, 10}, {0, 9}, {1, 2}, {0, 9}} {
+		b.Add(v)
+	}
+	var got []Version
+	b.Each(func(v Version) bool {
+		got = append(got, v)
+		return true
+	})
+	want := []Version{{0, 9}, {1, 2}, {1, 10}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func Test_SortedSetWithOptions(t *testing.T) {
+	a := NewThingSortedSetWithOptions(func(a, b Thing) bool { return a < b },
+		ThingSortedSetWithMaxLevel(4), ThingSortedSetWithP(0.25), ThingSortedSetWithSeed(42))
+	for i := 999; i >= 0; i-- {
+		a.Add(Thing(i))
+	}
+	if a.Len() != 1000 || !a.Contains(500) || a.Contains(1000) {
+		t.Error("a set with options should hold what was added")
+	}
+	for e := a.head[0]; e != nil; e = e.next[0] {
+		if len(e.next) > 4 {
+			t.Fatalf("element %v has %d levels, more than the max of 4", e.val, len(e.next))
+		}
+	}
+	if !a.Remove(500) || a.Contains(500) {
+		t.Error("Remove should work on a set with options")
+	}
+
+	// Clear and sets made from this one keep the options
+	a.Clear()
+	u := a.Union(makeSortedSet([]int{1, 2, 3}))
+	for _, s := range []*ThingSortedSet{a, u} {
+		if s.maxLevels != 4 || s.p != 0.25 || len(s.head) != 4 {
+			t.Errorf("expected options to be kept, got maxLevels %d, p %v", s.maxLevels, s.p)
+		}
+	}
+
+	// the same seed gives the same layout
+	layout := func(options ...ThingSortedSetOption) []int {
+		s := NewThingSortedSetWithOptions(func(a, b Thing) bool { return a < b }, options...)
+		for i := 0; i < 100; i++ {
+			s.Add(Thing(i))
+		}
+		var levels []int
+		for e := s.head[0]; e != nil; e = e.next[0] {
+			levels = append(levels, len(e.next))
+		}
+		return levels
+	}
+	same := func(a, b []int) bool {
+		return fmt.Sprint(a) == fmt.Sprint(b)
+	}
+	if !same(layout(ThingSortedSetWithSeed(7)), layout(ThingSortedSetWithSeed(7))) {
+		t.Error("sets with the same seed should have the same layout")
+	}
+	if same(layout(ThingSortedSetWithSeed(7)), layout(ThingSortedSetWithSeed(8))) {
+		t.Error("sets with different seeds should have different layouts")
+	}
+	if !same(layout(ThingSortedSetWithSeed(123123)), layout()) {
+		t.Error("the default seed should be 123123")
+	}
+	if !same(layout(ThingSortedSetWithSeed(7)), layout(ThingSortedSetWithRand(rand.New(rand.NewSource(7))))) {
+		t.Error("a set should draw its levels from the rand it is given")
+	}
+}
+
+func Test_SortedSetHeight(t *testing.T) {
+	a := NewThingSortedSetNatural()
+	var all []Thing
+	for i := 0; i < 1000; i++ {
+		a.Add(Thing(i))
+		all = append(all, Thing(i))
+		// levels are capped at log2(n) plus a few
+		if max := bits.Len(uint(i)) + 4; a.level > max {
+			t.Fatalf("after %d items the set is %d levels high, more than %d", i+1, a.level, max)
+		}
+	}
+	checkSortedSet(t, "a", a, all...)
+	for i := 0; i < 990; i++ {
+		a.Remove(Thing(i))
+	}
+	checkSortedSet(t, "after Remove", a, 990, 991, 992, 993, 994, 995, 996, 997, 998, 999)
+	for a.Len() > 0 {
+		a.PopFirst()
+	}
+	if a.level != 0 {
+		t.Errorf("an emptied set should have no levels, got %d", a.level)
+	}
+}
+
+func Test_SortedSetSearchDoesNotAllocate(t *testing.T) {
+	a := NewThingSortedSetNatural()
+	for i := 0; i < 1000; i += 2 {
+		a.Add(Thing(i))
+	}
+	for name, f := range map[string]func(){
+		"Contains":         func() { a.Contains(500) },
+		"Contains missing": func() { a.Contains(501) },
+		"Floor":            func() { a.Floor(501) },
+		"Higher":           func() { a.Higher(500) },
+		"Add existing":     func() { a.Add(500) },
+		"Remove missing":   func() { a.Remove(501) },
+		"Add and Remove": func() {
+			// only the new element itself is allocated
+			a.Add(501)
+			a.Remove(501)
+		},
+	} {
+		max := 0.0
+		if name == "Add and Remove" {
+			max = 2
+		}
+		if allocs := testing.AllocsPerRun(100, f); allocs > max {
+			t.Errorf("%s should make at most %v allocations, made %v", name, max, allocs)
+		}
+	}
+}
+
+func Test_SortedSetWithArena(t *testing.T) {
+	a := NewThingSortedSetWithOptions(func(a, b Thing) bool { return a < b }, ThingSortedSetWithArena(16))
+	b := makeSortedSet(nil)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		v := Thing(r.Intn(200))
+		switch r.Intn(3) {
+		case 0:
+			if a.Remove(v) != b.Remove(v) {
+				t.Fatalf("Remove(%v) should agree with a set without an arena", v)
+			}
+		case 1:
+			if av, _ := a.PopFirst(); b.Len() > 0 {
+				if bv, _ := b.PopFirst(); av != bv {
+					t.Fatalf("PopFirst should return %v, got %v", bv, av)
+				}
+			}
+		default:
+			if a.Add(v) != b.Add(v) {
+				t.Fatalf("Add(%v) should agree with a set without an arena", v)
+			}
+		}
+	}
+	var expected []Thing
+	b.Each(func(v Thing) bool {
+		expected = append(expected, v)
+		return true
+	})
+	checkSortedSet(t, "arena", a, expected...)
+
+	// a removed item's element is reused by the next item added
+	a.Add(1000)
+	if allocs := testing.AllocsPerRun(100, func() {
+		a.Remove(1000)
+		a.Add(1000)
+	}); allocs > 0 {
+		t.Errorf("re-adding a removed item should not allocate, made %v allocations", allocs)
+	}
+
+	// sets made from this one have arenas of their own
+	u := a.Union(makeSortedSet([]int{1000, 1001}))
+	if u.arena == nil || u.arena == a.arena {
+		t.Error("Union should make a set with its own arena")
+	}
+}
+
+// countFree returns how many elements are on the free lists of s's arena.
+func countFree(s *ThingSortedSet) int {
+	n := 0
+	for _, e := range s.arena.free {
+		for ; e != nil; e = e.next[0] {
+			n++
+		}
+	}
+	return n
+}
+
+func Test_SortedSetWithArenaBulkRemoval(t *testing.T) {
+	var all []Thing
+	for i := 0; i < 100; i++ {
+		all = append(all, Thing(i))
+	}
+	odds := makeSortedSet(nil)
+	var expected []Thing
+	for i := 1; i < 100; i += 2 {
+		odds.Add(Thing(i))
+		expected = append(expected, Thing(i))
+	}
+	evens := makeSortedSet(nil)
+	for i := 0; i < 100; i += 2 {
+		evens.Add(Thing(i))
+	}
+
+	for _, c := range []struct {
+		name   string
+		remove func(a *ThingSortedSet)
+	}{
+		{"RemoveIf", func(a *ThingSortedSet) { a.RemoveIf(func(v Thing) bool { return v%2 == 0 }) }},
+		{"IntersectWith", func(a *ThingSortedSet) { a.IntersectWith(odds) }},
+		{"DifferenceWith", func(a *ThingSortedSet) { a.DifferenceWith(evens) }},
+		{"RetainAll", func(a *ThingSortedSet) { a.RetainAll(odds) }},
+	} {
+		a := NewThingSortedSetWithOptions(func(a, b Thing) bool { return a < b }, ThingSortedSetWithArena(16))
+		a.AddAll(all...)
+		c.remove(a)
+		checkSortedSet(t, c.name, a, expected...)
+		if n := countFree(a); n != 50 {
+			t.Errorf("%s should recycle the 50 items it removes, recycled %d", c.name, n)
 		}
+
+		// adding the items back takes their elements from the free lists
+		for i := 0; i < 100; i += 2 {
+			a.Add(Thing(i))
+		}
+		checkSortedSet(t, c.name+" then Add", a, all...)
+		if n := countFree(a); n >= 50 {
+			t.Errorf("after %s, adding items back should reuse recycled elements, %d are still free", c.name, n)
+		}
+	}
+}
+
+func benchmarkSortedSetContains(b *testing.B, n int) {
+	a := NewThingSortedSetNatural()
+	for i := 0; i < n; i++ {
+		a.Add(Thing(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.Contains(Thing(i % n))
+	}
+}
+
+func Benchmark_SortedSetContains10(b *testing.B)     { benchmarkSortedSetContains(b, 10) }
+func Benchmark_SortedSetContains1000(b *testing.B)   { benchmarkSortedSetContains(b, 1000) }
+func Benchmark_SortedSetContains100000(b *testing.B) { benchmarkSortedSetContains(b, 100000) }
+
+func benchmarkSortedSetAddRemove(b *testing.B, n int) {
+	a := NewThingSortedSetNatural()
+	for i := 0; i < n; i++ {
+		a.Add(Thing(2 * i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := Thing(2*(i%n) + 1)
+		a.Add(v)
+		a.Remove(v)
+	}
+}
+
+func Benchmark_SortedSetAddRemove10(b *testing.B)     { benchmarkSortedSetAddRemove(b, 10) }
+func Benchmark_SortedSetAddRemove1000(b *testing.B)   { benchmarkSortedSetAddRemove(b, 1000) }
+func Benchmark_SortedSetAddRemove100000(b *testing.B) { benchmarkSortedSetAddRemove(b, 100000) }
+
+func Benchmark_SortedSetAddRemoveArena100000(b *testing.B) {
+	a := NewThingSortedSetWithOptions(func(a, b Thing) bool { return a < b }, ThingSortedSetWithArena(1024))
+	for i := 0; i < 100000; i++ {
+		a.Add(Thing(2 * i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := Thing(2*(i%100000) + 1)
+		a.Add(v)
+		a.Remove(v)
 	}
 }
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func makeSortedSliceList(load int, ints []int) *ThingSortedSliceList {
+	sl := NewThingSortedSliceListWithLoad(func(a, b Thing) bool { return a < b }, load)
+	for _, i := range ints {
+		sl.Add(Thing(i))
+	}
+	return sl
+}
+
+// checkSortedSliceList checks the list's items, by walking it and by
+// position, and that its slices and maxes are consistent.
+func checkSortedSliceList(t *testing.T, name string, sl *ThingSortedSliceList, expected []int) {
+	got := sl.ToSlice()
+	ok := len(got) == len(expected) && sl.Len() == len(expected)
+	for i := 0; ok && i < len(expected); i++ {
+		ok = int(got[i]) == expected[i] && int(sl.At(i)) == expected[i]
+	}
+	if !ok {
+		t.Fatalf("%s should be %v, got %v (Len %d)", name, expected, got, sl.Len())
+	}
+
+	if len(sl.lists) != len(sl.maxes) {
+		t.Fatalf("%s has %d slices but %d maxes", name, len(sl.lists), len(sl.maxes))
+	}
+	for k, s := range sl.lists {
+		if len(s) == 0 || len(s) > 2*sl.load {
+			t.Fatalf("%s has a slice of %d items", name, len(s))
+		}
+		if s[len(s)-1] != sl.maxes[k] {
+			t.Fatalf("%s has max %v for a slice ending in %v", name, sl.maxes[k], s[len(s)-1])
+		}
+	}
+}
+
+func Test_NewSortedSliceList(t *testing.T) {
+	sl := NewThingSortedSliceList(func(a, b Thing) bool { return a < b })
+
+	if sl.Len() != 0 {
+		t.Error("NewThingSortedSliceList should start out empty")
+	}
+	if sl.BisectLeft(5) != 0 || sl.BisectRight(5) != 0 || sl.Contains(5) || sl.Discard(5) {
+		t.Error("an empty list has nothing in it")
+	}
+	if sl.Iterator().Next() || sl.IRange(0, 10, true, true).Next() {
+		t.Error("an empty list has nothing to iterate")
+	}
+}
+
+func Test_SortedSliceListAt(t *testing.T) {
+	sl := makeSortedSliceList(2, []int{50, 10, 40, 20, 30, 20})
+	checkSortedSliceList(t, "list", sl, []int{10, 20, 20, 30, 40, 50})
+
+	for _, i := range []int{-1, 6} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("At(%d) should panic", i)
+				}
+			}()
+			sl.At(i)
+		}()
+	}
+}
+
+func Test_SortedSliceListBisect(t *testing.T) {
+	sl := makeSortedSliceList(2, []int{10, 20, 20, 20, 30, 40, 40, 50})
+
+	for _, c := range []struct {
+		v, left, right, count int
+	}{
+		{5, 0, 0, 0},
+		{10, 0, 1, 1},
+		{20, 1, 4, 3},
+		{25, 4, 4, 0},
+		{40, 5, 7, 2},
+		{50, 7, 8, 1},
+		{55, 8, 8, 0},
+	} {
+		if l := sl.BisectLeft(Thing(c.v)); l != c.left {
+			t.Errorf("BisectLeft(%d) should be %d, got %d", c.v, c.left, l)
+		}
+		if r := sl.BisectRight(Thing(c.v)); r != c.right {
+			t.Errorf("BisectRight(%d) should be %d, got %d", c.v, c.right, r)
+		}
+		if n := sl.Count(Thing(c.v)); n != c.count {
+			t.Errorf("Count(%d) should be %d, got %d", c.v, c.count, n)
+		}
+		if sl.Contains(Thing(c.v)) != (c.count > 0) {
+			t.Errorf("Contains(%d) should be %v", c.v, c.count > 0)
+		}
+	}
+}
+
+func Test_SortedSliceListIRange(t *testing.T) {
+	sl := makeSortedSliceList(2, []int{1, 2, 3, 3, 4, 5, 6, 7, 8, 9})
+	collect := func(it *ThingSortedSliceListIterator) []int {
+		got := []int{}
+		for it.Next() {
+			got = append(got, int(it.Value()))
+		}
+		return got
+	}
+	for _, c := range []struct {
+		lo, hi       Thing
+		loInc, hiInc bool
+		expected     []int
+	}{
+		{3, 6, true, true, []int{3, 3, 4, 5, 6}},
+		{3, 6, false, false, []int{4, 5}},
+		{3, 6, false, true, []int{4, 5, 6}},
+		{0, 100, true, true, []int{1, 2, 3, 3, 4, 5, 6, 7, 8, 9}},
+		{9, 100, false, true, []int{}},
+		{5, 5, true, true, []int{5}},
+		{5, 5, true, false, []int{}},
+		{6, 2, true, true, []int{}},
+	} {
+		got := collect(sl.IRange(c.lo, c.hi, c.loInc, c.hiInc))
+		ok := len(got) == len(c.expected)
+		for i := 0; ok && i < len(got); i++ {
+			ok = got[i] == c.expected[i]
+		}
+		if !ok {
+			t.Errorf("IRange(%v, %v, %v, %v) should be %v, got %v", c.lo, c.hi, c.loInc, c.hiInc, c.expected, got)
+		}
+	}
+	if got := collect(sl.Iterator()); len(got) != 10 || got[0] != 1 || got[9] != 9 {
+		t.Errorf("Iterator should walk the whole list, got %v", got)
+	}
+}
+
+func Test_SortedSliceListMatchesSortedSlice(t *testing.T) {
+	for _, load := range []int{1, 2, 3, 8} {
+		r := rand.New(rand.NewSource(int64(load)))
+		sl := makeSortedSliceList(load, nil)
+		var ref []int
+
+		for n := 0; n < 3000; n++ {
+			switch op := r.Intn(5); {
+			case op == 0 && len(ref) > 0:
+				i := r.Intn(len(ref))
+				if v := sl.RemoveAt(i); int(v) != ref[i] {
+					t.Fatalf("RemoveAt(%d) returned %d, expected %d", i, v, ref[i])
+				}
+				ref = append(ref[:i], ref[i+1:]...)
+			case op == 1:
+				v := r.Intn(200)
+				i := sort.SearchInts(ref, v)
+				present := i < len(ref) && ref[i] == v
+				if sl.Discard(Thing(v)) != present {
+					t.Fatalf("Discard(%d) should return %v", v, present)
+				}
+				if present {
+					ref = append(ref[:i], ref[i+1:]...)
+				}
+			default:
+				v := r.Intn(200)
+				sl.Add(Thing(v))
+				ref = append(ref, v)
+				sort.Ints(ref)
+			}
+			if n%100 == 0 {
+				checkSortedSliceList(t, "list", sl, ref)
+			}
+		}
+		checkSortedSliceList(t, "list", sl, ref)
+		for v := -1; v <= 200; v++ {
+			if sl.BisectLeft(Thing(v)) != sort.SearchInts(ref, v) || sl.BisectRight(Thing(v)) != sort.SearchInts(ref, v+1) {
+				t.Fatalf("load %d: bisecting %d is wrong", load, v)
+			}
+		}
+
+		for len(ref) > 0 {
+			i := r.Intn(len(ref))
+			sl.RemoveAt(i)
+			ref = append(ref[:i], ref[i+1:]...)
+		}
+		checkSortedSliceList(t, "emptied list", sl, ref)
+	}
+}
+
+func Test_SortedSliceListClear(t *testing.T) {
+	sl := makeSortedSliceList(2, []int{3, 1, 2})
+	sl.Clear()
+	checkSortedSliceList(t, "cleared list", sl, nil)
+	sl.Add(4)
+	checkSortedSliceList(t, "list added to after Clear", sl, []int{4})
+}
+
+func benchmarkSortedSliceListAddRemove(b *testing.B, n int) {
+	sl := NewThingSortedSliceList(func(a, b Thing) bool { return a < b })
+	for i := 0; i < n; i++ {
+		sl.Add(Thing(2 * i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := Thing(2*(i%n) + 1)
+		sl.Add(v)
+		sl.Discard(v)
+	}
+}
+
+func Benchmark_SortedSliceListAddRemove1000(b *testing.B) { benchmarkSortedSliceListAddRemove(b, 1000) }
+func Benchmark_SortedSliceListAddRemove100000(b *testing.B) {
+	benchmarkSortedSliceListAddRemove(b, 100000)
+}
+
+func benchmarkSortedSliceListAt(b *testing.B, n int) {
+	sl := NewThingSortedSliceList(func(a, b Thing) bool { return a < b })
+	for i := 0; i < n; i++ {
+		sl.Add(Thing(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sl.At(i % n)
+	}
+}
+
+func Benchmark_SortedSliceListAt1000(b *testing.B)   { benchmarkSortedSliceListAt(b, 1000) }
+func Benchmark_SortedSliceListAt100000(b *testing.B) { benchmarkSortedSliceListAt(b, 100000) }
+
+// the same workloads on the skiplist-backed SortedList, to compare against
+func benchmarkSortedListAddRemove(b *testing.B, n int) {
+	sl := makeSortedList(nil)
+	for i := 0; i < n; i++ {
+		sl.Add(Thing(2 * i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := Thing(2*(i%n) + 1)
+		sl.Add(v)
+		sl.Remove(v)
+	}
+}
+
+func Benchmark_SortedListAddRemove1000(b *testing.B)   { benchmarkSortedListAddRemove(b, 1000) }
+func Benchmark_SortedListAddRemove100000(b *testing.B) { benchmarkSortedListAddRemove(b, 100000) }
+
+func benchmarkSortedListAt(b *testing.B, n int) {
+	sl := makeSortedList(nil)
+	for i := 0; i < n; i++ {
+		sl.Add(Thing(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sl.At(i % n)
+	}
+}
+
+func Benchmark_SortedListAt1000(b *testing.B)   { benchmarkSortedListAt(b, 1000) }
+func Benchmark_SortedListAt100000(b *testing.B) { benchmarkSortedListAt(b, 100000) }
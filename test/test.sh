@@ -1,4 +1,6 @@
-go get -u
+# fetch only what's missing: -u would replace the checkout of this repo
+# with the published typewriter
+go get -d
 go run setup.go
 touch coverage.out
-go test -coverprofile=coverage.out
+go test -race -coverprofile=coverage.out
@@ -1,4 +1,16 @@
 package main
 
-// +test containers:"SortedSet"
+// +test containers:"SortedSet[natural],SortedMap[string],SortedMultiSet,SortedList,ConcurrentSortedSet,LockFreeSortedSet,ImmutableSortedSet,BTreeSortedSet[4],SortedSliceList,TreeSortedSet"
 type Thing int
+
+// +test containers:"SortedSet[natural]"
+type Version struct {
+	Major, Minor int
+}
+
+func (v Version) Compare(o Version) int {
+	if v.Major != o.Major {
+		return v.Major - o.Major
+	}
+	return v.Minor - o.Minor
+}
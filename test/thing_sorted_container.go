@@ -10,89 +10,344 @@ package main
 
 import (
 	"math"
+	"math/bits"
 	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
 )
 
+// the height bookkeeping shared by the skiplists behind ThingSortedSet,
+// ThingSortedMap, ThingSortedMultiSet and ThingSortedList
+type skiplistThingLevels struct {
+	level     int // how many levels have any elements on them
+	maxLevels int
+	p         float64
+	r         *rand.Rand
+}
+
+func newSkiplistThingLevels() skiplistThingLevels {
+	return skiplistThingLevels{
+		maxLevels: 64,
+		p:         0.5,
+		r:         rand.New(rand.NewSource(123123)),
+	}
+}
+
+// randomLevels picks how many levels a new element is on, in a skiplist of
+// n items. Levels are capped a few above the log2(n) such a skiplist needs,
+// so an unlucky run can't make a tower that every search has to climb down.
+func (lv *skiplistThingLevels) randomLevels(n int) int {
+	limit := bits.Len(uint(n)) + 4
+	if limit > lv.maxLevels {
+		limit = lv.maxLevels
+	}
+	level := int(math.Log(1.0-lv.r.Float64()) / math.Log(lv.p))
+	if level >= limit {
+		level = limit
+	}
+	if level == 0 {
+		level++
+	}
+	return level
+}
+
+// raise makes searches start high enough to see an element on the given
+// number of levels.
+func (lv *skiplistThingLevels) raise(levels int) {
+	if levels > lv.level {
+		lv.level = levels
+	}
+}
+
 // The primary type that represents a sorted set
 // backed by a skiplist
 type ThingSortedSet struct {
-	less      func(a, b Thing) bool
-	head      []*sortedSetThingElement
-	length    int
-	maxLevels int
-	r         *rand.Rand
+	less    func(a, b Thing) bool
+	compare func(a, b Thing) int
+	head    []*sortedSetThingElement
+	tail    *sortedSetThingElement
+	length  int
+	skiplistThingLevels
+	options []ThingSortedSetOption   // kept to make sets ordered and tuned the same way
+	update  []*sortedSetThingElement // scratch backPointer for Add and Remove
+	arena   *sortedSetThingArena     // nil unless made with ThingSortedSetWithArena
 }
 
 // the struct to hold elements of the skiplist
 type sortedSetThingElement struct {
 	val  Thing
 	next []*sortedSetThingElement
+	prev *sortedSetThingElement // the previous element on level 0
 }
 
 // Creates and returns a reference to an empty set.
-func NewThingSortedSet(less func(Thing, Thing) bool) ThingSortedSet {
-	return ThingSortedSet{
-		less:      less,
-		maxLevels: 64,
-		head:      make([]*sortedSetThingElement, 64),
-		r:         rand.New(rand.NewSource(123123)),
+func NewThingSortedSet(less func(Thing, Thing) bool) *ThingSortedSet {
+	return NewThingSortedSetWithOptions(less)
+}
+
+// Creates and returns a reference to an empty set ordered by a three-way
+// comparison, which returns a negative number when a is less than b, a
+// positive one when a is greater, and zero when they are equal.
+// Searching calls it once per element visited, where a less func has to
+// be called twice to tell equal items apart.
+func NewThingSortedSetWithCompare(compare func(Thing, Thing) int) *ThingSortedSet {
+	less := func(a, b Thing) bool {
+		return compare(a, b) < 0
+	}
+	return newThingSortedSet(less, compare)
+}
+
+// Creates and returns a reference to an empty set ordered by the < operator.
+func NewThingSortedSetNatural() *ThingSortedSet {
+	return NewThingSortedSetWithCompare(func(a, b Thing) int {
+		switch {
+		case a < b:
+			return -1
+		case b < a:
+			return 1
+		}
+		return 0
+	})
+}
+
+// A ThingSortedSetOption tunes the skiplist behind a set made by
+// NewThingSortedSetWithOptions.
+type ThingSortedSetOption func(ss *ThingSortedSet)
+
+// ThingSortedSetWithMaxLevel caps how many levels an element can reach,
+// 64 by default. A skiplist with p = 1/2 stays fast up to about 2^maxLevel
+// items, and a lower cap saves memory in every search.
+func ThingSortedSetWithMaxLevel(maxLevel int) ThingSortedSetOption {
+	if maxLevel < 1 {
+		panic("ThingSortedSet: max level must be at least 1")
+	}
+	return func(ss *ThingSortedSet) {
+		ss.maxLevels = maxLevel
+	}
+}
+
+// ThingSortedSetWithP sets the chance that an element reaching one level
+// reaches the next, 1/2 by default. A lower p, e.g. 1/4, gives elements
+// fewer links, using less memory for slightly longer searches.
+func ThingSortedSetWithP(p float64) ThingSortedSetOption {
+	if !(p > 0 && p < 1) {
+		panic("ThingSortedSet: p must be between 0 and 1")
+	}
+	return func(ss *ThingSortedSet) {
+		ss.p = p
+	}
+}
+
+// ThingSortedSetWithSeed seeds the random levels, 123123 by default.
+// Sets with the same seed given the same items in the same order end up
+// with the same layout.
+func ThingSortedSetWithSeed(seed int64) ThingSortedSetOption {
+	return func(ss *ThingSortedSet) {
+		ss.r = rand.New(rand.NewSource(seed))
+	}
+}
+
+// ThingSortedSetWithRand draws the random levels from r, e.g. one seeded
+// from the clock for a layout that differs from run to run. r is shared by
+// every set made from this one, such as by Union or Clone, so none of them
+// can be added to from another goroutine without locking r as well. Making
+// those sets doesn't draw from r, as their levels are spread evenly.
+func ThingSortedSetWithRand(r *rand.Rand) ThingSortedSetOption {
+	return func(ss *ThingSortedSet) {
+		ss.r = r
+	}
+}
+
+// ThingSortedSetWithArena allocates elements chunkSize at a time, with
+// their links, rather than making two allocations for every item, and
+// reuses the elements of removed items for items added later. This eases
+// the load on the garbage collector for very large sets.
+// Since elements are reused, an Iterator must not be moved on from an
+// item once that item has been removed.
+func ThingSortedSetWithArena(chunkSize int) ThingSortedSetOption {
+	if chunkSize < 1 {
+		panic("ThingSortedSet: arena chunk size must be at least 1")
+	}
+	return func(ss *ThingSortedSet) {
+		ss.arena = &sortedSetThingArena{chunkSize: chunkSize}
 	}
 }
 
+// Creates and returns a reference to an empty set ordered by less and
+// tuned by the given options.
+func NewThingSortedSetWithOptions(less func(Thing, Thing) bool, options ...ThingSortedSetOption) *ThingSortedSet {
+	compare := func(a, b Thing) int {
+		if less(a, b) {
+			return -1
+		}
+		if less(b, a) {
+			return 1
+		}
+		return 0
+	}
+	return newThingSortedSet(less, compare, options...)
+}
+
+func newThingSortedSet(less func(Thing, Thing) bool, compare func(Thing, Thing) int, options ...ThingSortedSetOption) *ThingSortedSet {
+	ss := &ThingSortedSet{
+		less:                less,
+		compare:             compare,
+		skiplistThingLevels: newSkiplistThingLevels(),
+		options:             options,
+	}
+	for _, option := range options {
+		option(ss)
+	}
+	ss.head = make([]*sortedSetThingElement, ss.maxLevels)
+	ss.update = make([]*sortedSetThingElement, ss.maxLevels)
+	if ss.arena != nil {
+		ss.arena.free = make([]*sortedSetThingElement, ss.maxLevels)
+	}
+	return ss
+}
+
+// empty returns a new, empty set ordered and tuned the same way as this one.
+func (ss *ThingSortedSet) empty() *ThingSortedSet {
+	return newThingSortedSet(ss.less, ss.compare, ss.options...)
+}
+
 func newSortedSetThingElement(v Thing, levels int) *sortedSetThingElement {
-	return &sortedSetThingElement{v, make([]*sortedSetThingElement, levels)}
+	return &sortedSetThingElement{val: v, next: make([]*sortedSetThingElement, levels)}
 }
 
-// Creates and returns a reference to a set from an existing slice
-func NewThingSortedSetFromSlice(less func(Thing, Thing) bool, s []Thing) ThingSortedSet {
-	a := NewThingSortedSet(less)
-	for _, item := range s {
-		a.Add(item)
+// newElement returns an element for v on the given number of levels, from
+// the set's arena if it has one.
+func (ss *ThingSortedSet) newElement(v Thing, levels int) *sortedSetThingElement {
+	if ss.arena != nil {
+		return ss.arena.element(v, levels)
 	}
-	return a
+	return newSortedSetThingElement(v, levels)
+}
+
+// the chunks a set made with ThingSortedSetWithArena takes its elements
+// from; elements given back by recycle are kept on a free list for each
+// number of levels, chained through next[0]
+type sortedSetThingArena struct {
+	chunkSize int
+	elems     []sortedSetThingElement  // the unused part of the current chunk of elements
+	links     []*sortedSetThingElement // the unused part of the current chunk of links
+	free      []*sortedSetThingElement
 }
 
-func (ss ThingSortedSet) randomLevels() int {
-	level := int(math.Log(1.0-ss.r.Float64()) / math.Log(0.5))
-	if level >= ss.maxLevels {
-		level = ss.maxLevels
+func (a *sortedSetThingArena) element(v Thing, levels int) *sortedSetThingElement {
+	if e := a.free[levels-1]; e != nil {
+		a.free[levels-1] = e.next[0]
+		e.next[0] = nil
+		e.val = v
+		return e
 	}
-	if level == 0 {
-		level++
+	if len(a.elems) == 0 {
+		a.elems = make([]sortedSetThingElement, a.chunkSize)
 	}
-	return level
+	if len(a.links) < levels {
+		// elements are on two levels on average with p = 1/2
+		n := 2 * a.chunkSize
+		if n < levels {
+			n = levels
+		}
+		a.links = make([]*sortedSetThingElement, n)
+	}
+	e := &a.elems[0]
+	a.elems = a.elems[1:]
+	e.val = v
+	e.next = a.links[:levels:levels]
+	a.links = a.links[levels:]
+	return e
+}
+
+// recycle puts e, which must no longer be linked into the set, on the free list.
+func (a *sortedSetThingArena) recycle(e *sortedSetThingElement) {
+	// drop what e refers to, so the garbage collector can have it
+	var zero Thing
+	e.val = zero
+	e.prev = nil
+	for level := range e.next {
+		e.next[level] = nil
+	}
+	e.next[0] = a.free[len(e.next)-1]
+	a.free[len(e.next)-1] = e
+}
+
+// Creates and returns a reference to a set from an existing slice.
+// The slice is copied and sorted, then built up as by
+// NewThingSortedSetFromSortedSlice; the first of any equal items is kept.
+func NewThingSortedSetFromSlice(less func(Thing, Thing) bool, s []Thing) *ThingSortedSet {
+	sorted := make([]Thing, len(s))
+	copy(sorted, s)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return NewThingSortedSetFromSortedSlice(less, sorted)
+}
+
+// Creates and returns a reference to a set from a slice already sorted by
+// less, in O(n). Runs of equal items are only added once.
+// Rather than being random, levels are spread evenly: every 2^k-th item
+// reaches level k, as in a perfectly balanced skiplist.
+func NewThingSortedSetFromSortedSlice(less func(Thing, Thing) bool, s []Thing) *ThingSortedSet {
+	a := NewThingSortedSet(less)
+	ap := a.appender()
+	for i, v := range s {
+		if i > 0 && !less(s[i-1], v) {
+			// equal to the item before it
+			continue
+		}
+		ap.push(v)
+	}
+	ap.finish()
+	return a
 }
 
 // Adds an item to the current set if it doesn't already exist in the set.
-func (ss ThingSortedSet) Add(v Thing) bool {
-	var backPointer = make([]*sortedSetThingElement, 64)
-	// zeroing this causes the compiler to not allocate memory each time
-	// for a 20-30% boost in speed
-	for i := 0; i < 64; i++ {
-		backPointer[i] = nil
-	}
-	for level := ss.maxLevels - 1; level >= 0; level-- {
+func (ss *ThingSortedSet) Add(v Thing) bool {
+	backPointer := ss.updateVector()
+	for level := ss.level - 1; level >= 0; level-- {
 		var e *sortedSetThingElement = nil
-		if level+1 == ss.maxLevels || backPointer[level+1] == nil {
+		if level+1 == ss.level || backPointer[level+1] == nil {
 			e = ss.head[level]
 		} else {
 			e = backPointer[level+1]
 		}
 		for e != nil {
+			c := ss.compare(v, e.val)
 			// if they are equal, overwrite?
-			if ss.less(v, e.val) == ss.less(e.val, v) {
+			if c == 0 {
 				return false
 			}
 			// if inspected val is greater than k, go back and down a level
-			if ss.less(v, e.val) {
+			if c < 0 {
 				break
 			}
 			backPointer[level] = e
 			e = e.next[level]
 		}
 	}
+	ss.insert(v, backPointer)
+	return true
+}
+
+// updateVector returns the set's scratch backPointer, cleared, so that
+// Add and Remove don't allocate one each call. Only methods that change
+// the set may use it: methods that only read can be called from many
+// goroutines at once, e.g. under ThingConcurrentSortedSet's read lock.
+func (ss *ThingSortedSet) updateVector() []*sortedSetThingElement {
+	for i := range ss.update {
+		ss.update[i] = nil
+	}
+	return ss.update
+}
+
+// insert creates an element for v and connects it up after backPointer,
+// which must hold the last element before v on every level.
+func (ss *ThingSortedSet) insert(v Thing, backPointer []*sortedSetThingElement) *sortedSetThingElement {
 	// create new element
-	e := newSortedSetThingElement(v, ss.randomLevels())
+	e := ss.newElement(v, ss.randomLevels(ss.length))
 
 	// connect new element up with backPointer
 	for level := 0; level < len(e.next); level++ {
@@ -104,232 +359,5094 @@ func (ss ThingSortedSet) Add(v Thing) bool {
 			backPointer[level].next[level] = e
 		}
 	}
+	e.prev = backPointer[0]
+	if e.next[0] != nil {
+		e.next[0].prev = e
+	} else {
+		ss.tail = e
+	}
+	ss.raise(len(e.next))
 
 	ss.length++
-	return true
+	return e
 }
 
-// Determines if a given item is already in the set.
-func (ss ThingSortedSet) Contains(v Thing) bool {
-	var backPointer = make([]*sortedSetThingElement, 64)
-	// zeroing this causes the compiler to not allocate memory each time
-	// for a 20-30% boost in speed
-	for i := 0; i < 64; i++ {
-		backPointer[i] = nil
-	}
-	for level := ss.maxLevels - 1; level >= 0; level-- {
-		var e *sortedSetThingElement = nil
-		if level+1 == ss.maxLevels || backPointer[level+1] == nil {
-			e = ss.head[level]
+// unlink disconnects e, given backPointer holding the last element before
+// it on every level.
+func (ss *ThingSortedSet) unlink(e *sortedSetThingElement, backPointer []*sortedSetThingElement) {
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			ss.head[level] = e.next[level]
 		} else {
-			e = backPointer[level+1]
-		}
-		for e != nil {
-			// if they are equal, return val
-			if ss.less(v, e.val) == ss.less(e.val, v) {
-				return true
-			}
-			// if inspected val is greater than v, go back and down a level
-			if ss.less(v, e.val) {
-				break
-			}
-			backPointer[level] = e
-			e = e.next[level]
+			backPointer[level].next[level] = e.next[level]
 		}
 	}
-	return false
-}
-
-// Determines if the given items are all in the set
-func (ss ThingSortedSet) ContainsAll(i ...Thing) bool {
-	for _, elem := range i {
-		if !ss.Contains(elem) {
-			return false
-		}
+	if e.next[0] != nil {
+		e.next[0].prev = backPointer[0]
+	} else {
+		ss.tail = backPointer[0]
 	}
-	return true
-}
 
-// Determines if every item in the other set is in this set.
-func (ss ThingSortedSet) IsSubset(other ThingSortedSet) bool {
-	e := ss.head[0]
-	for e != nil {
-		if !other.Contains(e.val) {
-			return false
-		}
-		e = e.next[0]
+	ss.length--
+	ss.lower()
+	if ss.arena != nil {
+		ss.arena.recycle(e)
 	}
-	return true
 }
 
-// Determines if every item of this set is in the other set.
-func (ss ThingSortedSet) IsSuperset(other ThingSortedSet) bool {
-	return other.IsSubset(ss)
+// drop hands e, which a bulk removal has left out of the set, back to the
+// arena if the set has one.
+func (ss *ThingSortedSet) drop(e *sortedSetThingElement) {
+	if ss.arena != nil {
+		ss.arena.recycle(e)
+	}
 }
 
-// Returns a new set with all items in both sets.
-func (ss ThingSortedSet) Union(other ThingSortedSet) ThingSortedSet {
-	unionedSet := NewThingSortedSet(ss.less)
-
-	e := ss.head[0]
-	for e != nil {
-		unionedSet.Add(e.val)
-		e = e.next[0]
+// dropFrom drops e and every element after it on level 0.
+func (ss *ThingSortedSet) dropFrom(e *sortedSetThingElement) {
+	if ss.arena == nil {
+		return
 	}
-	e = other.head[0]
 	for e != nil {
-		unionedSet.Add(e.val)
-		e = e.next[0]
+		next := e.next[0]
+		ss.arena.recycle(e)
+		e = next
 	}
-	return unionedSet
 }
 
-// Returns a new set with items that exist only in both sets.
-func (ss ThingSortedSet) Intersect(other ThingSortedSet) ThingSortedSet {
-	intersection := NewThingSortedSet(ss.less)
-	// loop over smaller set
-	if ss.Cardinality() < other.Cardinality() {
-		e := ss.head[0]
-		for e != nil {
-			if other.Contains(e.val) {
-				intersection.Add(e.val)
+// lower drops the height to the highest level that still has elements.
+func (ss *ThingSortedSet) lower() {
+	for ss.level > 0 && ss.head[ss.level-1] == nil {
+		ss.level--
+	}
+}
+
+// seek fills backPointer with the last element before v on every level,
+// returning the element holding v, or nil if v is not in the set.
+// Whatever backPointer already holds is used as a finger to start each
+// level from, so it must be nil or elements before v; a run of seeks for
+// ascending items then walks each level only once.
+func (ss *ThingSortedSet) seek(v Thing, backPointer []*sortedSetThingElement) *sortedSetThingElement {
+	var next *sortedSetThingElement
+	for level := ss.level - 1; level >= 0; level-- {
+		// start from the finger or from where the level above stopped,
+		// whichever is further along
+		e := backPointer[level]
+		if level+1 < ss.level && backPointer[level+1] != nil {
+			if e == nil || ss.compare(e.val, backPointer[level+1].val) < 0 {
+				e = backPointer[level+1]
 			}
-			e = e.next[0]
 		}
-	} else {
-		e := other.head[0]
-		for e != nil {
-			if ss.Contains(e.val) {
-				intersection.Add(e.val)
-			}
-			e = e.next[0]
+		if e == nil {
+			next = ss.head[level]
+		} else {
+			next = e.next[level]
 		}
+		for next != nil && ss.compare(next.val, v) < 0 {
+			e = next
+			next = e.next[level]
+		}
+		backPointer[level] = e
 	}
-	return intersection
+	if next != nil && ss.compare(v, next.val) == 0 {
+		return next
+	}
+	return nil
 }
 
-// Returns a new set with items in the current set but not in the other set
-func (ss ThingSortedSet) Difference(other ThingSortedSet) ThingSortedSet {
-	differencedSet := NewThingSortedSet(ss.less)
-	e := ss.head[0]
-	for e != nil {
-		if !other.Contains(e.val) {
-			differencedSet.Add(e.val)
+// sortedBatch returns a copy of items sorted by less, so that they can be
+// sought one after another with a finger.
+func (ss *ThingSortedSet) sortedBatch(items []Thing) []Thing {
+	sorted := make([]Thing, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return ss.less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// AddAll adds every given item not already in the set, returning how many
+// were added. The items are sorted first so the search for each one starts
+// where the last left off.
+func (ss *ThingSortedSet) AddAll(items ...Thing) int {
+	var backPointer = make([]*sortedSetThingElement, ss.maxLevels)
+	added := 0
+	for _, v := range ss.sortedBatch(items) {
+		if ss.seek(v, backPointer) != nil {
+			continue
 		}
-		e = e.next[0]
+		ss.insert(v, backPointer)
+		added++
 	}
-	return differencedSet
+	return added
 }
 
-// Returns a new set with items in the current set or the other set but not in both.
-func (ss ThingSortedSet) SymmetricDifference(other ThingSortedSet) ThingSortedSet {
-	aDiff := ss.Difference(other)
-	bDiff := other.Difference(ss)
-	return aDiff.Union(bDiff)
+// RemoveAll removes every given item that is in the set, returning how many
+// were removed. The items are sorted first so the search for each one starts
+// where the last left off.
+func (ss *ThingSortedSet) RemoveAll(items ...Thing) int {
+	var backPointer = make([]*sortedSetThingElement, ss.maxLevels)
+	removed := 0
+	for _, v := range ss.sortedBatch(items) {
+		if e := ss.seek(v, backPointer); e != nil {
+			ss.unlink(e, backPointer)
+			removed++
+		}
+	}
+	return removed
 }
 
-// Clears the entire set to be the empty set.
-func (ss *ThingSortedSet) Clear() {
-	*ss = ThingSortedSet{
-		less:      ss.less,
-		maxLevels: 64,
-		head:      make([]*sortedSetThingElement, 64),
-		r:         rand.New(rand.NewSource(123123)),
-	}
+// RetainAll removes every item that is not in the other set, returning how
+// many were removed.
+func (ss *ThingSortedSet) RetainAll(other *ThingSortedSet) int {
+	before := ss.length
+	ss.IntersectWith(other)
+	return before - ss.length
 }
 
-// Allows the removal of a single item in the set.
-func (ss ThingSortedSet) Remove(v Thing) {
-	var backPointer = make([]*sortedSetThingElement, 64)
-	// zeroing this causes the compiler to not allocate memory each time
-	// for a 20-30% boost in speed
-	for i := 0; i < 64; i++ {
-		backPointer[i] = nil
-	}
-	for level := ss.maxLevels - 1; level >= 0; level-- {
-		var e *sortedSetThingElement = nil
-		if level+1 == ss.maxLevels || backPointer[level+1] == nil {
-			e = ss.head[level]
+// RemoveIf removes every item for which pred returns true, returning how
+// many were removed. The set is relinked in a single pass.
+func (ss *ThingSortedSet) RemoveIf(pred func(v Thing) bool) int {
+	before := ss.length
+	e := ss.head[0]
+	a := ss.appender()
+	for e != nil {
+		// recycling e clears its links, so step past it first
+		next := e.next[0]
+		if pred(e.val) {
+			ss.drop(e)
 		} else {
-			e = backPointer[level+1]
+			a.link(e)
 		}
-		for e != nil {
-			// if they are equal, remove
-			if level == 0 && ss.less(v, e.val) == ss.less(e.val, v) {
-				for level := 0; level < len(e.next); level++ {
-					if backPointer[level] == nil {
-						ss.head[level] = e.next[level]
-					} else {
-						backPointer[level].next[level] = e.next[level]
-					}
-				}
+		e = next
+	}
+	a.finish()
+	return before - ss.length
+}
 
-				ss.length--
+// Determines if a given item is already in the set.
+func (ss *ThingSortedSet) Contains(v Thing) bool {
+	// only the last element before v matters, so no backPointer is kept
+	var prev *sortedSetThingElement
+	for level := ss.level - 1; level >= 0; level-- {
+		e := ss.head[level]
+		if prev != nil {
+			e = prev.next[level]
+		}
+		for ; e != nil; e = e.next[level] {
+			c := ss.compare(v, e.val)
+			// if they are equal, return val
+			if c == 0 {
+				return true
 			}
-			if ss.less(v, e.val) == ss.less(e.val, v) {
+			// if inspected val is greater than v, go back and down a level
+			if c < 0 {
 				break
 			}
-			// if inspected val is greater than k, go back and down a level
-			if ss.less(v, e.val) {
+			prev = e
+		}
+	}
+	return false
+}
+
+// neighbors descends the skiplist the same way Add does, returning the last
+// element less than v and the first element not less than v. Either is nil
+// if there is no such element.
+func (ss *ThingSortedSet) neighbors(v Thing) (lower, ceiling *sortedSetThingElement) {
+	for level := ss.level - 1; level >= 0; level-- {
+		e := ss.head[level]
+		if lower != nil {
+			e = lower.next[level]
+		}
+		for ; e != nil; e = e.next[level] {
+			// if inspected val is not less than v, go back and down a level
+			if ss.compare(e.val, v) >= 0 {
 				break
 			}
-			backPointer[level] = e
-			e = e.next[level]
+			lower = e
 		}
 	}
+	if lower == nil {
+		return nil, ss.head[0]
+	}
+	return lower, lower.next[0]
 }
 
-// Cardinality returns how many items are currently in the set.
-func (ss ThingSortedSet) Cardinality() int {
-	e := ss.head[0]
-	ret := 0
-	for e != nil {
-		ret++
+// Floor returns the greatest item in the set less than or equal to v.
+// ok is false if there is no such item.
+func (ss *ThingSortedSet) Floor(v Thing) (val Thing, ok bool) {
+	lower, ceiling := ss.neighbors(v)
+	if ceiling != nil && !ss.less(v, ceiling.val) {
+		return ceiling.val, true
+	}
+	if lower != nil {
+		return lower.val, true
+	}
+	return
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+// ok is false if there is no such item.
+func (ss *ThingSortedSet) Ceiling(v Thing) (val Thing, ok bool) {
+	_, ceiling := ss.neighbors(v)
+	if ceiling != nil {
+		return ceiling.val, true
+	}
+	return
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+// ok is false if there is no such item.
+func (ss *ThingSortedSet) Lower(v Thing) (val Thing, ok bool) {
+	lower, _ := ss.neighbors(v)
+	if lower != nil {
+		return lower.val, true
+	}
+	return
+}
+
+// Higher returns the least item in the set strictly greater than v.
+// ok is false if there is no such item.
+func (ss *ThingSortedSet) Higher(v Thing) (val Thing, ok bool) {
+	_, e := ss.neighbors(v)
+	if e != nil && !ss.less(v, e.val) {
 		e = e.next[0]
 	}
-	return ret
+	if e != nil {
+		return e.val, true
+	}
+	return
 }
 
-// Iter() returns a channel of type Thing that you can range over.
-func (ss ThingSortedSet) Iter() <-chan Thing {
-	ch := make(chan Thing)
-	go func() {
-		e := ss.head[0]
-		for e != nil {
-			ch <- e.val
-			e = e.next[0]
+// A read-only view of the items of a ThingSortedSet between two bounds.
+// The view is evaluated lazily, seeking to its lower bound each time it
+// is iterated, so it reflects changes made to the set after it was created.
+type ThingSortedSetView struct {
+	ss                       *ThingSortedSet
+	lo, hi                   Thing
+	hasLo, hasHi             bool
+	loInclusive, hiInclusive bool
+}
+
+// Range returns a view of the items between lo and hi, each bound being
+// included or excluded as requested.
+func (ss *ThingSortedSet) Range(lo, hi Thing, loInclusive, hiInclusive bool) ThingSortedSetView {
+	return ThingSortedSetView{ss: ss, lo: lo, hi: hi, hasLo: true, hasHi: true, loInclusive: loInclusive, hiInclusive: hiInclusive}
+}
+
+// HeadSet returns a view of the items strictly less than hi.
+func (ss *ThingSortedSet) HeadSet(hi Thing) ThingSortedSetView {
+	return ThingSortedSetView{ss: ss, hi: hi, hasHi: true}
+}
+
+// TailSet returns a view of the items greater than or equal to lo.
+func (ss *ThingSortedSet) TailSet(lo Thing) ThingSortedSetView {
+	return ThingSortedSetView{ss: ss, lo: lo, hasLo: true, loInclusive: true}
+}
+
+// first returns the first element inside the lower bound of the view.
+func (sv ThingSortedSetView) first() *sortedSetThingElement {
+	if !sv.hasLo {
+		return sv.ss.head[0]
+	}
+	_, e := sv.ss.neighbors(sv.lo)
+	if e != nil && !sv.loInclusive && !sv.ss.less(sv.lo, e.val) {
+		e = e.next[0]
+	}
+	return e
+}
+
+// inside determines if v is inside the upper bound of the view.
+func (sv ThingSortedSetView) inside(v Thing) bool {
+	if !sv.hasHi {
+		return true
+	}
+	if sv.hiInclusive {
+		return !sv.ss.less(sv.hi, v)
+	}
+	return sv.ss.less(v, sv.hi)
+}
+
+// Each calls f for every item in the view in ascending order, stopping
+// early if f returns false.
+func (sv ThingSortedSetView) Each(f func(v Thing) bool) {
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		if !f(e.val) {
+			return
 		}
-		close(ch)
-	}()
+	}
+}
 
-	return ch
+// Cardinality returns how many items are currently in the view.
+// Unlike the set's, this walks every item in the view.
+func (sv ThingSortedSetView) Cardinality() int {
+	n := 0
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		n++
+	}
+	return n
 }
 
-// Equal determines if two sets are equal to each other.
-// If they both are the same size and have the same items they are considered equal.
-// Order of items is not relevent for sets to be equal.
-func (ss ThingSortedSet) Equal(other ThingSortedSet) bool {
-	if ss.Cardinality() != other.Cardinality() {
-		return false
+// ToSlice returns the items in the view in ascending order.
+func (sv ThingSortedSetView) ToSlice() []Thing {
+	var s []Thing
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		s = append(s, e.val)
 	}
-	e := ss.head[0]
-	for e != nil {
-		if !other.Contains(e.val) {
+	return s
+}
+
+// ToSet copies the items in the view into a new set.
+func (sv ThingSortedSetView) ToSet() *ThingSortedSet {
+	set := sv.ss.empty()
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		set.Add(e.val)
+	}
+	return set
+}
+
+// Determines if the given items are all in the set
+func (ss *ThingSortedSet) ContainsAll(i ...Thing) bool {
+	for _, elem := range i {
+		if !ss.Contains(elem) {
 			return false
 		}
-		e = e.next[0]
 	}
 	return true
 }
 
-// Returns a clone of the set.
-// Does NOT clone the underlying elements.
-func (ss ThingSortedSet) Clone() ThingSortedSet {
-	clonedSet := NewThingSortedSet(ss.less)
-	e := ss.head[0]
+// IsSubset determines if every item in this set is in the other set.
+// An empty set is a subset of every set, and every set is a subset of itself.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *ThingSortedSet) IsSubset(other *ThingSortedSet) bool {
+	if ss.length > other.length {
+		return false
+	}
+	e, o := ss.head[0], other.head[0]
 	for e != nil {
-		clonedSet.Add(e.val)
-		e = e.next[0]
+		if o == nil {
+			return false
+		}
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			// e.val was skipped over in the other set
+			return false
+		case c > 0:
+			o = o.next[0]
+		default:
+			e, o = e.next[0], o.next[0]
+		}
 	}
-	return clonedSet
+	return true
+}
+
+// IsSuperset determines if every item in the other set is in this set.
+func (ss *ThingSortedSet) IsSuperset(other *ThingSortedSet) bool {
+	return other.IsSubset(ss)
+}
+
+// IsProperSubset determines if this set is a subset of the other set and
+// the other set has at least one item this one does not.
+func (ss *ThingSortedSet) IsProperSubset(other *ThingSortedSet) bool {
+	return ss.length < other.length && ss.IsSubset(other)
+}
+
+// IsProperSuperset determines if the other set is a proper subset of this one.
+func (ss *ThingSortedSet) IsProperSuperset(other *ThingSortedSet) bool {
+	return other.IsProperSubset(ss)
+}
+
+// IsDisjoint determines if the two sets have no items in common.
+// An empty set is disjoint with every set, itself included.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *ThingSortedSet) IsDisjoint(other *ThingSortedSet) bool {
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Overlaps determines if the two sets have at least one item in common.
+func (ss *ThingSortedSet) Overlaps(other *ThingSortedSet) bool {
+	return !ss.IsDisjoint(other)
+}
+
+// sortedSetThingAppender refills a set with elements given in ascending
+// order, linking each one after the last element appended on every level
+// instead of searching for its place.
+type sortedSetThingAppender struct {
+	ss   *ThingSortedSet
+	ends []*sortedSetThingElement
+}
+
+// appender empties the set, leaving its elements alone so they can be
+// linked back in, and returns an appender to refill it.
+func (ss *ThingSortedSet) appender() *sortedSetThingAppender {
+	ss.tail = nil
+	ss.length = 0
+	ss.level = 0
+	return &sortedSetThingAppender{ss, make([]*sortedSetThingElement, ss.maxLevels)}
+}
+
+// push appends a new element holding v. Its levels are spread evenly
+// rather than drawn at random: with p = 1/2, every 2^k-th item appended
+// reaches level k. So Clone and the set algebra never touch the set's
+// rand, which sets copied under a read lock may share.
+func (a *sortedSetThingAppender) push(v Thing) {
+	step := int(1/a.ss.p + 0.5)
+	if step < 2 {
+		step = 2
+	}
+	n := a.ss.length + 1
+	levels := 1
+	for span := step; levels < a.ss.maxLevels && n%span == 0; span *= step {
+		levels++
+	}
+	a.link(a.ss.newElement(v, levels))
+}
+
+// link appends e, which must not be less than anything appended before it.
+func (a *sortedSetThingAppender) link(e *sortedSetThingElement) {
+	for level := 0; level < len(e.next); level++ {
+		if a.ends[level] == nil {
+			a.ss.head[level] = e
+		} else {
+			a.ends[level].next[level] = e
+		}
+		a.ends[level] = e
+	}
+	e.prev = a.ss.tail
+	a.ss.tail = e
+	a.ss.length++
+}
+
+// finish terminates every level after the last element appended to it.
+func (a *sortedSetThingAppender) finish() {
+	for level, e := range a.ends {
+		if e == nil {
+			a.ss.head[level] = nil
+		} else {
+			e.next[level] = nil
+			a.ss.level = level + 1
+		}
+	}
+}
+
+// Returns a new set with all items in both sets.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *ThingSortedSet) Union(other *ThingSortedSet) *ThingSortedSet {
+	unionedSet := ss.empty()
+	a := unionedSet.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.push(e.val)
+			e = e.next[0]
+		case c > 0:
+			a.push(o.val)
+			o = o.next[0]
+		default:
+			a.push(e.val)
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	for ; o != nil; o = o.next[0] {
+		a.push(o.val)
+	}
+	a.finish()
+	return unionedSet
+}
+
+// Returns a new set with items that exist only in both sets.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *ThingSortedSet) Intersect(other *ThingSortedSet) *ThingSortedSet {
+	intersection := ss.empty()
+	a := intersection.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			a.push(e.val)
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	a.finish()
+	return intersection
+}
+
+// Returns a new set with items in the current set but not in the other set.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *ThingSortedSet) Difference(other *ThingSortedSet) *ThingSortedSet {
+	differencedSet := ss.empty()
+	a := differencedSet.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.push(e.val)
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	a.finish()
+	return differencedSet
+}
+
+// Returns a new set with items in the current set or the other set but not in both.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *ThingSortedSet) SymmetricDifference(other *ThingSortedSet) *ThingSortedSet {
+	symmetricDifference := ss.empty()
+	a := symmetricDifference.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.push(e.val)
+			e = e.next[0]
+		case c > 0:
+			a.push(o.val)
+			o = o.next[0]
+		default:
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	for ; o != nil; o = o.next[0] {
+		a.push(o.val)
+	}
+	a.finish()
+	return symmetricDifference
+}
+
+// UnionWith adds every item of the other set to this one, in O(n+m).
+// Elements already in this set are relinked rather than copied.
+func (ss *ThingSortedSet) UnionWith(other *ThingSortedSet) {
+	if other == ss {
+		return
+	}
+	e, o := ss.head[0], other.head[0]
+	a := ss.appender()
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.link(e)
+			e = e.next[0]
+		case c > 0:
+			a.push(o.val)
+			o = o.next[0]
+		default:
+			a.link(e)
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.link(e)
+	}
+	for ; o != nil; o = o.next[0] {
+		a.push(o.val)
+	}
+	a.finish()
+}
+
+// IntersectWith removes every item that is not also in the other set, in O(n+m).
+func (ss *ThingSortedSet) IntersectWith(other *ThingSortedSet) {
+	if other == ss {
+		return
+	}
+	e, o := ss.head[0], other.head[0]
+	a := ss.appender()
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			next := e.next[0]
+			ss.drop(e)
+			e = next
+		case c > 0:
+			o = o.next[0]
+		default:
+			a.link(e)
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	ss.dropFrom(e)
+	a.finish()
+}
+
+// DifferenceWith removes every item that is also in the other set, in O(n+m).
+func (ss *ThingSortedSet) DifferenceWith(other *ThingSortedSet) {
+	if other == ss {
+		ss.Clear()
+		return
+	}
+	e, o := ss.head[0], other.head[0]
+	a := ss.appender()
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.link(e)
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			next := e.next[0]
+			ss.drop(e)
+			e, o = next, o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.link(e)
+	}
+	a.finish()
+}
+
+// Clears the entire set to be the empty set.
+func (ss *ThingSortedSet) Clear() {
+	*ss = *ss.empty()
+}
+
+// Allows the removal of a single item in the set.
+// Returns true if the item was in the set.
+func (ss *ThingSortedSet) Remove(v Thing) bool {
+	backPointer := ss.updateVector()
+	for level := ss.level - 1; level >= 0; level-- {
+		var e *sortedSetThingElement = nil
+		if level+1 == ss.level || backPointer[level+1] == nil {
+			e = ss.head[level]
+		} else {
+			e = backPointer[level+1]
+		}
+		for e != nil {
+			c := ss.compare(v, e.val)
+			// if they are equal, remove
+			if level == 0 && c == 0 {
+				ss.unlink(e, backPointer)
+				return true
+			}
+			// if inspected val is greater than or equal to k, go back and down a level
+			if c <= 0 {
+				break
+			}
+			backPointer[level] = e
+			e = e.next[level]
+		}
+	}
+	return false
+}
+
+// Cardinality returns how many items are currently in the set.
+func (ss *ThingSortedSet) Cardinality() int {
+	return ss.length
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (ss *ThingSortedSet) Len() int {
+	return ss.length
+}
+
+// A cursor over the items of a ThingSortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next, Prev or Seek has returned true.
+type ThingSortedSetIterator struct {
+	ss      *ThingSortedSet
+	e       *sortedSetThingElement
+	started bool // with a nil e, whether the cursor is past the last item rather than before the first
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (ss *ThingSortedSet) Iterator() *ThingSortedSetIterator {
+	return &ThingSortedSetIterator{ss: ss}
+}
+
+// ReverseIterator returns a cursor positioned after the last item of the
+// set, to be walked from largest to smallest with Prev.
+func (ss *ThingSortedSet) ReverseIterator() *ThingSortedSetIterator {
+	return &ThingSortedSetIterator{ss: ss, started: true}
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *ThingSortedSetIterator) Next() bool {
+	if !it.started {
+		it.e = it.ss.head[0]
+		it.started = true
+	} else if it.e != nil {
+		it.e = it.e.next[0]
+	}
+	return it.e != nil
+}
+
+// Prev moves the cursor to the previous item, returning false once it has
+// moved before the first one.
+func (it *ThingSortedSetIterator) Prev() bool {
+	if !it.started {
+		return false
+	}
+	if it.e == nil {
+		it.e = it.ss.last()
+	} else {
+		it.e = it.e.prev
+	}
+	if it.e == nil {
+		it.started = false
+	}
+	return it.e != nil
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *ThingSortedSetIterator) Seek(v Thing) bool {
+	_, it.e = it.ss.neighbors(v)
+	it.started = true
+	return it.e != nil
+}
+
+// Value returns the item at the cursor.
+func (it *ThingSortedSetIterator) Value() Thing {
+	return it.e.val
+}
+
+// last returns the element holding the greatest item, or nil if the set is empty.
+func (ss *ThingSortedSet) last() *sortedSetThingElement {
+	return ss.tail
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (ss *ThingSortedSet) Each(f func(v Thing) bool) {
+	for e := ss.head[0]; e != nil; e = e.next[0] {
+		if !f(e.val) {
+			return
+		}
+	}
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+func (ss *ThingSortedSet) ReverseEach(f func(v Thing) bool) {
+	for e := ss.last(); e != nil; e = e.prev {
+		if !f(e.val) {
+			return
+		}
+	}
+}
+
+// First returns the least item in the set.
+// ok is false if the set is empty.
+func (ss *ThingSortedSet) First() (val Thing, ok bool) {
+	if e := ss.head[0]; e != nil {
+		return e.val, true
+	}
+	return
+}
+
+// PopFirst removes and returns the least item in the set.
+// ok is false if the set is empty.
+func (ss *ThingSortedSet) PopFirst() (val Thing, ok bool) {
+	e := ss.head[0]
+	if e == nil {
+		return
+	}
+	// the first element comes straight after the head on every level it is on
+	for level := 0; level < len(e.next); level++ {
+		ss.head[level] = e.next[level]
+	}
+	if e.next[0] != nil {
+		e.next[0].prev = nil
+	} else {
+		ss.tail = nil
+	}
+	ss.length--
+	ss.lower()
+	val = e.val
+	if ss.arena != nil {
+		ss.arena.recycle(e)
+	}
+	return val, true
+}
+
+// PopN removes and returns the k least items in the set in ascending
+// order, or every item if there are fewer than k.
+func (ss *ThingSortedSet) PopN(k int) []Thing {
+	var popped []Thing
+	for len(popped) < k {
+		v, ok := ss.PopFirst()
+		if !ok {
+			break
+		}
+		popped = append(popped, v)
+	}
+	return popped
+}
+
+// Last returns the greatest item in the set.
+// ok is false if the set is empty.
+func (ss *ThingSortedSet) Last() (val Thing, ok bool) {
+	if e := ss.last(); e != nil {
+		return e.val, true
+	}
+	return
+}
+
+// PopLast removes and returns the greatest item in the set, like PopMax.
+// ok is false if the set is empty.
+func (ss *ThingSortedSet) PopLast() (val Thing, ok bool) {
+	return ss.PopMax()
+}
+
+// PopMax removes and returns the greatest item in the set.
+// ok is false if the set is empty.
+func (ss *ThingSortedSet) PopMax() (val Thing, ok bool) {
+	val, ok = ss.Last()
+	if ok {
+		ss.Remove(val)
+	}
+	return
+}
+
+// Iter() returns a channel of type Thing that you can range over.
+// It is kept for compatibility: breaking out of the range loop leaks the
+// goroutine feeding the channel, so prefer Iterator or Each.
+func (ss *ThingSortedSet) Iter() <-chan Thing {
+	ch := make(chan Thing)
+	go func() {
+		ss.Each(func(v Thing) bool {
+			ch <- v
+			return true
+		})
+		close(ch)
+	}()
+
+	return ch
+}
+
+// Equal determines if two sets are equal to each other.
+// If they both are the same size and have the same items they are considered equal.
+// Order of items is not relevent for sets to be equal.
+func (ss *ThingSortedSet) Equal(other *ThingSortedSet) bool {
+	if ss.Cardinality() != other.Cardinality() {
+		return false
+	}
+	// both sets are in order, so walk them side by side in O(n)
+	for e, o := ss.head[0], other.head[0]; e != nil; e, o = e.next[0], o.next[0] {
+		if ss.compare(e.val, o.val) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a clone of the set, in O(n).
+// Does NOT clone the underlying elements.
+func (ss *ThingSortedSet) Clone() *ThingSortedSet {
+	clonedSet := ss.empty()
+	a := clonedSet.appender()
+	for e := ss.head[0]; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	a.finish()
+	return clonedSet
+}
+
+// A map from Thing to string that keeps its keys sorted,
+// backed by a skiplist
+type ThingSortedMap struct {
+	less   func(a, b Thing) bool
+	head   []*sortedMapThingElement
+	length int
+	skiplistThingLevels
+	update []*sortedMapThingElement // scratch backPointer for Put and Delete
+}
+
+// the struct to hold entries of the skiplist
+type sortedMapThingElement struct {
+	key   Thing
+	value string
+	next  []*sortedMapThingElement
+}
+
+// Creates and returns a reference to an empty map.
+func NewThingSortedMap(less func(Thing, Thing) bool) *ThingSortedMap {
+	sm := &ThingSortedMap{
+		less:                less,
+		skiplistThingLevels: newSkiplistThingLevels(),
+	}
+	sm.head = make([]*sortedMapThingElement, sm.maxLevels)
+	sm.update = make([]*sortedMapThingElement, sm.maxLevels)
+	return sm
+}
+
+func newSortedMapThingElement(k Thing, v string, levels int) *sortedMapThingElement {
+	return &sortedMapThingElement{k, v, make([]*sortedMapThingElement, levels)}
+}
+
+// updateVector returns the map's scratch backPointer, cleared, so that Put
+// and Delete don't allocate one each call. Only methods that change the map
+// may use it, so that many goroutines can read the map at once.
+func (sm *ThingSortedMap) updateVector() []*sortedMapThingElement {
+	for i := range sm.update {
+		sm.update[i] = nil
+	}
+	return sm.update
+}
+
+// find descends the skiplist looking for k, recording in backPointer, unless
+// it is nil, the last element before k on every level. It returns the
+// element holding k, or nil if there is none.
+func (sm *ThingSortedMap) find(k Thing, backPointer []*sortedMapThingElement) *sortedMapThingElement {
+	var pred, e *sortedMapThingElement
+	for level := sm.level - 1; level >= 0; level-- {
+		if pred == nil {
+			e = sm.head[level]
+		} else {
+			e = pred.next[level]
+		}
+		// stop at the first key not less than k, then go down a level
+		for e != nil && sm.less(e.key, k) {
+			pred = e
+			e = e.next[level]
+		}
+		if backPointer != nil {
+			backPointer[level] = pred
+		}
+	}
+	if e != nil && !sm.less(k, e.key) {
+		return e
+	}
+	return nil
+}
+
+// Put associates v with k, replacing any value already stored for k.
+// Returns true if k was not in the map before.
+func (sm *ThingSortedMap) Put(k Thing, v string) bool {
+	backPointer := sm.updateVector()
+	if e := sm.find(k, backPointer); e != nil {
+		e.value = v
+		return false
+	}
+	// create new element
+	e := newSortedMapThingElement(k, v, sm.randomLevels(sm.length))
+
+	// connect new element up with backPointer
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			e.next[level] = sm.head[level]
+			sm.head[level] = e
+		} else {
+			e.next[level] = backPointer[level].next[level]
+			backPointer[level].next[level] = e
+		}
+	}
+	sm.raise(len(e.next))
+
+	sm.length++
+	return true
+}
+
+// Get returns the value stored for k, and whether k was found at all.
+func (sm *ThingSortedMap) Get(k Thing) (string, bool) {
+	if e := sm.find(k, nil); e != nil {
+		return e.value, true
+	}
+	var zero string
+	return zero, false
+}
+
+// Determines if a given key is in the map.
+func (sm *ThingSortedMap) ContainsKey(k Thing) bool {
+	return sm.find(k, nil) != nil
+}
+
+// Delete removes k and its value from the map.
+// Returns true if k was in the map.
+func (sm *ThingSortedMap) Delete(k Thing) bool {
+	backPointer := sm.updateVector()
+	e := sm.find(k, backPointer)
+	if e == nil {
+		return false
+	}
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			sm.head[level] = e.next[level]
+		} else {
+			backPointer[level].next[level] = e.next[level]
+		}
+	}
+	// drop the height to the highest level that still has elements
+	for sm.level > 0 && sm.head[sm.level-1] == nil {
+		sm.level--
+	}
+	sm.length--
+	return true
+}
+
+// Len returns how many entries are currently in the map.
+func (sm *ThingSortedMap) Len() int {
+	return sm.length
+}
+
+// Clears the entire map.
+func (sm *ThingSortedMap) Clear() {
+	*sm = *NewThingSortedMap(sm.less)
+}
+
+// Keys returns all keys in ascending order.
+func (sm *ThingSortedMap) Keys() []Thing {
+	keys := make([]Thing, 0, sm.length)
+	for e := sm.head[0]; e != nil; e = e.next[0] {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+// Values returns all values in ascending order of their keys.
+func (sm *ThingSortedMap) Values() []string {
+	values := make([]string, 0, sm.length)
+	for e := sm.head[0]; e != nil; e = e.next[0] {
+		values = append(values, e.value)
+	}
+	return values
+}
+
+// Each calls f for every entry in ascending key order, stopping early
+// if f returns false.
+func (sm *ThingSortedMap) Each(f func(k Thing, v string) bool) {
+	for e := sm.head[0]; e != nil; e = e.next[0] {
+		if !f(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// A sorted bag of Thing that, unlike ThingSortedSet, keeps
+// every copy of equal items, backed by a skiplist.
+// Equal items are kept in the order they were added.
+type ThingSortedMultiSet struct {
+	less   func(a, b Thing) bool
+	head   []*sortedMultiSetThingElement
+	length int
+	skiplistThingLevels
+	update []*sortedMultiSetThingElement // scratch backPointer for Add and the removes
+}
+
+// the struct to hold elements of the skiplist
+type sortedMultiSetThingElement struct {
+	val  Thing
+	next []*sortedMultiSetThingElement
+}
+
+// Creates and returns a reference to an empty multiset.
+func NewThingSortedMultiSet(less func(Thing, Thing) bool) *ThingSortedMultiSet {
+	ms := &ThingSortedMultiSet{
+		less:                less,
+		skiplistThingLevels: newSkiplistThingLevels(),
+	}
+	ms.head = make([]*sortedMultiSetThingElement, ms.maxLevels)
+	ms.update = make([]*sortedMultiSetThingElement, ms.maxLevels)
+	return ms
+}
+
+func newSortedMultiSetThingElement(v Thing, levels int) *sortedMultiSetThingElement {
+	return &sortedMultiSetThingElement{v, make([]*sortedMultiSetThingElement, levels)}
+}
+
+// updateVector returns the multiset's scratch backPointer, cleared, so that
+// Add and the removes don't allocate one each call. Only methods that change
+// the multiset may use it, so that many goroutines can read it at once.
+func (ms *ThingSortedMultiSet) updateVector() []*sortedMultiSetThingElement {
+	for i := range ms.update {
+		ms.update[i] = nil
+	}
+	return ms.update
+}
+
+// seek descends the skiplist recording in backPointer, unless it is nil, the
+// last element on every level that is less than v (or, if after is true, not
+// greater than v). It returns the element following that on the bottom level.
+func (ms *ThingSortedMultiSet) seek(v Thing, after bool, backPointer []*sortedMultiSetThingElement) *sortedMultiSetThingElement {
+	var pred *sortedMultiSetThingElement
+	e := ms.head[0]
+	for level := ms.level - 1; level >= 0; level-- {
+		if pred == nil {
+			e = ms.head[level]
+		} else {
+			e = pred.next[level]
+		}
+		for e != nil {
+			// if inspected val is past v, go back and down a level
+			if after && ms.less(v, e.val) || !after && !ms.less(e.val, v) {
+				break
+			}
+			pred = e
+			e = e.next[level]
+		}
+		if backPointer != nil {
+			backPointer[level] = pred
+		}
+	}
+	return e
+}
+
+// lower drops the height to the highest level that still has elements.
+func (ms *ThingSortedMultiSet) lower() {
+	for ms.level > 0 && ms.head[ms.level-1] == nil {
+		ms.level--
+	}
+}
+
+func (ms *ThingSortedMultiSet) equal(a, b Thing) bool {
+	return !ms.less(a, b) && !ms.less(b, a)
+}
+
+// Adds a copy of an item to the multiset, after any equal items already in it.
+func (ms *ThingSortedMultiSet) Add(v Thing) {
+	backPointer := ms.updateVector()
+	ms.seek(v, true, backPointer)
+
+	// create new element
+	e := newSortedMultiSetThingElement(v, ms.randomLevels(ms.length))
+
+	// connect new element up with backPointer
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			e.next[level] = ms.head[level]
+			ms.head[level] = e
+		} else {
+			e.next[level] = backPointer[level].next[level]
+			backPointer[level].next[level] = e
+		}
+	}
+	ms.raise(len(e.next))
+
+	ms.length++
+}
+
+// Determines if at least one copy of a given item is in the multiset.
+func (ms *ThingSortedMultiSet) Contains(v Thing) bool {
+	e := ms.seek(v, false, nil)
+	return e != nil && ms.equal(v, e.val)
+}
+
+// Count returns how many copies of a given item are in the multiset.
+func (ms *ThingSortedMultiSet) Count(v Thing) int {
+	n := 0
+	for e := ms.seek(v, false, nil); e != nil && ms.equal(v, e.val); e = e.next[0] {
+		n++
+	}
+	return n
+}
+
+// RemoveOne removes the earliest added copy of an item.
+// Returns true if there was a copy to remove.
+func (ms *ThingSortedMultiSet) RemoveOne(v Thing) bool {
+	backPointer := ms.updateVector()
+	e := ms.seek(v, false, backPointer)
+	if e == nil || !ms.equal(v, e.val) {
+		return false
+	}
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			ms.head[level] = e.next[level]
+		} else {
+			backPointer[level].next[level] = e.next[level]
+		}
+	}
+	ms.lower()
+	ms.length--
+	return true
+}
+
+// RemoveAll removes every copy of an item, returning how many were removed.
+func (ms *ThingSortedMultiSet) RemoveAll(v Thing) int {
+	backPointer := ms.updateVector()
+	ms.seek(v, false, backPointer)
+	removed := 0
+	for level := 0; level < ms.level; level++ {
+		var e *sortedMultiSetThingElement
+		if backPointer[level] == nil {
+			e = ms.head[level]
+		} else {
+			e = backPointer[level].next[level]
+		}
+		if e == nil || !ms.equal(v, e.val) {
+			// no copy reaches this level, so none reach the ones above
+			break
+		}
+		for e != nil && ms.equal(v, e.val) {
+			if level == 0 {
+				removed++
+			}
+			e = e.next[level]
+		}
+		if backPointer[level] == nil {
+			ms.head[level] = e
+		} else {
+			backPointer[level].next[level] = e
+		}
+	}
+	ms.lower()
+	ms.length -= removed
+	return removed
+}
+
+// Len returns how many items, counting every copy, are in the multiset.
+func (ms *ThingSortedMultiSet) Len() int {
+	return ms.length
+}
+
+// Clears the entire multiset.
+func (ms *ThingSortedMultiSet) Clear() {
+	*ms = *NewThingSortedMultiSet(ms.less)
+}
+
+// Each calls f for every item in ascending order, including every copy of
+// equal items in the order they were added, stopping early if f returns false.
+func (ms *ThingSortedMultiSet) Each(f func(v Thing) bool) {
+	for e := ms.head[0]; e != nil; e = e.next[0] {
+		if !f(e.val) {
+			return
+		}
+	}
+}
+
+// ToSlice returns every item, including copies, in ascending order.
+func (ms *ThingSortedMultiSet) ToSlice() []Thing {
+	s := make([]Thing, 0, ms.length)
+	for e := ms.head[0]; e != nil; e = e.next[0] {
+		s = append(s, e.val)
+	}
+	return s
+}
+
+// A sorted list of Thing that can be indexed by position,
+// backed by an indexable skiplist.
+// Equal items are all kept, in the order they were added.
+type ThingSortedList struct {
+	less   func(a, b Thing) bool
+	head   *sortedListThingElement // sentinel before the first item
+	length int
+	skiplistThingLevels
+	update []*sortedListThingElement // scratch backPointer for Add and the removes
+	pos    []int                     // scratch positions of update for Add
+}
+
+// the struct to hold elements of the skiplist; width[level] is how many
+// items next[level] is ahead of this element, counting a nil next as one
+// past the last item. Only the widths below the list's level are kept up.
+type sortedListThingElement struct {
+	val   Thing
+	next  []*sortedListThingElement
+	width []int
+}
+
+// Creates and returns a reference to an empty list.
+func NewThingSortedList(less func(Thing, Thing) bool) *ThingSortedList {
+	var zero Thing
+	sl := &ThingSortedList{
+		less:                less,
+		skiplistThingLevels: newSkiplistThingLevels(),
+	}
+	sl.head = newSortedListThingElement(zero, sl.maxLevels)
+	sl.update = make([]*sortedListThingElement, sl.maxLevels)
+	sl.pos = make([]int, sl.maxLevels)
+	// the bottom level is always searched, even with nothing on it
+	sl.head.width[0] = 1
+	sl.raise(1)
+	return sl
+}
+
+func newSortedListThingElement(v Thing, levels int) *sortedListThingElement {
+	return &sortedListThingElement{v, make([]*sortedListThingElement, levels), make([]int, levels)}
+}
+
+// updateVector returns the list's scratch backPointer, cleared, so that Add
+// and the removes don't allocate one each call. Only methods that change the
+// list may use it, so that many goroutines can read the list at once.
+func (sl *ThingSortedList) updateVector() []*sortedListThingElement {
+	for i := range sl.update {
+		sl.update[i] = nil
+	}
+	return sl.update
+}
+
+// seek descends the skiplist recording in backPointer, unless it is nil, the
+// last element on every level that is less than v (or, if after is true, not
+// greater than v), and in pos, unless it is nil, how many items come before
+// each. It returns the index of the item after the element found on the
+// bottom level, and that element.
+func (sl *ThingSortedList) seek(v Thing, after bool, backPointer []*sortedListThingElement, pos []int) (int, *sortedListThingElement) {
+	e := sl.head
+	i := 0
+	for level := sl.level - 1; level >= 0; level-- {
+		for next := e.next[level]; next != nil; next = e.next[level] {
+			// if inspected val is past v, go down a level
+			if after && sl.less(v, next.val) || !after && !sl.less(next.val, v) {
+				break
+			}
+			i += e.width[level]
+			e = next
+		}
+		if backPointer != nil {
+			backPointer[level] = e
+		}
+		if pos != nil {
+			pos[level] = i
+		}
+	}
+	return i, e
+}
+
+// seekIndex is like seek, but stops before the item at index i.
+func (sl *ThingSortedList) seekIndex(i int, backPointer []*sortedListThingElement) *sortedListThingElement {
+	e := sl.head
+	pos := 0
+	for level := sl.level - 1; level >= 0; level-- {
+		for e.next[level] != nil && pos+e.width[level] <= i {
+			pos += e.width[level]
+			e = e.next[level]
+		}
+		if backPointer != nil {
+			backPointer[level] = e
+		}
+	}
+	return e
+}
+
+func (sl *ThingSortedList) equal(a, b Thing) bool {
+	return !sl.less(a, b) && !sl.less(b, a)
+}
+
+// Adds an item to the list, after any equal items already in it.
+func (sl *ThingSortedList) Add(v Thing) {
+	// create new element, which will sit at index i
+	e := newSortedListThingElement(v, sl.randomLevels(sl.length))
+
+	// levels coming into use start out with head spanning the whole list
+	for level := sl.level; level < len(e.next); level++ {
+		sl.head.width[level] = sl.length + 1
+	}
+	sl.raise(len(e.next))
+
+	backPointer, pos := sl.updateVector(), sl.pos
+	i, _ := sl.seek(v, true, backPointer, pos)
+
+	// connect new element up with backPointer, splitting the widths it lands in
+	for level := 0; level < sl.level; level++ {
+		b := backPointer[level]
+		if level < len(e.next) {
+			e.next[level] = b.next[level]
+			b.next[level] = e
+			e.width[level] = b.width[level] - (i - pos[level])
+			b.width[level] = i + 1 - pos[level]
+		} else {
+			b.width[level]++
+		}
+	}
+
+	sl.length++
+}
+
+// unlink removes the element after backPointer on the bottom level.
+func (sl *ThingSortedList) unlink(backPointer []*sortedListThingElement) {
+	e := backPointer[0].next[0]
+	for level := 0; level < sl.level; level++ {
+		b := backPointer[level]
+		if level < len(e.next) {
+			b.width[level] += e.width[level] - 1
+			b.next[level] = e.next[level]
+		} else {
+			b.width[level]--
+		}
+	}
+	// drop the height to the highest level that still has elements
+	for sl.level > 1 && sl.head.next[sl.level-1] == nil {
+		sl.level--
+	}
+	sl.length--
+}
+
+// Remove removes the earliest added copy of an item.
+// Returns true if there was a copy to remove.
+func (sl *ThingSortedList) Remove(v Thing) bool {
+	backPointer := sl.updateVector()
+	_, b := sl.seek(v, false, backPointer, nil)
+	if e := b.next[0]; e == nil || !sl.equal(v, e.val) {
+		return false
+	}
+	sl.unlink(backPointer)
+	return true
+}
+
+// RemoveAt removes and returns the item at index i.
+// Panics if i is out of range.
+func (sl *ThingSortedList) RemoveAt(i int) Thing {
+	if i < 0 || i >= sl.length {
+		panic("ThingSortedList: index out of range")
+	}
+	backPointer := sl.updateVector()
+	v := sl.seekIndex(i, backPointer).next[0].val
+	sl.unlink(backPointer)
+	return v
+}
+
+// At returns the item at index i, where index 0 is the smallest item.
+// Panics if i is out of range.
+func (sl *ThingSortedList) At(i int) Thing {
+	if i < 0 || i >= sl.length {
+		panic("ThingSortedList: index out of range")
+	}
+	e := sl.head
+	pos := 0
+	for level := sl.level - 1; level >= 0; level-- {
+		for e.next[level] != nil && pos+e.width[level] <= i+1 {
+			pos += e.width[level]
+			e = e.next[level]
+		}
+	}
+	return e.val
+}
+
+// Rank returns how many items in the list are less than v.
+func (sl *ThingSortedList) Rank(v Thing) int {
+	i, _ := sl.seek(v, false, nil, nil)
+	return i
+}
+
+// IndexOf returns the index of the earliest added copy of v, or -1 if
+// v is not in the list.
+func (sl *ThingSortedList) IndexOf(v Thing) int {
+	i, b := sl.seek(v, false, nil, nil)
+	if e := b.next[0]; e == nil || !sl.equal(v, e.val) {
+		return -1
+	}
+	return i
+}
+
+// Determines if a given item is in the list.
+func (sl *ThingSortedList) Contains(v Thing) bool {
+	return sl.IndexOf(v) >= 0
+}
+
+// Slice returns the items from index i up to but not including index j.
+// Panics if the indexes are out of range, like slicing a slice would.
+func (sl *ThingSortedList) Slice(i, j int) []Thing {
+	if i < 0 || j < i || j > sl.length {
+		panic("ThingSortedList: slice bounds out of range")
+	}
+	s := make([]Thing, 0, j-i)
+	if i == j {
+		return s
+	}
+	for e := sl.seekIndex(i, nil).next[0]; len(s) < j-i; e = e.next[0] {
+		s = append(s, e.val)
+	}
+	return s
+}
+
+// Len returns how many items are in the list.
+func (sl *ThingSortedList) Len() int {
+	return sl.length
+}
+
+// Clears the entire list.
+func (sl *ThingSortedList) Clear() {
+	*sl = *NewThingSortedList(sl.less)
+}
+
+// Each calls f with the index and value of every item in ascending order,
+// stopping early if f returns false.
+func (sl *ThingSortedList) Each(f func(i int, v Thing) bool) {
+	i := 0
+	for e := sl.head.next[0]; e != nil; e = e.next[0] {
+		if !f(i, e.val) {
+			return
+		}
+		i++
+	}
+}
+
+// A ThingSortedSet that is safe to share between goroutines.
+// Reads take a read lock and writes take the write lock. Methods that
+// take another set copy it under its own read lock first, so two sets
+// are never locked at once.
+type ThingConcurrentSortedSet struct {
+	mu  sync.RWMutex
+	set *ThingSortedSet
+}
+
+// Creates and returns a reference to an empty concurrent set.
+func NewThingConcurrentSortedSet(less func(Thing, Thing) bool) *ThingConcurrentSortedSet {
+	return &ThingConcurrentSortedSet{set: NewThingSortedSet(less)}
+}
+
+// Creates and returns a reference to an empty concurrent set ordered by a
+// three-way comparison, as NewThingSortedSetWithCompare.
+func NewThingConcurrentSortedSetWithCompare(compare func(Thing, Thing) int) *ThingConcurrentSortedSet {
+	return &ThingConcurrentSortedSet{set: NewThingSortedSetWithCompare(compare)}
+}
+
+// Creates and returns a reference to an empty concurrent set whose
+// skiplist is tuned by the given options, as NewThingSortedSetWithOptions.
+func NewThingConcurrentSortedSetWithOptions(less func(Thing, Thing) bool, options ...ThingSortedSetOption) *ThingConcurrentSortedSet {
+	return &ThingConcurrentSortedSet{set: NewThingSortedSetWithOptions(less, options...)}
+}
+
+// Creates and returns a reference to an empty concurrent set in the
+// natural order of Thing, as NewThingSortedSetNatural.
+func NewThingConcurrentSortedSetNatural() *ThingConcurrentSortedSet {
+	return &ThingConcurrentSortedSet{set: NewThingSortedSetNatural()}
+}
+
+// Creates and returns a reference to a concurrent set from an existing slice.
+func NewThingConcurrentSortedSetFromSlice(less func(Thing, Thing) bool, s []Thing) *ThingConcurrentSortedSet {
+	return &ThingConcurrentSortedSet{set: NewThingSortedSetFromSlice(less, s)}
+}
+
+// Creates and returns a reference to a concurrent set from a slice already
+// sorted by less, in O(n), as NewThingSortedSetFromSortedSlice.
+func NewThingConcurrentSortedSetFromSortedSlice(less func(Thing, Thing) bool, s []Thing) *ThingConcurrentSortedSet {
+	return &ThingConcurrentSortedSet{set: NewThingSortedSetFromSortedSlice(less, s)}
+}
+
+// Snapshot returns a copy of the set as it is now, which the caller can
+// read and iterate without any locking. Taking one doesn't draw from a rand
+// given by ThingSortedSetWithRand, but adding to it does, as the copy
+// shares that rand.
+func (cs *ThingConcurrentSortedSet) Snapshot() *ThingSortedSet {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Clone()
+}
+
+// Returns a clone of the set, in O(n).
+// Does NOT clone the underlying elements.
+func (cs *ThingConcurrentSortedSet) Clone() *ThingConcurrentSortedSet {
+	return &ThingConcurrentSortedSet{set: cs.Snapshot()}
+}
+
+// Adds an item to the set if it doesn't already exist in the set.
+func (cs *ThingConcurrentSortedSet) Add(v Thing) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.Add(v)
+}
+
+// AddAll adds every given item not already in the set, returning how many were added.
+func (cs *ThingConcurrentSortedSet) AddAll(items ...Thing) int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.AddAll(items...)
+}
+
+// Allows the removal of a single item in the set.
+// Returns true if the item was in the set.
+func (cs *ThingConcurrentSortedSet) Remove(v Thing) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.Remove(v)
+}
+
+// RemoveAll removes every given item that is in the set, returning how many were removed.
+func (cs *ThingConcurrentSortedSet) RemoveAll(items ...Thing) int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.RemoveAll(items...)
+}
+
+// RemoveIf removes every item for which pred returns true, returning how many were removed.
+// pred is called with the write lock held, so it must not use the set.
+func (cs *ThingConcurrentSortedSet) RemoveIf(pred func(v Thing) bool) int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.RemoveIf(pred)
+}
+
+// RetainAll removes every item that is not in the other set, returning how
+// many were removed.
+func (cs *ThingConcurrentSortedSet) RetainAll(other *ThingConcurrentSortedSet) int {
+	if other == cs {
+		return 0
+	}
+	o := other.Snapshot()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.RetainAll(o)
+}
+
+// Clears the entire set to be the empty set.
+func (cs *ThingConcurrentSortedSet) Clear() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.set.Clear()
+}
+
+// Determines if a given item is already in the set.
+func (cs *ThingConcurrentSortedSet) Contains(v Thing) bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Contains(v)
+}
+
+// Determines if the given items are all in the set
+func (cs *ThingConcurrentSortedSet) ContainsAll(i ...Thing) bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.ContainsAll(i...)
+}
+
+// Cardinality returns how many items are currently in the set.
+func (cs *ThingConcurrentSortedSet) Cardinality() int {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Cardinality()
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (cs *ThingConcurrentSortedSet) Len() int {
+	return cs.Cardinality()
+}
+
+// Floor returns the greatest item in the set less than or equal to v.
+func (cs *ThingConcurrentSortedSet) Floor(v Thing) (Thing, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Floor(v)
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+func (cs *ThingConcurrentSortedSet) Ceiling(v Thing) (Thing, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Ceiling(v)
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+func (cs *ThingConcurrentSortedSet) Lower(v Thing) (Thing, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Lower(v)
+}
+
+// Higher returns the least item in the set strictly greater than v.
+func (cs *ThingConcurrentSortedSet) Higher(v Thing) (Thing, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Higher(v)
+}
+
+// First returns the least item in the set.
+func (cs *ThingConcurrentSortedSet) First() (Thing, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.First()
+}
+
+// Last returns the greatest item in the set.
+func (cs *ThingConcurrentSortedSet) Last() (Thing, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Last()
+}
+
+// PopFirst removes and returns the least item in the set.
+func (cs *ThingConcurrentSortedSet) PopFirst() (Thing, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.PopFirst()
+}
+
+// PopLast removes and returns the greatest item in the set.
+func (cs *ThingConcurrentSortedSet) PopLast() (Thing, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.PopLast()
+}
+
+// PopMax removes and returns the greatest item in the set, like PopLast.
+func (cs *ThingConcurrentSortedSet) PopMax() (Thing, bool) {
+	return cs.PopLast()
+}
+
+// PopN removes and returns the k least items in the set in ascending order.
+func (cs *ThingConcurrentSortedSet) PopN(k int) []Thing {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.set.PopN(k)
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+// f is called with the read lock held, so it must not change the set.
+func (cs *ThingConcurrentSortedSet) Each(f func(v Thing) bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	cs.set.Each(f)
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+// f is called with the read lock held, so it must not change the set.
+func (cs *ThingConcurrentSortedSet) ReverseEach(f func(v Thing) bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	cs.set.ReverseEach(f)
+}
+
+// Iter returns a channel of the items of a snapshot of the set, in ascending order.
+func (cs *ThingConcurrentSortedSet) Iter() <-chan Thing {
+	return cs.Snapshot().Iter()
+}
+
+// Range returns a view of the items of a snapshot of the set between lo
+// and hi, each bound being included or excluded as requested. Unlike a
+// ThingSortedSet's, the view doesn't see later changes to the set.
+func (cs *ThingConcurrentSortedSet) Range(lo, hi Thing, loInclusive, hiInclusive bool) ThingSortedSetView {
+	return cs.Snapshot().Range(lo, hi, loInclusive, hiInclusive)
+}
+
+// HeadSet returns a view of the items of a snapshot of the set strictly less than hi.
+func (cs *ThingConcurrentSortedSet) HeadSet(hi Thing) ThingSortedSetView {
+	return cs.Snapshot().HeadSet(hi)
+}
+
+// TailSet returns a view of the items of a snapshot of the set greater than or equal to lo.
+func (cs *ThingConcurrentSortedSet) TailSet(lo Thing) ThingSortedSetView {
+	return cs.Snapshot().TailSet(lo)
+}
+
+// Iterator returns a cursor over a snapshot of the set, so it can be
+// walked at any pace without holding up writers.
+func (cs *ThingConcurrentSortedSet) Iterator() *ThingSortedSetIterator {
+	return cs.Snapshot().Iterator()
+}
+
+// ReverseIterator returns a cursor positioned after the last item of a
+// snapshot of the set, to be walked from largest to smallest with Prev.
+func (cs *ThingConcurrentSortedSet) ReverseIterator() *ThingSortedSetIterator {
+	return cs.Snapshot().ReverseIterator()
+}
+
+// Returns a new set with all items in both sets.
+func (cs *ThingConcurrentSortedSet) Union(other *ThingConcurrentSortedSet) *ThingConcurrentSortedSet {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return &ThingConcurrentSortedSet{set: cs.set.Union(o)}
+}
+
+// Returns a new set with items that exist only in both sets.
+func (cs *ThingConcurrentSortedSet) Intersect(other *ThingConcurrentSortedSet) *ThingConcurrentSortedSet {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return &ThingConcurrentSortedSet{set: cs.set.Intersect(o)}
+}
+
+// Returns a new set with items in the current set but not in the other set.
+func (cs *ThingConcurrentSortedSet) Difference(other *ThingConcurrentSortedSet) *ThingConcurrentSortedSet {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return &ThingConcurrentSortedSet{set: cs.set.Difference(o)}
+}
+
+// Returns a new set with items in the current set or the other set but not in both.
+func (cs *ThingConcurrentSortedSet) SymmetricDifference(other *ThingConcurrentSortedSet) *ThingConcurrentSortedSet {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return &ThingConcurrentSortedSet{set: cs.set.SymmetricDifference(o)}
+}
+
+// UnionWith adds every item of the other set to this one.
+func (cs *ThingConcurrentSortedSet) UnionWith(other *ThingConcurrentSortedSet) {
+	o := other.Snapshot()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.set.UnionWith(o)
+}
+
+// IntersectWith removes every item that is not also in the other set.
+func (cs *ThingConcurrentSortedSet) IntersectWith(other *ThingConcurrentSortedSet) {
+	o := other.Snapshot()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.set.IntersectWith(o)
+}
+
+// DifferenceWith removes every item that is also in the other set.
+func (cs *ThingConcurrentSortedSet) DifferenceWith(other *ThingConcurrentSortedSet) {
+	o := other.Snapshot()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.set.DifferenceWith(o)
+}
+
+// IsSubset determines if every item in this set is in the other set.
+func (cs *ThingConcurrentSortedSet) IsSubset(other *ThingConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.IsSubset(o)
+}
+
+// IsSuperset determines if every item in the other set is in this set.
+func (cs *ThingConcurrentSortedSet) IsSuperset(other *ThingConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.IsSuperset(o)
+}
+
+// IsProperSubset determines if this set is a subset of the other set and
+// the other set has at least one item this one does not.
+func (cs *ThingConcurrentSortedSet) IsProperSubset(other *ThingConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.IsProperSubset(o)
+}
+
+// IsProperSuperset determines if this set is a superset of the other set
+// and has at least one item the other set does not.
+func (cs *ThingConcurrentSortedSet) IsProperSuperset(other *ThingConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.IsProperSuperset(o)
+}
+
+// IsDisjoint determines if the two sets have no items in common.
+func (cs *ThingConcurrentSortedSet) IsDisjoint(other *ThingConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.IsDisjoint(o)
+}
+
+// Overlaps determines if the two sets have at least one item in common.
+func (cs *ThingConcurrentSortedSet) Overlaps(other *ThingConcurrentSortedSet) bool {
+	return !cs.IsDisjoint(other)
+}
+
+// Equal determines if two sets are equal to each other.
+func (cs *ThingConcurrentSortedSet) Equal(other *ThingConcurrentSortedSet) bool {
+	o := other.Snapshot()
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.set.Equal(o)
+}
+
+// A sorted set that is safe to share between goroutines without locking,
+// backed by a lock-free skiplist (Herlihy and Shavit's, after Fraser).
+// An item is removed by first marking its element's links as deleted,
+// which is what makes it gone, and then unlinking it; any goroutine
+// that comes across a marked element helps unlink it.
+// Iteration is weakly consistent: it sees every item present for the whole
+// walk and may or may not see items added or removed during it.
+type ThingLockFreeSortedSet struct {
+	less   func(a, b Thing) bool
+	head   *lockFreeSortedSetThingElement // sentinel before the first item
+	length atomic.Int64
+	level  atomic.Int32 // how many levels have ever had elements on them; it never goes down
+}
+
+// the most levels an element can be on, enough for 2^64 items
+const lockFreeSortedSetThingMaxLevels = 64
+
+// the struct to hold elements of the skiplist
+type lockFreeSortedSetThingElement struct {
+	val  Thing
+	next []atomic.Pointer[lockFreeSortedSetThingLink]
+}
+
+// a link to the next element on one level, together with whether the
+// element holding the link has been removed. Links are never changed,
+// only swapped, so the two always change together.
+type lockFreeSortedSetThingLink struct {
+	next   *lockFreeSortedSetThingElement
+	marked bool
+}
+
+// Creates and returns a reference to an empty lock-free set.
+func NewThingLockFreeSortedSet(less func(Thing, Thing) bool) *ThingLockFreeSortedSet {
+	var zero Thing
+	ls := &ThingLockFreeSortedSet{
+		less: less,
+		head: newLockFreeSortedSetThingElement(zero, lockFreeSortedSetThingMaxLevels, nil),
+	}
+	ls.level.Store(1)
+	return ls
+}
+
+func newLockFreeSortedSetThingElement(v Thing, levels int, succs []*lockFreeSortedSetThingElement) *lockFreeSortedSetThingElement {
+	e := &lockFreeSortedSetThingElement{val: v, next: make([]atomic.Pointer[lockFreeSortedSetThingLink], levels)}
+	for level := range e.next {
+		var next *lockFreeSortedSetThingElement
+		if succs != nil {
+			next = succs[level]
+		}
+		e.next[level].Store(&lockFreeSortedSetThingLink{next: next})
+	}
+	return e
+}
+
+// randomLevels uses the global source from math/rand, which is safe to
+// use from many goroutines at once. Levels are capped a few above the
+// log2(n) a set of n items needs, as in ThingSortedSet.
+func (ls *ThingLockFreeSortedSet) randomLevels() int {
+	limit := bits.Len(uint(ls.Len())) + 4
+	if limit > lockFreeSortedSetThingMaxLevels {
+		limit = lockFreeSortedSetThingMaxLevels
+	}
+	level := int(math.Log(1.0-rand.Float64()) / math.Log(0.5))
+	if level >= limit {
+		level = limit
+	}
+	if level == 0 {
+		level++
+	}
+	return level
+}
+
+// raise makes sure searches start at least as high as the given number of
+// levels. It's called before an element on that many levels is linked, so
+// any search that can see the element goes through all its levels.
+func (ls *ThingLockFreeSortedSet) raise(levels int) {
+	for {
+		level := ls.level.Load()
+		if int(level) >= levels || ls.level.CompareAndSwap(level, int32(levels)) {
+			return
+		}
+	}
+}
+
+// find fills preds and succs with the elements either side of where v
+// belongs on every level in use, unlinking any marked elements it passes.
+// Returns whether succs[0] holds v.
+func (ls *ThingLockFreeSortedSet) find(v Thing, preds, succs []*lockFreeSortedSetThingElement) bool {
+retry:
+	for {
+		pred := ls.head
+		var curr *lockFreeSortedSetThingElement
+		for level := int(ls.level.Load()) - 1; level >= 0; level-- {
+			curr = pred.next[level].Load().next
+			for curr != nil {
+				link := curr.next[level].Load()
+				for link.marked {
+					// curr is removed, try to unlink it from pred
+					predLink := pred.next[level].Load()
+					if predLink.marked || predLink.next != curr ||
+						!pred.next[level].CompareAndSwap(predLink, &lockFreeSortedSetThingLink{next: link.next}) {
+						continue retry
+					}
+					curr = link.next
+					if curr == nil {
+						break
+					}
+					link = curr.next[level].Load()
+				}
+				if curr == nil || !ls.less(curr.val, v) {
+					break
+				}
+				pred = curr
+				curr = link.next
+			}
+			preds[level] = pred
+			succs[level] = curr
+		}
+		return curr != nil && !ls.less(v, curr.val)
+	}
+}
+
+// Adds an item to the set if it doesn't already exist in the set.
+func (ls *ThingLockFreeSortedSet) Add(v Thing) bool {
+	// arrays rather than slices, so they stay on the stack
+	var preds, succs [lockFreeSortedSetThingMaxLevels]*lockFreeSortedSetThingElement
+	levels := ls.randomLevels()
+	ls.raise(levels)
+	for {
+		if ls.find(v, preds[:], succs[:]) {
+			return false
+		}
+		e := newLockFreeSortedSetThingElement(v, levels, succs[:])
+
+		// the item is in the set once it is linked on the bottom level
+		predLink := preds[0].next[0].Load()
+		if predLink.marked || predLink.next != succs[0] ||
+			!preds[0].next[0].CompareAndSwap(predLink, &lockFreeSortedSetThingLink{next: e}) {
+			continue
+		}
+		ls.length.Add(1)
+
+		// the levels above are only shortcuts, link them as we can
+		for level := 1; level < levels; level++ {
+			for {
+				link := e.next[level].Load()
+				if link.marked {
+					// already being removed, no point linking it further
+					return true
+				}
+				if link.next != succs[level] &&
+					!e.next[level].CompareAndSwap(link, &lockFreeSortedSetThingLink{next: succs[level]}) {
+					continue
+				}
+				predLink := preds[level].next[level].Load()
+				if !predLink.marked && predLink.next == succs[level] &&
+					preds[level].next[level].CompareAndSwap(predLink, &lockFreeSortedSetThingLink{next: e}) {
+					break
+				}
+				ls.find(v, preds[:], succs[:])
+			}
+		}
+		return true
+	}
+}
+
+// Allows the removal of a single item in the set.
+// Returns true if this call removed the item.
+func (ls *ThingLockFreeSortedSet) Remove(v Thing) bool {
+	var preds, succs [lockFreeSortedSetThingMaxLevels]*lockFreeSortedSetThingElement
+	if !ls.find(v, preds[:], succs[:]) {
+		return false
+	}
+	e := succs[0]
+
+	// mark the levels above first, so nobody links e any higher
+	for level := len(e.next) - 1; level >= 1; level-- {
+		link := e.next[level].Load()
+		for !link.marked {
+			e.next[level].CompareAndSwap(link, &lockFreeSortedSetThingLink{next: link.next, marked: true})
+			link = e.next[level].Load()
+		}
+	}
+
+	// whoever marks the bottom level removes the item
+	for {
+		link := e.next[0].Load()
+		if link.marked {
+			return false
+		}
+		if e.next[0].CompareAndSwap(link, &lockFreeSortedSetThingLink{next: link.next, marked: true}) {
+			ls.length.Add(-1)
+			// unlink it
+			ls.find(v, preds[:], succs[:])
+			return true
+		}
+	}
+}
+
+// Determines if a given item is in the set. It never waits on other
+// goroutines, and leaves unlinking marked elements to Add and Remove.
+func (ls *ThingLockFreeSortedSet) Contains(v Thing) bool {
+	pred := ls.head
+	var curr *lockFreeSortedSetThingElement
+	for level := int(ls.level.Load()) - 1; level >= 0; level-- {
+		curr = pred.next[level].Load().next
+		for curr != nil {
+			link := curr.next[level].Load()
+			if link.marked {
+				// step over removed elements
+				curr = link.next
+				continue
+			}
+			if !ls.less(curr.val, v) {
+				break
+			}
+			pred = curr
+			curr = link.next
+		}
+	}
+	return curr != nil && !ls.less(v, curr.val) && !curr.next[0].Load().marked
+}
+
+// Cardinality returns how many items are currently in the set.
+// While other goroutines are changing the set it is only approximate: a
+// Remove can count its item out before the Add that put it in counts it
+// in, so it is clamped at 0.
+func (ls *ThingLockFreeSortedSet) Cardinality() int {
+	if n := ls.length.Load(); n > 0 {
+		return int(n)
+	}
+	return 0
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (ls *ThingLockFreeSortedSet) Len() int {
+	return ls.Cardinality()
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (ls *ThingLockFreeSortedSet) Each(f func(v Thing) bool) {
+	it := ls.Iterator()
+	for it.Next() {
+		if !f(it.Value()) {
+			return
+		}
+	}
+}
+
+// ToSlice returns the items in the set in ascending order.
+func (ls *ThingLockFreeSortedSet) ToSlice() []Thing {
+	var s []Thing
+	ls.Each(func(v Thing) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// A cursor over the items of a ThingLockFreeSortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next or Seek has returned true.
+type ThingLockFreeSortedSetIterator struct {
+	ls *ThingLockFreeSortedSet
+	e  *lockFreeSortedSetThingElement
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (ls *ThingLockFreeSortedSet) Iterator() *ThingLockFreeSortedSetIterator {
+	return &ThingLockFreeSortedSetIterator{ls: ls, e: ls.head}
+}
+
+// Next moves the cursor to the next item still in the set, returning false
+// once it has moved past the last one.
+func (it *ThingLockFreeSortedSetIterator) Next() bool {
+	if it.e == nil {
+		return false
+	}
+	it.e = it.e.next[0].Load().next
+	for it.e != nil && it.e.next[0].Load().marked {
+		it.e = it.e.next[0].Load().next
+	}
+	return it.e != nil
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *ThingLockFreeSortedSetIterator) Seek(v Thing) bool {
+	var preds, succs [lockFreeSortedSetThingMaxLevels]*lockFreeSortedSetThingElement
+	it.ls.find(v, preds[:], succs[:])
+	it.e = preds[0]
+	return it.Next()
+}
+
+// Value returns the item at the cursor.
+func (it *ThingLockFreeSortedSetIterator) Value() Thing {
+	return it.e.val
+}
+
+// A sorted set that never changes once made, backed by a persistent treap.
+// With and Without return new sets that share every node off the changed
+// path with the old one, so old versions stay valid and cost nothing to
+// keep or to hand to other goroutines.
+type ThingImmutableSortedSet struct {
+	less   func(a, b Thing) bool
+	root   *immutableSortedSetThingNode
+	length int
+}
+
+// the struct to hold nodes of the treap; nodes are never changed once
+// they are reachable from a set
+type immutableSortedSetThingNode struct {
+	val         Thing
+	priority    uint32
+	left, right *immutableSortedSetThingNode
+}
+
+// Creates and returns an empty immutable set.
+func NewThingImmutableSortedSet(less func(Thing, Thing) bool) ThingImmutableSortedSet {
+	return ThingImmutableSortedSet{less: less}
+}
+
+// Creates and returns an immutable set from a slice already sorted by
+// less, in O(n). Runs of equal items are only added once.
+func NewThingImmutableSortedSetFromSortedSlice(less func(Thing, Thing) bool, s []Thing) ThingImmutableSortedSet {
+	is := ThingImmutableSortedSet{less: less}
+	// build the treap left to right, keeping its right spine on a stack
+	var spine []*immutableSortedSetThingNode
+	for i, v := range s {
+		if i > 0 && !less(s[i-1], v) {
+			// equal to the item before it
+			continue
+		}
+		n := &immutableSortedSetThingNode{val: v, priority: rand.Uint32()}
+		var last *immutableSortedSetThingNode
+		for len(spine) > 0 && spine[len(spine)-1].priority < n.priority {
+			last = spine[len(spine)-1]
+			spine = spine[:len(spine)-1]
+		}
+		n.left = last
+		if len(spine) > 0 {
+			spine[len(spine)-1].right = n
+		}
+		spine = append(spine, n)
+		is.length++
+	}
+	if len(spine) > 0 {
+		is.root = spine[0]
+	}
+	return is
+}
+
+// Creates and returns an immutable set from an existing slice.
+func NewThingImmutableSortedSetFromSlice(less func(Thing, Thing) bool, s []Thing) ThingImmutableSortedSet {
+	is := NewThingImmutableSortedSet(less)
+	for _, v := range s {
+		is = is.With(v)
+	}
+	return is
+}
+
+// With returns a set holding v as well as every item of this one, or this
+// set itself if v is already in it.
+func (is ThingImmutableSortedSet) With(v Thing) ThingImmutableSortedSet {
+	root := is.with(is.root, v)
+	if root == is.root {
+		return is
+	}
+	return ThingImmutableSortedSet{less: is.less, root: root, length: is.length + 1}
+}
+
+// with returns a copy of the path from n down to where v belongs, with v
+// added, or n itself if v is already there.
+func (is ThingImmutableSortedSet) with(n *immutableSortedSetThingNode, v Thing) *immutableSortedSetThingNode {
+	if n == nil {
+		// random priorities keep the treap balanced; the global source is
+		// used since sets can be shared between goroutines
+		return &immutableSortedSetThingNode{val: v, priority: rand.Uint32()}
+	}
+	switch {
+	case is.less(v, n.val):
+		left := is.with(n.left, v)
+		if left == n.left {
+			return n
+		}
+		// left is a fresh copy, so it's ours to rotate
+		if left.priority > n.priority {
+			c := *n
+			c.left = left.right
+			left.right = &c
+			return left
+		}
+		c := *n
+		c.left = left
+		return &c
+	case is.less(n.val, v):
+		right := is.with(n.right, v)
+		if right == n.right {
+			return n
+		}
+		if right.priority > n.priority {
+			c := *n
+			c.right = right.left
+			right.left = &c
+			return right
+		}
+		c := *n
+		c.right = right
+		return &c
+	}
+	return n
+}
+
+// Without returns a set holding every item of this one except v, or this
+// set itself if v is not in it.
+func (is ThingImmutableSortedSet) Without(v Thing) ThingImmutableSortedSet {
+	root := is.without(is.root, v)
+	if root == is.root {
+		return is
+	}
+	return ThingImmutableSortedSet{less: is.less, root: root, length: is.length - 1}
+}
+
+// without returns a copy of the path from n down to v, with v removed,
+// or n itself if v is not there.
+func (is ThingImmutableSortedSet) without(n *immutableSortedSetThingNode, v Thing) *immutableSortedSetThingNode {
+	if n == nil {
+		return nil
+	}
+	switch {
+	case is.less(v, n.val):
+		left := is.without(n.left, v)
+		if left == n.left {
+			return n
+		}
+		c := *n
+		c.left = left
+		return &c
+	case is.less(n.val, v):
+		right := is.without(n.right, v)
+		if right == n.right {
+			return n
+		}
+		c := *n
+		c.right = right
+		return &c
+	}
+	return mergeImmutableSortedSetThingNodes(n.left, n.right)
+}
+
+// mergeImmutableSortedSetThingNodes joins two treaps, every item of a
+// being less than every item of b, copying only the nodes along the seam.
+func mergeImmutableSortedSetThingNodes(a, b *immutableSortedSetThingNode) *immutableSortedSetThingNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.priority > b.priority {
+		c := *a
+		c.right = mergeImmutableSortedSetThingNodes(a.right, b)
+		return &c
+	}
+	c := *b
+	c.left = mergeImmutableSortedSetThingNodes(a, b.left)
+	return &c
+}
+
+// Determines if a given item is in the set.
+func (is ThingImmutableSortedSet) Contains(v Thing) bool {
+	n := is.root
+	for n != nil {
+		switch {
+		case is.less(v, n.val):
+			n = n.left
+		case is.less(n.val, v):
+			n = n.right
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Cardinality returns how many items are in the set.
+func (is ThingImmutableSortedSet) Cardinality() int {
+	return is.length
+}
+
+// Len returns how many items are in the set, like Cardinality.
+func (is ThingImmutableSortedSet) Len() int {
+	return is.length
+}
+
+// First returns the least item in the set.
+// ok is false if the set is empty.
+func (is ThingImmutableSortedSet) First() (val Thing, ok bool) {
+	n := is.root
+	for n != nil && n.left != nil {
+		n = n.left
+	}
+	if n != nil {
+		return n.val, true
+	}
+	return
+}
+
+// Last returns the greatest item in the set.
+// ok is false if the set is empty.
+func (is ThingImmutableSortedSet) Last() (val Thing, ok bool) {
+	n := is.root
+	for n != nil && n.right != nil {
+		n = n.right
+	}
+	if n != nil {
+		return n.val, true
+	}
+	return
+}
+
+// floor returns the greatest item in the set less than or equal to v, or
+// if strict, less than v.
+func (is ThingImmutableSortedSet) floor(v Thing, strict bool) (val Thing, ok bool) {
+	for n := is.root; n != nil; {
+		switch {
+		case is.less(n.val, v):
+			// a candidate, but there may be a greater one to the right
+			val, ok = n.val, true
+			n = n.right
+		case is.less(v, n.val) || strict:
+			n = n.left
+		default:
+			return n.val, true
+		}
+	}
+	return
+}
+
+// ceiling returns the least item in the set greater than or equal to v, or
+// if strict, greater than v.
+func (is ThingImmutableSortedSet) ceiling(v Thing, strict bool) (val Thing, ok bool) {
+	for n := is.root; n != nil; {
+		switch {
+		case is.less(v, n.val):
+			// a candidate, but there may be a lesser one to the left
+			val, ok = n.val, true
+			n = n.left
+		case is.less(n.val, v) || strict:
+			n = n.right
+		default:
+			return n.val, true
+		}
+	}
+	return
+}
+
+// Floor returns the greatest item in the set less than or equal to v.
+// ok is false if there is no such item.
+func (is ThingImmutableSortedSet) Floor(v Thing) (val Thing, ok bool) {
+	return is.floor(v, false)
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+// ok is false if there is no such item.
+func (is ThingImmutableSortedSet) Ceiling(v Thing) (val Thing, ok bool) {
+	return is.ceiling(v, false)
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+// ok is false if there is no such item.
+func (is ThingImmutableSortedSet) Lower(v Thing) (val Thing, ok bool) {
+	return is.floor(v, true)
+}
+
+// Higher returns the least item in the set strictly greater than v.
+// ok is false if there is no such item.
+func (is ThingImmutableSortedSet) Higher(v Thing) (val Thing, ok bool) {
+	return is.ceiling(v, true)
+}
+
+// A read-only view of the items of a ThingImmutableSortedSet between
+// two bounds. The set never changes, so neither does the view.
+type ThingImmutableSortedSetView struct {
+	is                       ThingImmutableSortedSet
+	lo, hi                   Thing
+	hasLo, hasHi             bool
+	loInclusive, hiInclusive bool
+}
+
+// Range returns a view of the items between lo and hi, each bound being
+// included or excluded as requested.
+func (is ThingImmutableSortedSet) Range(lo, hi Thing, loInclusive, hiInclusive bool) ThingImmutableSortedSetView {
+	return ThingImmutableSortedSetView{is: is, lo: lo, hi: hi, hasLo: true, hasHi: true, loInclusive: loInclusive, hiInclusive: hiInclusive}
+}
+
+// HeadSet returns a view of the items strictly less than hi.
+func (is ThingImmutableSortedSet) HeadSet(hi Thing) ThingImmutableSortedSetView {
+	return ThingImmutableSortedSetView{is: is, hi: hi, hasHi: true}
+}
+
+// TailSet returns a view of the items greater than or equal to lo.
+func (is ThingImmutableSortedSet) TailSet(lo Thing) ThingImmutableSortedSetView {
+	return ThingImmutableSortedSetView{is: is, lo: lo, hasLo: true, loInclusive: true}
+}
+
+// Each calls f for every item in the view in ascending order, stopping
+// early if f returns false.
+func (sv ThingImmutableSortedSetView) Each(f func(v Thing) bool) {
+	it := sv.is.Iterator()
+	var ok bool
+	if !sv.hasLo {
+		ok = it.Next()
+	} else if ok = it.Seek(sv.lo); ok && !sv.loInclusive && !sv.is.less(sv.lo, it.Value()) {
+		ok = it.Next()
+	}
+	for ; ok; ok = it.Next() {
+		v := it.Value()
+		if sv.hasHi && (sv.is.less(sv.hi, v) || !sv.hiInclusive && !sv.is.less(v, sv.hi)) {
+			return
+		}
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// Cardinality returns how many items are in the view.
+// Unlike the set's, this walks every item in the view.
+func (sv ThingImmutableSortedSetView) Cardinality() int {
+	n := 0
+	sv.Each(func(v Thing) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// ToSlice returns the items in the view in ascending order.
+func (sv ThingImmutableSortedSetView) ToSlice() []Thing {
+	var s []Thing
+	sv.Each(func(v Thing) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// ToSet returns the items in the view as a set of their own, in O(k).
+func (sv ThingImmutableSortedSetView) ToSet() ThingImmutableSortedSet {
+	return NewThingImmutableSortedSetFromSortedSlice(sv.is.less, sv.ToSlice())
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (is ThingImmutableSortedSet) Each(f func(v Thing) bool) {
+	it := is.Iterator()
+	for it.Next() {
+		if !f(it.Value()) {
+			return
+		}
+	}
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+func (is ThingImmutableSortedSet) ReverseEach(f func(v Thing) bool) {
+	it := is.ReverseIterator()
+	for it.Prev() {
+		if !f(it.Value()) {
+			return
+		}
+	}
+}
+
+// ToSlice returns the items in the set in ascending order.
+func (is ThingImmutableSortedSet) ToSlice() []Thing {
+	s := make([]Thing, 0, is.length)
+	is.Each(func(v Thing) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// ToBuilder returns a builder starting out with the items of this set.
+func (is ThingImmutableSortedSet) ToBuilder() *ThingImmutableSortedSetBuilder {
+	return &ThingImmutableSortedSetBuilder{set: is}
+}
+
+// A cursor over the items of a ThingImmutableSortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next, Prev or Seek has returned true.
+type ThingImmutableSortedSetIterator struct {
+	is      ThingImmutableSortedSet
+	stack   []*immutableSortedSetThingNode // the path from the root to the node at the cursor
+	started bool                           // with an empty stack, whether the cursor is past the last item rather than before the first
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (is ThingImmutableSortedSet) Iterator() *ThingImmutableSortedSetIterator {
+	return &ThingImmutableSortedSetIterator{is: is}
+}
+
+// ReverseIterator returns a cursor positioned after the last item of the
+// set, to be walked from largest to smallest with Prev.
+func (is ThingImmutableSortedSet) ReverseIterator() *ThingImmutableSortedSetIterator {
+	return &ThingImmutableSortedSetIterator{is: is, started: true}
+}
+
+// pushFirst extends the path down to the least item under n.
+func (it *ThingImmutableSortedSetIterator) pushFirst(n *immutableSortedSetThingNode) {
+	for ; n != nil; n = n.left {
+		it.stack = append(it.stack, n)
+	}
+}
+
+// pushLast extends the path down to the greatest item under n.
+func (it *ThingImmutableSortedSetIterator) pushLast(n *immutableSortedSetThingNode) {
+	for ; n != nil; n = n.right {
+		it.stack = append(it.stack, n)
+	}
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *ThingImmutableSortedSetIterator) Next() bool {
+	if len(it.stack) == 0 {
+		if it.started || it.is.root == nil {
+			it.started = true
+			return false
+		}
+		it.started = true
+		it.pushFirst(it.is.root)
+		return true
+	}
+	if n := it.stack[len(it.stack)-1]; n.right != nil {
+		it.pushFirst(n.right)
+		return true
+	}
+	// climb to the nearest node whose left subtree the cursor was in
+	for {
+		child := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			return false
+		}
+		if it.stack[len(it.stack)-1].left == child {
+			return true
+		}
+	}
+}
+
+// Prev moves the cursor to the previous item, returning false once it has
+// moved before the first one.
+func (it *ThingImmutableSortedSetIterator) Prev() bool {
+	if len(it.stack) == 0 {
+		if !it.started || it.is.root == nil {
+			it.started = false
+			return false
+		}
+		it.pushLast(it.is.root)
+		return true
+	}
+	if n := it.stack[len(it.stack)-1]; n.left != nil {
+		it.pushLast(n.left)
+		return true
+	}
+	// climb to the nearest node whose right subtree the cursor was in
+	for {
+		child := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			it.started = false
+			return false
+		}
+		if it.stack[len(it.stack)-1].right == child {
+			return true
+		}
+	}
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *ThingImmutableSortedSetIterator) Seek(v Thing) bool {
+	it.stack = it.stack[:0]
+	it.started = true
+	// the length of the path down to the least item seen greater than v
+	ceiling := 0
+	for n := it.is.root; n != nil; {
+		it.stack = append(it.stack, n)
+		switch {
+		case it.is.less(v, n.val):
+			ceiling = len(it.stack)
+			n = n.left
+		case it.is.less(n.val, v):
+			n = n.right
+		default:
+			return true
+		}
+	}
+	it.stack = it.stack[:ceiling]
+	return ceiling > 0
+}
+
+// Value returns the item at the cursor.
+func (it *ThingImmutableSortedSetIterator) Value() Thing {
+	return it.stack[len(it.stack)-1].val
+}
+
+// A mutable sorted set whose current contents can be taken as a
+// ThingImmutableSortedSet in O(1), at any time and as often as needed.
+// Changes made afterwards copy only the nodes they touch, so they never
+// show up in snapshots already taken. Use it in place of a
+// ThingSortedSet wherever readers need snapshots; a SortedSet can only
+// hand them a Clone, which copies every item.
+type ThingImmutableSortedSetBuilder struct {
+	set ThingImmutableSortedSet
+}
+
+// Creates and returns a reference to an empty builder.
+func NewThingImmutableSortedSetBuilder(less func(Thing, Thing) bool) *ThingImmutableSortedSetBuilder {
+	return &ThingImmutableSortedSetBuilder{set: NewThingImmutableSortedSet(less)}
+}
+
+// Adds an item to the builder if it isn't already in it.
+func (b *ThingImmutableSortedSetBuilder) Add(v Thing) bool {
+	before := b.set.length
+	b.set = b.set.With(v)
+	return b.set.length != before
+}
+
+// Allows the removal of a single item in the builder.
+// Returns true if the item was in it.
+func (b *ThingImmutableSortedSetBuilder) Remove(v Thing) bool {
+	before := b.set.length
+	b.set = b.set.Without(v)
+	return b.set.length != before
+}
+
+// Determines if a given item is in the builder.
+func (b *ThingImmutableSortedSetBuilder) Contains(v Thing) bool {
+	return b.set.Contains(v)
+}
+
+// Removes every item from the builder, leaving snapshots as they were.
+func (b *ThingImmutableSortedSetBuilder) Clear() {
+	b.set = NewThingImmutableSortedSet(b.set.less)
+}
+
+// Cardinality returns how many items are in the builder.
+func (b *ThingImmutableSortedSetBuilder) Cardinality() int {
+	return b.set.length
+}
+
+// Len returns how many items are in the builder, like Cardinality.
+func (b *ThingImmutableSortedSetBuilder) Len() int {
+	return b.set.length
+}
+
+// First returns the least item in the builder.
+// ok is false if the builder is empty.
+func (b *ThingImmutableSortedSetBuilder) First() (val Thing, ok bool) {
+	return b.set.First()
+}
+
+// Last returns the greatest item in the builder.
+// ok is false if the builder is empty.
+func (b *ThingImmutableSortedSetBuilder) Last() (val Thing, ok bool) {
+	return b.set.Last()
+}
+
+// Floor returns the greatest item in the builder less than or equal to v.
+// ok is false if there is no such item.
+func (b *ThingImmutableSortedSetBuilder) Floor(v Thing) (val Thing, ok bool) {
+	return b.set.Floor(v)
+}
+
+// Ceiling returns the least item in the builder greater than or equal to v.
+// ok is false if there is no such item.
+func (b *ThingImmutableSortedSetBuilder) Ceiling(v Thing) (val Thing, ok bool) {
+	return b.set.Ceiling(v)
+}
+
+// Lower returns the greatest item in the builder strictly less than v.
+// ok is false if there is no such item.
+func (b *ThingImmutableSortedSetBuilder) Lower(v Thing) (val Thing, ok bool) {
+	return b.set.Lower(v)
+}
+
+// Higher returns the least item in the builder strictly greater than v.
+// ok is false if there is no such item.
+func (b *ThingImmutableSortedSetBuilder) Higher(v Thing) (val Thing, ok bool) {
+	return b.set.Higher(v)
+}
+
+// Range returns a view of the builder's current items between lo and hi,
+// each bound being included or excluded as requested. Later changes to the
+// builder don't show up in the view.
+func (b *ThingImmutableSortedSetBuilder) Range(lo, hi Thing, loInclusive, hiInclusive bool) ThingImmutableSortedSetView {
+	return b.set.Range(lo, hi, loInclusive, hiInclusive)
+}
+
+// HeadSet returns a view of the builder's current items strictly less than hi.
+func (b *ThingImmutableSortedSetBuilder) HeadSet(hi Thing) ThingImmutableSortedSetView {
+	return b.set.HeadSet(hi)
+}
+
+// TailSet returns a view of the builder's current items greater than or equal to lo.
+func (b *ThingImmutableSortedSetBuilder) TailSet(lo Thing) ThingImmutableSortedSetView {
+	return b.set.TailSet(lo)
+}
+
+// Each calls f for every item in ascending order, stopping early if f
+// returns false. f may change the builder; the walk carries on over the
+// items as they were when it started.
+func (b *ThingImmutableSortedSetBuilder) Each(f func(v Thing) bool) {
+	b.set.Each(f)
+}
+
+// ReverseEach calls f for every item in descending order, stopping early
+// if f returns false. Like Each, f may change the builder.
+func (b *ThingImmutableSortedSetBuilder) ReverseEach(f func(v Thing) bool) {
+	b.set.ReverseEach(f)
+}
+
+// Iterator returns a cursor positioned before the first of the builder's
+// current items. It walks a snapshot, so later changes to the builder
+// neither show up in it nor invalidate it.
+func (b *ThingImmutableSortedSetBuilder) Iterator() *ThingImmutableSortedSetIterator {
+	return b.set.Iterator()
+}
+
+// ReverseIterator returns a cursor positioned after the last of the
+// builder's current items, to be walked from largest to smallest with Prev.
+func (b *ThingImmutableSortedSetBuilder) ReverseIterator() *ThingImmutableSortedSetIterator {
+	return b.set.ReverseIterator()
+}
+
+// ToSlice returns the items in the builder in ascending order.
+func (b *ThingImmutableSortedSetBuilder) ToSlice() []Thing {
+	return b.set.ToSlice()
+}
+
+// Snapshot returns the builder's current items as an immutable set, in O(1).
+func (b *ThingImmutableSortedSetBuilder) Snapshot() ThingImmutableSortedSet {
+	return b.set
+}
+
+// A sorted set backed by a B-tree with up to 4 children per node.
+// It has the methods of ThingSortedSet, bar its skiplist options, so
+// switching a tag from one to the other only changes the type's name, but
+// keeps items packed in slices, which makes it faster to search and
+// smaller than a skiplist.
+// Changing a set invalidates its iterators, and f must not change the set
+// while Each or ReverseEach is running.
+type ThingBTreeSortedSet struct {
+	less    func(a, b Thing) bool
+	compare func(a, b Thing) int
+	root    *btreeSortedSetThingNode // nil when the set is empty
+	length  int
+}
+
+// the struct to hold nodes of the B-tree; leaves have no children, other
+// nodes have one more child than items, children[i] holding the items
+// between items[i-1] and items[i]
+type btreeSortedSetThingNode struct {
+	items    []Thing
+	children []*btreeSortedSetThingNode
+}
+
+// every node but the root holds between the min and max number of items
+const (
+	btreeSortedSetThingMaxItems = 4 - 1
+	btreeSortedSetThingMinItems = btreeSortedSetThingMaxItems / 2
+)
+
+// Creates and returns a reference to an empty set.
+func NewThingBTreeSortedSet(less func(Thing, Thing) bool) *ThingBTreeSortedSet {
+	compare := func(a, b Thing) int {
+		if less(a, b) {
+			return -1
+		}
+		if less(b, a) {
+			return 1
+		}
+		return 0
+	}
+	return &ThingBTreeSortedSet{less: less, compare: compare}
+}
+
+// Creates and returns a reference to an empty set ordered by a three-way
+// comparison, as NewThingSortedSetWithCompare.
+func NewThingBTreeSortedSetWithCompare(compare func(Thing, Thing) int) *ThingBTreeSortedSet {
+	less := func(a, b Thing) bool {
+		return compare(a, b) < 0
+	}
+	return &ThingBTreeSortedSet{less: less, compare: compare}
+}
+
+// Creates and returns a reference to an empty set ordered by the < operator.
+func NewThingBTreeSortedSetNatural() *ThingBTreeSortedSet {
+	return NewThingBTreeSortedSetWithCompare(func(a, b Thing) int {
+		switch {
+		case a < b:
+			return -1
+		case b < a:
+			return 1
+		}
+		return 0
+	})
+}
+
+// Creates and returns a reference to a set from an existing slice.
+func NewThingBTreeSortedSetFromSlice(less func(Thing, Thing) bool, s []Thing) *ThingBTreeSortedSet {
+	bs := NewThingBTreeSortedSet(less)
+	for _, v := range s {
+		bs.Add(v)
+	}
+	return bs
+}
+
+// Creates and returns a reference to a set from a slice already sorted by
+// less, in O(n). Runs of equal items are only added once.
+func NewThingBTreeSortedSetFromSortedSlice(less func(Thing, Thing) bool, s []Thing) *ThingBTreeSortedSet {
+	distinct := make([]Thing, 0, len(s))
+	for i, v := range s {
+		if i > 0 && !less(s[i-1], v) {
+			// equal to the item before it
+			continue
+		}
+		distinct = append(distinct, v)
+	}
+	return NewThingBTreeSortedSet(less).fromSorted(distinct)
+}
+
+// fromSorted returns a new set ordered the same way as this one, holding
+// the given items, which must be sorted and distinct.
+func (bs *ThingBTreeSortedSet) fromSorted(items []Thing) *ThingBTreeSortedSet {
+	set := &ThingBTreeSortedSet{less: bs.less, compare: bs.compare, length: len(items)}
+	if len(items) == 0 {
+		return set
+	}
+	// find the least height that can hold every item
+	levels := 1
+	for most := btreeSortedSetThingMaxItems; most < len(items); most = most*(btreeSortedSetThingMaxItems+1) + btreeSortedSetThingMaxItems {
+		levels++
+	}
+	set.root = buildBTreeSortedSetThingNode(items, levels, true)
+	return set
+}
+
+// buildBTreeSortedSetThingNode returns a subtree of the given height
+// holding items, sharing them out as evenly as it can so that every node
+// is at least half full.
+func buildBTreeSortedSetThingNode(items []Thing, levels int, root bool) *btreeSortedSetThingNode {
+	if levels == 1 {
+		n := newBTreeSortedSetThingNode(false)
+		n.items = append(n.items, items...)
+		return n
+	}
+	// capacity is one more than the most items a child can hold
+	capacity := 1
+	for level := 1; level < levels; level++ {
+		capacity *= btreeSortedSetThingMaxItems + 1
+	}
+	k := (len(items) + capacity) / capacity
+	least := btreeSortedSetThingMinItems + 1
+	if root {
+		least = 2
+	}
+	if k < least {
+		k = least
+	}
+	n := newBTreeSortedSetThingNode(true)
+	size, extra := (len(items)-(k-1))/k, (len(items)-(k-1))%k
+	for j := 0; j < k; j++ {
+		m := size
+		if j < extra {
+			m++
+		}
+		n.children = append(n.children, buildBTreeSortedSetThingNode(items[:m], levels-1, false))
+		items = items[m:]
+		if j < k-1 {
+			n.items = append(n.items, items[0])
+			items = items[1:]
+		}
+	}
+	return n
+}
+
+func newBTreeSortedSetThingNode(internal bool) *btreeSortedSetThingNode {
+	n := &btreeSortedSetThingNode{items: make([]Thing, 0, btreeSortedSetThingMaxItems)}
+	if internal {
+		n.children = make([]*btreeSortedSetThingNode, 0, btreeSortedSetThingMaxItems+1)
+	}
+	return n
+}
+
+func (n *btreeSortedSetThingNode) leaf() bool {
+	return len(n.children) == 0
+}
+
+func (n *btreeSortedSetThingNode) insertItem(i int, v Thing) {
+	n.items = append(n.items, v)
+	copy(n.items[i+1:], n.items[i:])
+	n.items[i] = v
+}
+
+func (n *btreeSortedSetThingNode) removeItem(i int) Thing {
+	v := n.items[i]
+	copy(n.items[i:], n.items[i+1:])
+	var zero Thing
+	n.items[len(n.items)-1] = zero
+	n.items = n.items[:len(n.items)-1]
+	return v
+}
+
+func (n *btreeSortedSetThingNode) insertChild(i int, c *btreeSortedSetThingNode) {
+	n.children = append(n.children, c)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = c
+}
+
+func (n *btreeSortedSetThingNode) removeChild(i int) *btreeSortedSetThingNode {
+	c := n.children[i]
+	copy(n.children[i:], n.children[i+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+	return c
+}
+
+// split moves the items after item i, and the children after them, into a
+// new node, returning item i, which is taken out too, and the new node.
+func (n *btreeSortedSetThingNode) split(i int) (Thing, *btreeSortedSetThingNode) {
+	v := n.items[i]
+	next := newBTreeSortedSetThingNode(!n.leaf())
+	next.items = append(next.items, n.items[i+1:]...)
+	var zero Thing
+	for j := i; j < len(n.items); j++ {
+		n.items[j] = zero
+	}
+	n.items = n.items[:i]
+	if !n.leaf() {
+		next.children = append(next.children, n.children[i+1:]...)
+		for j := i + 1; j < len(n.children); j++ {
+			n.children[j] = nil
+		}
+		n.children = n.children[:i+1]
+	}
+	return v, next
+}
+
+// find returns the index of v in n's items, and true, or else the index of
+// the child v belongs under, and false.
+func (bs *ThingBTreeSortedSet) find(n *btreeSortedSetThingNode, v Thing) (int, bool) {
+	lo, hi := 0, len(n.items)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		switch c := bs.compare(n.items[m], v); {
+		case c < 0:
+			lo = m + 1
+		case c > 0:
+			hi = m
+		default:
+			return m, true
+		}
+	}
+	return lo, false
+}
+
+// Adds an item to the set if it doesn't already exist in the set.
+func (bs *ThingBTreeSortedSet) Add(v Thing) bool {
+	if bs.root == nil {
+		bs.root = newBTreeSortedSetThingNode(false)
+	}
+	// full nodes are split on the way down, so there is always room for v
+	if len(bs.root.items) == btreeSortedSetThingMaxItems {
+		old := bs.root
+		item, next := old.split(btreeSortedSetThingMaxItems / 2)
+		bs.root = newBTreeSortedSetThingNode(true)
+		bs.root.items = append(bs.root.items, item)
+		bs.root.children = append(bs.root.children, old, next)
+	}
+	n := bs.root
+	for {
+		i, found := bs.find(n, v)
+		if found {
+			return false
+		}
+		if n.leaf() {
+			n.insertItem(i, v)
+			bs.length++
+			return true
+		}
+		if len(n.children[i].items) == btreeSortedSetThingMaxItems {
+			item, next := n.children[i].split(btreeSortedSetThingMaxItems / 2)
+			n.insertItem(i, item)
+			n.insertChild(i+1, next)
+			switch c := bs.compare(v, item); {
+			case c == 0:
+				return false
+			case c > 0:
+				i++
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// AddAll adds every given item not already in the set, returning how many were added.
+func (bs *ThingBTreeSortedSet) AddAll(items ...Thing) int {
+	added := 0
+	for _, v := range items {
+		if bs.Add(v) {
+			added++
+		}
+	}
+	return added
+}
+
+// grow gives n.children[i], which has the fewest items a node may have, one
+// more, by taking one from a sibling through n or by merging it with one.
+func (bs *ThingBTreeSortedSet) grow(n *btreeSortedSetThingNode, i int) {
+	switch {
+	case i > 0 && len(n.children[i-1].items) > btreeSortedSetThingMinItems:
+		// rotate an item in from the left sibling
+		child, left := n.children[i], n.children[i-1]
+		child.insertItem(0, n.items[i-1])
+		n.items[i-1] = left.removeItem(len(left.items) - 1)
+		if !left.leaf() {
+			child.insertChild(0, left.removeChild(len(left.children)-1))
+		}
+	case i < len(n.items) && len(n.children[i+1].items) > btreeSortedSetThingMinItems:
+		// rotate an item in from the right sibling
+		child, right := n.children[i], n.children[i+1]
+		child.items = append(child.items, n.items[i])
+		n.items[i] = right.removeItem(0)
+		if !right.leaf() {
+			child.children = append(child.children, right.removeChild(0))
+		}
+	default:
+		// merge the child with a sibling and the item between them
+		if i == len(n.items) {
+			i--
+		}
+		child := n.children[i]
+		child.items = append(child.items, n.removeItem(i))
+		right := n.removeChild(i + 1)
+		child.items = append(child.items, right.items...)
+		child.children = append(child.children, right.children...)
+	}
+}
+
+// remove removes v from the subtree under n, which must have more than the
+// fewest items a node may have unless it is the root.
+func (bs *ThingBTreeSortedSet) remove(n *btreeSortedSetThingNode, v Thing) bool {
+	for {
+		i, found := bs.find(n, v)
+		if n.leaf() {
+			if found {
+				n.removeItem(i)
+			}
+			return found
+		}
+		if len(n.children[i].items) <= btreeSortedSetThingMinItems {
+			// make room to remove from the child, then look again, as
+			// this may have moved v
+			bs.grow(n, i)
+			continue
+		}
+		if found {
+			// replace v with the greatest item before it, which is in a leaf
+			n.items[i] = bs.removeLast(n.children[i])
+			return true
+		}
+		n = n.children[i]
+	}
+}
+
+// removeFirst removes and returns the least item under n, which must not
+// be empty and must have more than the fewest items a node may have
+// unless it is the root.
+func (bs *ThingBTreeSortedSet) removeFirst(n *btreeSortedSetThingNode) Thing {
+	for !n.leaf() {
+		if len(n.children[0].items) <= btreeSortedSetThingMinItems {
+			bs.grow(n, 0)
+			continue
+		}
+		n = n.children[0]
+	}
+	return n.removeItem(0)
+}
+
+// removeLast removes and returns the greatest item under n, as removeFirst.
+func (bs *ThingBTreeSortedSet) removeLast(n *btreeSortedSetThingNode) Thing {
+	for !n.leaf() {
+		last := len(n.children) - 1
+		if len(n.children[last].items) <= btreeSortedSetThingMinItems {
+			bs.grow(n, last)
+			continue
+		}
+		n = n.children[last]
+	}
+	return n.removeItem(len(n.items) - 1)
+}
+
+// shrink drops the root once it has no items left, as merging its last
+// two children or removing the last item leaves it.
+func (bs *ThingBTreeSortedSet) shrink() {
+	if bs.root != nil && len(bs.root.items) == 0 {
+		if bs.root.leaf() {
+			bs.root = nil
+		} else {
+			bs.root = bs.root.children[0]
+		}
+	}
+}
+
+// Allows the removal of a single item in the set.
+// Returns true if the item was in the set.
+func (bs *ThingBTreeSortedSet) Remove(v Thing) bool {
+	if bs.root == nil {
+		return false
+	}
+	removed := bs.remove(bs.root, v)
+	bs.shrink()
+	if removed {
+		bs.length--
+	}
+	return removed
+}
+
+// RemoveAll removes every given item that is in the set, returning how many were removed.
+func (bs *ThingBTreeSortedSet) RemoveAll(items ...Thing) int {
+	removed := 0
+	for _, v := range items {
+		if bs.Remove(v) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// RetainAll removes every item that is not in the other set, returning how
+// many were removed.
+func (bs *ThingBTreeSortedSet) RetainAll(other *ThingBTreeSortedSet) int {
+	before := bs.length
+	bs.IntersectWith(other)
+	return before - bs.length
+}
+
+// RemoveIf removes every item for which pred returns true, returning how
+// many were removed. The set is rebuilt in a single pass.
+func (bs *ThingBTreeSortedSet) RemoveIf(pred func(v Thing) bool) int {
+	before := bs.length
+	var kept []Thing
+	bs.Each(func(v Thing) bool {
+		if !pred(v) {
+			kept = append(kept, v)
+		}
+		return true
+	})
+	*bs = *bs.fromSorted(kept)
+	return before - bs.length
+}
+
+// Clears the entire set to be the empty set.
+func (bs *ThingBTreeSortedSet) Clear() {
+	bs.root = nil
+	bs.length = 0
+}
+
+// Determines if a given item is already in the set.
+func (bs *ThingBTreeSortedSet) Contains(v Thing) bool {
+	n := bs.root
+	for n != nil {
+		i, found := bs.find(n, v)
+		if found {
+			return true
+		}
+		if n.leaf() {
+			return false
+		}
+		n = n.children[i]
+	}
+	return false
+}
+
+// Determines if the given items are all in the set
+func (bs *ThingBTreeSortedSet) ContainsAll(i ...Thing) bool {
+	for _, v := range i {
+		if !bs.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// floor returns the greatest item in the set less than or equal to v, or
+// if strict, less than v.
+func (bs *ThingBTreeSortedSet) floor(v Thing, strict bool) (val Thing, ok bool) {
+	for n := bs.root; n != nil; {
+		i, found := bs.find(n, v)
+		if found && !strict {
+			return n.items[i], true
+		}
+		// the item before i is less than v, and children[i] may hold greater ones
+		if i > 0 {
+			val, ok = n.items[i-1], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return
+}
+
+// ceiling returns the least item in the set greater than or equal to v, or
+// if strict, greater than v.
+func (bs *ThingBTreeSortedSet) ceiling(v Thing, strict bool) (val Thing, ok bool) {
+	for n := bs.root; n != nil; {
+		i, found := bs.find(n, v)
+		if found {
+			if !strict {
+				return n.items[i], true
+			}
+			i++
+		}
+		// item i is greater than v, and children[i] may hold lesser ones
+		if i < len(n.items) {
+			val, ok = n.items[i], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return
+}
+
+// Floor returns the greatest item in the set less than or equal to v.
+// ok is false if there is no such item.
+func (bs *ThingBTreeSortedSet) Floor(v Thing) (val Thing, ok bool) {
+	return bs.floor(v, false)
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+// ok is false if there is no such item.
+func (bs *ThingBTreeSortedSet) Ceiling(v Thing) (val Thing, ok bool) {
+	return bs.ceiling(v, false)
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+// ok is false if there is no such item.
+func (bs *ThingBTreeSortedSet) Lower(v Thing) (val Thing, ok bool) {
+	return bs.floor(v, true)
+}
+
+// Higher returns the least item in the set strictly greater than v.
+// ok is false if there is no such item.
+func (bs *ThingBTreeSortedSet) Higher(v Thing) (val Thing, ok bool) {
+	return bs.ceiling(v, true)
+}
+
+// A read-only view of the items of a ThingBTreeSortedSet between two
+// bounds. The view is evaluated lazily, seeking to its lower bound each time
+// it is iterated, so it reflects changes made to the set after it was created.
+type ThingBTreeSortedSetView struct {
+	bs                       *ThingBTreeSortedSet
+	lo, hi                   Thing
+	hasLo, hasHi             bool
+	loInclusive, hiInclusive bool
+}
+
+// Range returns a view of the items between lo and hi, each bound being
+// included or excluded as requested.
+func (bs *ThingBTreeSortedSet) Range(lo, hi Thing, loInclusive, hiInclusive bool) ThingBTreeSortedSetView {
+	return ThingBTreeSortedSetView{bs: bs, lo: lo, hi: hi, hasLo: true, hasHi: true, loInclusive: loInclusive, hiInclusive: hiInclusive}
+}
+
+// HeadSet returns a view of the items strictly less than hi.
+func (bs *ThingBTreeSortedSet) HeadSet(hi Thing) ThingBTreeSortedSetView {
+	return ThingBTreeSortedSetView{bs: bs, hi: hi, hasHi: true}
+}
+
+// TailSet returns a view of the items greater than or equal to lo.
+func (bs *ThingBTreeSortedSet) TailSet(lo Thing) ThingBTreeSortedSetView {
+	return ThingBTreeSortedSetView{bs: bs, lo: lo, hasLo: true, loInclusive: true}
+}
+
+// Each calls f for every item in the view in ascending order, stopping
+// early if f returns false.
+func (sv ThingBTreeSortedSetView) Each(f func(v Thing) bool) {
+	it := sv.bs.Iterator()
+	var ok bool
+	if !sv.hasLo {
+		ok = it.Next()
+	} else if ok = it.Seek(sv.lo); ok && !sv.loInclusive && !sv.bs.less(sv.lo, it.Value()) {
+		ok = it.Next()
+	}
+	for ; ok; ok = it.Next() {
+		v := it.Value()
+		if sv.hasHi && (sv.bs.less(sv.hi, v) || !sv.hiInclusive && !sv.bs.less(v, sv.hi)) {
+			return
+		}
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// Cardinality returns how many items are currently in the view.
+// Unlike the set's, this walks every item in the view.
+func (sv ThingBTreeSortedSetView) Cardinality() int {
+	n := 0
+	sv.Each(func(v Thing) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// ToSlice returns the items in the view in ascending order.
+func (sv ThingBTreeSortedSetView) ToSlice() []Thing {
+	var s []Thing
+	sv.Each(func(v Thing) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// ToSet copies the items in the view into a new set.
+func (sv ThingBTreeSortedSetView) ToSet() *ThingBTreeSortedSet {
+	return sv.bs.fromSorted(sv.ToSlice())
+}
+
+// merge walks both sets side by side in O(n+m), calling f with every item
+// in either set and whether it is in each, until f returns false.
+func (bs *ThingBTreeSortedSet) merge(other *ThingBTreeSortedSet, f func(v Thing, inThis, inOther bool) bool) {
+	it, ot := bs.Iterator(), other.Iterator()
+	ok, ook := it.Next(), ot.Next()
+	for ok || ook {
+		var c int
+		switch {
+		case !ook:
+			c = -1
+		case !ok:
+			c = 1
+		default:
+			c = bs.compare(it.Value(), ot.Value())
+		}
+		switch {
+		case c < 0:
+			if !f(it.Value(), true, false) {
+				return
+			}
+			ok = it.Next()
+		case c > 0:
+			if !f(ot.Value(), false, true) {
+				return
+			}
+			ook = ot.Next()
+		default:
+			if !f(it.Value(), true, true) {
+				return
+			}
+			ok, ook = it.Next(), ot.Next()
+		}
+	}
+}
+
+// mergeInto returns a new set of the items merge finds for which keep
+// returns true.
+func (bs *ThingBTreeSortedSet) mergeInto(other *ThingBTreeSortedSet, keep func(inThis, inOther bool) bool) *ThingBTreeSortedSet {
+	var items []Thing
+	bs.merge(other, func(v Thing, inThis, inOther bool) bool {
+		if keep(inThis, inOther) {
+			items = append(items, v)
+		}
+		return true
+	})
+	return bs.fromSorted(items)
+}
+
+// IsSubset determines if every item in this set is in the other set.
+func (bs *ThingBTreeSortedSet) IsSubset(other *ThingBTreeSortedSet) bool {
+	if bs.length > other.length {
+		return false
+	}
+	subset := true
+	bs.merge(other, func(v Thing, inThis, inOther bool) bool {
+		subset = !inThis || inOther
+		return subset
+	})
+	return subset
+}
+
+// IsSuperset determines if every item in the other set is in this set.
+func (bs *ThingBTreeSortedSet) IsSuperset(other *ThingBTreeSortedSet) bool {
+	return other.IsSubset(bs)
+}
+
+// IsProperSubset determines if this set is a subset of the other set and
+// the other set has at least one item this one does not.
+func (bs *ThingBTreeSortedSet) IsProperSubset(other *ThingBTreeSortedSet) bool {
+	return bs.length < other.length && bs.IsSubset(other)
+}
+
+// IsProperSuperset determines if this set is a superset of the other set
+// and has at least one item the other set does not.
+func (bs *ThingBTreeSortedSet) IsProperSuperset(other *ThingBTreeSortedSet) bool {
+	return other.IsProperSubset(bs)
+}
+
+// IsDisjoint determines if the two sets have no items in common.
+// An empty set is disjoint with every set, itself included.
+func (bs *ThingBTreeSortedSet) IsDisjoint(other *ThingBTreeSortedSet) bool {
+	disjoint := true
+	bs.merge(other, func(v Thing, inThis, inOther bool) bool {
+		disjoint = !inThis || !inOther
+		return disjoint
+	})
+	return disjoint
+}
+
+// Overlaps determines if the two sets have at least one item in common.
+func (bs *ThingBTreeSortedSet) Overlaps(other *ThingBTreeSortedSet) bool {
+	return !bs.IsDisjoint(other)
+}
+
+// Returns a new set with all items in both sets, in O(n+m).
+func (bs *ThingBTreeSortedSet) Union(other *ThingBTreeSortedSet) *ThingBTreeSortedSet {
+	return bs.mergeInto(other, func(inThis, inOther bool) bool {
+		return true
+	})
+}
+
+// Returns a new set with items that exist only in both sets, in O(n+m).
+func (bs *ThingBTreeSortedSet) Intersect(other *ThingBTreeSortedSet) *ThingBTreeSortedSet {
+	return bs.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis && inOther
+	})
+}
+
+// Returns a new set with items in the current set but not in the other set, in O(n+m).
+func (bs *ThingBTreeSortedSet) Difference(other *ThingBTreeSortedSet) *ThingBTreeSortedSet {
+	return bs.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis && !inOther
+	})
+}
+
+// Returns a new set with items in the current set or the other set but not in both, in O(n+m).
+func (bs *ThingBTreeSortedSet) SymmetricDifference(other *ThingBTreeSortedSet) *ThingBTreeSortedSet {
+	return bs.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis != inOther
+	})
+}
+
+// UnionWith adds every item of the other set to this one.
+func (bs *ThingBTreeSortedSet) UnionWith(other *ThingBTreeSortedSet) {
+	*bs = *bs.Union(other)
+}
+
+// IntersectWith removes every item that is not also in the other set.
+func (bs *ThingBTreeSortedSet) IntersectWith(other *ThingBTreeSortedSet) {
+	*bs = *bs.Intersect(other)
+}
+
+// DifferenceWith removes every item that is also in the other set.
+func (bs *ThingBTreeSortedSet) DifferenceWith(other *ThingBTreeSortedSet) {
+	*bs = *bs.Difference(other)
+}
+
+// Cardinality returns how many items are currently in the set.
+func (bs *ThingBTreeSortedSet) Cardinality() int {
+	return bs.length
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (bs *ThingBTreeSortedSet) Len() int {
+	return bs.length
+}
+
+// A cursor over the items of a ThingBTreeSortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next, Prev or Seek has returned true.
+type ThingBTreeSortedSetIterator struct {
+	bs *ThingBTreeSortedSet
+	// the path from the root to the item at the cursor: the top frame
+	// holds the item's index, the ones below the child descended into
+	stack   []btreeSortedSetThingFrame
+	started bool // with an empty stack, whether the cursor is past the last item rather than before the first
+}
+
+type btreeSortedSetThingFrame struct {
+	n *btreeSortedSetThingNode
+	i int
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (bs *ThingBTreeSortedSet) Iterator() *ThingBTreeSortedSetIterator {
+	return &ThingBTreeSortedSetIterator{bs: bs}
+}
+
+// ReverseIterator returns a cursor positioned after the last item of the
+// set, to be walked from largest to smallest with Prev.
+func (bs *ThingBTreeSortedSet) ReverseIterator() *ThingBTreeSortedSetIterator {
+	return &ThingBTreeSortedSetIterator{bs: bs, started: true}
+}
+
+// pushFirst extends the path down to the least item under n.
+func (it *ThingBTreeSortedSetIterator) pushFirst(n *btreeSortedSetThingNode) {
+	for {
+		it.stack = append(it.stack, btreeSortedSetThingFrame{n, 0})
+		if n.leaf() {
+			return
+		}
+		n = n.children[0]
+	}
+}
+
+// pushLast extends the path down to the greatest item under n.
+func (it *ThingBTreeSortedSetIterator) pushLast(n *btreeSortedSetThingNode) {
+	for !n.leaf() {
+		it.stack = append(it.stack, btreeSortedSetThingFrame{n, len(n.children) - 1})
+		n = n.children[len(n.children)-1]
+	}
+	it.stack = append(it.stack, btreeSortedSetThingFrame{n, len(n.items) - 1})
+}
+
+// up climbs from a leaf whose items have all been passed to the nearest
+// item after it, returning false if there is none.
+func (it *ThingBTreeSortedSetIterator) up() bool {
+	for {
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			return false
+		}
+		// the child descended into comes just before the item of the same index
+		if top := it.stack[len(it.stack)-1]; top.i < len(top.n.items) {
+			return true
+		}
+	}
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *ThingBTreeSortedSetIterator) Next() bool {
+	if len(it.stack) == 0 {
+		if it.started || it.bs.root == nil {
+			it.started = true
+			return false
+		}
+		it.started = true
+		it.pushFirst(it.bs.root)
+		return true
+	}
+	top := &it.stack[len(it.stack)-1]
+	top.i++
+	if !top.n.leaf() {
+		it.pushFirst(top.n.children[top.i])
+		return true
+	}
+	if top.i < len(top.n.items) {
+		return true
+	}
+	return it.up()
+}
+
+// Prev moves the cursor to the previous item, returning false once it has
+// moved before the first one.
+func (it *ThingBTreeSortedSetIterator) Prev() bool {
+	if len(it.stack) == 0 {
+		if !it.started || it.bs.root == nil {
+			it.started = false
+			return false
+		}
+		it.pushLast(it.bs.root)
+		return true
+	}
+	top := &it.stack[len(it.stack)-1]
+	if !top.n.leaf() {
+		it.pushLast(top.n.children[top.i])
+		return true
+	}
+	top.i--
+	if top.i >= 0 {
+		return true
+	}
+	for {
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			it.started = false
+			return false
+		}
+		// the item before the child descended into has the index before it
+		if top := &it.stack[len(it.stack)-1]; top.i > 0 {
+			top.i--
+			return true
+		}
+	}
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *ThingBTreeSortedSetIterator) Seek(v Thing) bool {
+	it.stack = it.stack[:0]
+	it.started = true
+	n := it.bs.root
+	for n != nil {
+		i, found := it.bs.find(n, v)
+		it.stack = append(it.stack, btreeSortedSetThingFrame{n, i})
+		if found {
+			return true
+		}
+		if n.leaf() {
+			if i < len(n.items) {
+				return true
+			}
+			// every item in the leaf is less than v
+			return it.up()
+		}
+		n = n.children[i]
+	}
+	return false
+}
+
+// Value returns the item at the cursor.
+func (it *ThingBTreeSortedSetIterator) Value() Thing {
+	top := it.stack[len(it.stack)-1]
+	return top.n.items[top.i]
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (bs *ThingBTreeSortedSet) Each(f func(v Thing) bool) {
+	if bs.root != nil {
+		bs.root.each(f)
+	}
+}
+
+func (n *btreeSortedSetThingNode) each(f func(v Thing) bool) bool {
+	for i, v := range n.items {
+		if !n.leaf() && !n.children[i].each(f) {
+			return false
+		}
+		if !f(v) {
+			return false
+		}
+	}
+	return n.leaf() || n.children[len(n.items)].each(f)
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+func (bs *ThingBTreeSortedSet) ReverseEach(f func(v Thing) bool) {
+	if bs.root != nil {
+		bs.root.reverseEach(f)
+	}
+}
+
+func (n *btreeSortedSetThingNode) reverseEach(f func(v Thing) bool) bool {
+	for i := len(n.items) - 1; i >= 0; i-- {
+		if !n.leaf() && !n.children[i+1].reverseEach(f) {
+			return false
+		}
+		if !f(n.items[i]) {
+			return false
+		}
+	}
+	return n.leaf() || n.children[0].reverseEach(f)
+}
+
+// First returns the least item in the set.
+// ok is false if the set is empty.
+func (bs *ThingBTreeSortedSet) First() (val Thing, ok bool) {
+	n := bs.root
+	if n == nil {
+		return
+	}
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return n.items[0], true
+}
+
+// PopFirst removes and returns the least item in the set.
+// ok is false if the set is empty.
+func (bs *ThingBTreeSortedSet) PopFirst() (val Thing, ok bool) {
+	if bs.root == nil {
+		return
+	}
+	val = bs.removeFirst(bs.root)
+	bs.shrink()
+	bs.length--
+	return val, true
+}
+
+// PopN removes and returns the k least items in the set in ascending
+// order, or every item if there are fewer than k.
+func (bs *ThingBTreeSortedSet) PopN(k int) []Thing {
+	var popped []Thing
+	for len(popped) < k {
+		v, ok := bs.PopFirst()
+		if !ok {
+			break
+		}
+		popped = append(popped, v)
+	}
+	return popped
+}
+
+// Last returns the greatest item in the set.
+// ok is false if the set is empty.
+func (bs *ThingBTreeSortedSet) Last() (val Thing, ok bool) {
+	n := bs.root
+	if n == nil {
+		return
+	}
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	return n.items[len(n.items)-1], true
+}
+
+// PopLast removes and returns the greatest item in the set.
+// ok is false if the set is empty.
+func (bs *ThingBTreeSortedSet) PopLast() (val Thing, ok bool) {
+	if bs.root == nil {
+		return
+	}
+	val = bs.removeLast(bs.root)
+	bs.shrink()
+	bs.length--
+	return val, true
+}
+
+// PopMax removes and returns the greatest item in the set, like PopLast.
+func (bs *ThingBTreeSortedSet) PopMax() (val Thing, ok bool) {
+	return bs.PopLast()
+}
+
+// Iter() returns a channel of type Thing that you can range over,
+// like ThingSortedSet's, so code ranging over a set keeps working when
+// the tag is switched. The set must not be changed until the channel is
+// drained, and breaking out of the range loop leaks the goroutine feeding
+// the channel, so prefer Iterator or Each.
+func (bs *ThingBTreeSortedSet) Iter() <-chan Thing {
+	ch := make(chan Thing)
+	go func() {
+		bs.Each(func(v Thing) bool {
+			ch <- v
+			return true
+		})
+		close(ch)
+	}()
+
+	return ch
+}
+
+// Equal determines if two sets are equal to each other.
+// If they both are the same size and have the same items they are considered equal.
+func (bs *ThingBTreeSortedSet) Equal(other *ThingBTreeSortedSet) bool {
+	return bs.length == other.length && bs.IsSubset(other)
+}
+
+// Returns a clone of the set, in O(n).
+// Does NOT clone the underlying elements.
+func (bs *ThingBTreeSortedSet) Clone() *ThingBTreeSortedSet {
+	items := make([]Thing, 0, bs.length)
+	bs.Each(func(v Thing) bool {
+		items = append(items, v)
+		return true
+	})
+	return bs.fromSorted(items)
+}
+
+// A sorted list of Thing kept as a list of short sorted
+// slices, after Grant Jenks' Python sortedcontainers. Items sit next to
+// each other in memory, so for small value types it is quicker than the
+// pointer-based containers and much smaller.
+// Equal items are all kept, in the order they were added.
+type ThingSortedSliceList struct {
+	less   func(a, b Thing) bool
+	load   int
+	length int
+	lists  [][]Thing // sorted, non-empty, and each no longer than twice load
+	maxes  []Thing   // the last item of each list
+	// a Fenwick tree of the lengths of the lists, to find items by
+	// position; nil when it has to be rebuilt, as lists are split or merged
+	index []int
+}
+
+// Creates and returns a reference to an empty list, whose slices hold
+// about 1000 items each.
+func NewThingSortedSliceList(less func(Thing, Thing) bool) *ThingSortedSliceList {
+	return NewThingSortedSliceListWithLoad(less, 1000)
+}
+
+// Creates and returns a reference to an empty list whose slices hold
+// between load/2 and 2*load items each. A larger load makes adding and
+// removing slower, but the list smaller and quicker to walk.
+func NewThingSortedSliceListWithLoad(less func(Thing, Thing) bool, load int) *ThingSortedSliceList {
+	if load < 1 {
+		panic("ThingSortedSliceList: load must be at least 1")
+	}
+	return &ThingSortedSliceList{less: less, load: load}
+}
+
+// bisectLeft returns the index of the first item in s not less than v.
+func (sl *ThingSortedSliceList) bisectLeft(s []Thing, v Thing) int {
+	return sort.Search(len(s), func(i int) bool {
+		return !sl.less(s[i], v)
+	})
+}
+
+// bisectRight returns the index of the first item in s greater than v.
+func (sl *ThingSortedSliceList) bisectRight(s []Thing, v Thing) int {
+	return sort.Search(len(s), func(i int) bool {
+		return sl.less(v, s[i])
+	})
+}
+
+// Adds an item to the list, after any equal items already in it.
+func (sl *ThingSortedSliceList) Add(v Thing) {
+	sl.length++
+	if len(sl.lists) == 0 {
+		sl.lists = append(sl.lists, []Thing{v})
+		sl.maxes = append(sl.maxes, v)
+		sl.index = nil
+		return
+	}
+	k := sl.bisectRight(sl.maxes, v)
+	if k == len(sl.lists) {
+		// v goes after everything
+		k--
+		sl.lists[k] = append(sl.lists[k], v)
+		sl.maxes[k] = v
+	} else {
+		s := sl.lists[k]
+		j := sl.bisectRight(s, v)
+		s = append(s, v)
+		copy(s[j+1:], s[j:])
+		s[j] = v
+		sl.lists[k] = s
+	}
+	sl.expand(k)
+}
+
+// expand splits list k in two if it has grown too long.
+func (sl *ThingSortedSliceList) expand(k int) {
+	s := sl.lists[k]
+	if len(s) <= 2*sl.load {
+		sl.updateIndex(k, 1)
+		return
+	}
+	half := make([]Thing, len(s)-sl.load, 2*sl.load+1)
+	copy(half, s[sl.load:])
+	var zero Thing
+	for i := sl.load; i < len(s); i++ {
+		s[i] = zero
+	}
+	sl.lists[k] = s[:sl.load]
+	sl.maxes[k] = s[sl.load-1]
+
+	sl.lists = append(sl.lists, nil)
+	copy(sl.lists[k+2:], sl.lists[k+1:])
+	sl.lists[k+1] = half
+	sl.maxes = append(sl.maxes, zero)
+	copy(sl.maxes[k+2:], sl.maxes[k+1:])
+	sl.maxes[k+1] = half[len(half)-1]
+	sl.index = nil
+}
+
+// delete removes item j of list k, merging the list into a neighbour if
+// it has become too short.
+func (sl *ThingSortedSliceList) delete(k, j int) Thing {
+	s := sl.lists[k]
+	v := s[j]
+	copy(s[j:], s[j+1:])
+	var zero Thing
+	s[len(s)-1] = zero
+	s = s[:len(s)-1]
+	sl.lists[k] = s
+	sl.length--
+
+	switch {
+	case len(s) > sl.load/2:
+		sl.maxes[k] = s[len(s)-1]
+		sl.updateIndex(k, -1)
+	case len(sl.lists) > 1:
+		// merge with the list before, or after if this is the first
+		if k == 0 {
+			k = 1
+		}
+		merged := append(sl.lists[k-1], sl.lists[k]...)
+		sl.lists[k-1] = merged
+		sl.maxes[k-1] = merged[len(merged)-1]
+		copy(sl.lists[k:], sl.lists[k+1:])
+		sl.lists[len(sl.lists)-1] = nil
+		sl.lists = sl.lists[:len(sl.lists)-1]
+		copy(sl.maxes[k:], sl.maxes[k+1:])
+		sl.maxes[len(sl.maxes)-1] = zero
+		sl.maxes = sl.maxes[:len(sl.maxes)-1]
+		sl.index = nil
+		// the merged list may be too long now; expand counts it as grown
+		// by one, but the index is rebuilt anyway
+		sl.expand(k - 1)
+	case len(s) > 0:
+		sl.maxes[k] = s[len(s)-1]
+		sl.updateIndex(k, -1)
+	default:
+		// that was the last item
+		sl.lists = sl.lists[:0]
+		sl.maxes = sl.maxes[:0]
+		sl.index = nil
+	}
+	return v
+}
+
+// updateIndex adds delta to the length of list k in the index, if it is built.
+func (sl *ThingSortedSliceList) updateIndex(k, delta int) {
+	if sl.index == nil {
+		return
+	}
+	for i := k + 1; i < len(sl.index); i += i & -i {
+		sl.index[i] += delta
+	}
+}
+
+// buildIndex builds the index, if it isn't already, in O(number of lists).
+func (sl *ThingSortedSliceList) buildIndex() {
+	if sl.index != nil {
+		return
+	}
+	sl.index = make([]int, len(sl.lists)+1)
+	for k, s := range sl.lists {
+		i := k + 1
+		sl.index[i] += len(s)
+		if parent := i + i&-i; parent < len(sl.index) {
+			sl.index[parent] += sl.index[i]
+		}
+	}
+}
+
+// position returns how many items come before list k.
+func (sl *ThingSortedSliceList) position(k int) int {
+	if k == 0 {
+		return 0
+	}
+	sl.buildIndex()
+	n := 0
+	for i := k; i > 0; i -= i & -i {
+		n += sl.index[i]
+	}
+	return n
+}
+
+// locate returns the list holding the item at index i, and its index there.
+func (sl *ThingSortedSliceList) locate(i int) (k, j int) {
+	if i < len(sl.lists[0]) {
+		return 0, i
+	}
+	sl.buildIndex()
+	step := 1
+	for step*2 <= len(sl.lists) {
+		step *= 2
+	}
+	// find the last list with fewer than i items before it
+	for ; step > 0; step /= 2 {
+		if next := k + step; next <= len(sl.lists) && sl.index[next] <= i {
+			k = next
+			i -= sl.index[next]
+		}
+	}
+	return k, i
+}
+
+// Discard removes the earliest added copy of an item.
+// Returns true if there was a copy to remove.
+func (sl *ThingSortedSliceList) Discard(v Thing) bool {
+	k := sl.bisectLeft(sl.maxes, v)
+	if k == len(sl.lists) {
+		return false
+	}
+	j := sl.bisectLeft(sl.lists[k], v)
+	if sl.less(v, sl.lists[k][j]) {
+		return false
+	}
+	sl.delete(k, j)
+	return true
+}
+
+// RemoveAt removes and returns the item at index i.
+// Panics if i is out of range.
+func (sl *ThingSortedSliceList) RemoveAt(i int) Thing {
+	if i < 0 || i >= sl.length {
+		panic("ThingSortedSliceList: index out of range")
+	}
+	k, j := sl.locate(i)
+	return sl.delete(k, j)
+}
+
+// At returns the item at index i, where index 0 is the smallest item.
+// Panics if i is out of range.
+func (sl *ThingSortedSliceList) At(i int) Thing {
+	if i < 0 || i >= sl.length {
+		panic("ThingSortedSliceList: index out of range")
+	}
+	k, j := sl.locate(i)
+	return sl.lists[k][j]
+}
+
+// BisectLeft returns the index where v would be added before any equal
+// items, which is how many items are less than v.
+func (sl *ThingSortedSliceList) BisectLeft(v Thing) int {
+	k := sl.bisectLeft(sl.maxes, v)
+	if k == len(sl.lists) {
+		return sl.length
+	}
+	return sl.position(k) + sl.bisectLeft(sl.lists[k], v)
+}
+
+// BisectRight returns the index where v would be added after any equal
+// items, which is how many items are less than or equal to v.
+func (sl *ThingSortedSliceList) BisectRight(v Thing) int {
+	k := sl.bisectRight(sl.maxes, v)
+	if k == len(sl.lists) {
+		return sl.length
+	}
+	return sl.position(k) + sl.bisectRight(sl.lists[k], v)
+}
+
+// Determines if a given item is in the list.
+func (sl *ThingSortedSliceList) Contains(v Thing) bool {
+	k := sl.bisectLeft(sl.maxes, v)
+	if k == len(sl.lists) {
+		return false
+	}
+	j := sl.bisectLeft(sl.lists[k], v)
+	return !sl.less(v, sl.lists[k][j])
+}
+
+// Count returns how many copies of v are in the list.
+func (sl *ThingSortedSliceList) Count(v Thing) int {
+	return sl.BisectRight(v) - sl.BisectLeft(v)
+}
+
+// Len returns how many items are in the list.
+func (sl *ThingSortedSliceList) Len() int {
+	return sl.length
+}
+
+// Clears the entire list.
+func (sl *ThingSortedSliceList) Clear() {
+	*sl = *NewThingSortedSliceListWithLoad(sl.less, sl.load)
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (sl *ThingSortedSliceList) Each(f func(v Thing) bool) {
+	for _, s := range sl.lists {
+		for _, v := range s {
+			if !f(v) {
+				return
+			}
+		}
+	}
+}
+
+// ToSlice returns the items in the list in ascending order.
+func (sl *ThingSortedSliceList) ToSlice() []Thing {
+	s := make([]Thing, 0, sl.length)
+	for _, l := range sl.lists {
+		s = append(s, l...)
+	}
+	return s
+}
+
+// A cursor over the items of a ThingSortedSliceList in ascending
+// order, up to an optional bound. It starts out positioned before its
+// first item; Value is only valid after Next has returned true.
+// Changing the list invalidates it.
+type ThingSortedSliceListIterator struct {
+	sl                 *ThingSortedSliceList
+	k, j               int // where the next item is
+	v                  Thing
+	hi                 Thing
+	hasHi, hiInclusive bool
+}
+
+// Iterator returns a cursor positioned before the first item of the list.
+func (sl *ThingSortedSliceList) Iterator() *ThingSortedSliceListIterator {
+	return &ThingSortedSliceListIterator{sl: sl}
+}
+
+// IRange returns a cursor over the items between lo and hi, each bound
+// being included or excluded as requested.
+func (sl *ThingSortedSliceList) IRange(lo, hi Thing, loInclusive, hiInclusive bool) *ThingSortedSliceListIterator {
+	it := &ThingSortedSliceListIterator{sl: sl, hi: hi, hasHi: true, hiInclusive: hiInclusive}
+	if loInclusive {
+		it.k = sl.bisectLeft(sl.maxes, lo)
+		if it.k < len(sl.lists) {
+			it.j = sl.bisectLeft(sl.lists[it.k], lo)
+		}
+	} else {
+		it.k = sl.bisectRight(sl.maxes, lo)
+		if it.k < len(sl.lists) {
+			it.j = sl.bisectRight(sl.lists[it.k], lo)
+		}
+	}
+	return it
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *ThingSortedSliceListIterator) Next() bool {
+	if it.k >= len(it.sl.lists) {
+		return false
+	}
+	v := it.sl.lists[it.k][it.j]
+	if it.hasHi && (it.sl.less(it.hi, v) || !it.hiInclusive && !it.sl.less(v, it.hi)) {
+		it.k = len(it.sl.lists)
+		return false
+	}
+	it.v = v
+	it.j++
+	if it.j == len(it.sl.lists[it.k]) {
+		it.k, it.j = it.k+1, 0
+	}
+	return true
+}
+
+// Value returns the item at the cursor.
+func (it *ThingSortedSliceListIterator) Value() Thing {
+	return it.v
+}
+
+// A sorted set backed by an AVL tree. It has the methods of
+// ThingBTreeSortedSet, but Add, Remove and Contains are O(log n) in the
+// worst case, where the skiplist's bounds only hold on average and its
+// levels can be predicted from the seed.
+// Changing a set invalidates its iterators, and f must not change the set
+// while Each or ReverseEach is running.
+type ThingTreeSortedSet struct {
+	less    func(a, b Thing) bool
+	compare func(a, b Thing) int
+	root    *treeSortedSetThingNode // nil when the set is empty
+	length  int
+}
+
+// the struct to hold nodes of the tree; the heights of a node's subtrees
+// differ by at most one
+type treeSortedSetThingNode struct {
+	value       Thing
+	left, right *treeSortedSetThingNode
+	height      int // of the subtree under the node, 1 for a leaf
+}
+
+// Creates and returns a reference to an empty set.
+func NewThingTreeSortedSet(less func(Thing, Thing) bool) *ThingTreeSortedSet {
+	compare := func(a, b Thing) int {
+		if less(a, b) {
+			return -1
+		}
+		if less(b, a) {
+			return 1
+		}
+		return 0
+	}
+	return &ThingTreeSortedSet{less: less, compare: compare}
+}
+
+// Creates and returns a reference to an empty set ordered by a three-way
+// comparison, as NewThingSortedSetWithCompare.
+func NewThingTreeSortedSetWithCompare(compare func(Thing, Thing) int) *ThingTreeSortedSet {
+	less := func(a, b Thing) bool {
+		return compare(a, b) < 0
+	}
+	return &ThingTreeSortedSet{less: less, compare: compare}
+}
+
+// Creates and returns a reference to an empty set ordered by the < operator.
+func NewThingTreeSortedSetNatural() *ThingTreeSortedSet {
+	return NewThingTreeSortedSetWithCompare(func(a, b Thing) int {
+		switch {
+		case a < b:
+			return -1
+		case b < a:
+			return 1
+		}
+		return 0
+	})
+}
+
+// Creates and returns a reference to a set from an existing slice.
+func NewThingTreeSortedSetFromSlice(less func(Thing, Thing) bool, s []Thing) *ThingTreeSortedSet {
+	ts := NewThingTreeSortedSet(less)
+	for _, v := range s {
+		ts.Add(v)
+	}
+	return ts
+}
+
+// Creates and returns a reference to a set from a slice already sorted by
+// less, in O(n). Runs of equal items are only added once.
+func NewThingTreeSortedSetFromSortedSlice(less func(Thing, Thing) bool, s []Thing) *ThingTreeSortedSet {
+	distinct := make([]Thing, 0, len(s))
+	for i, v := range s {
+		if i > 0 && !less(s[i-1], v) {
+			// equal to the item before it
+			continue
+		}
+		distinct = append(distinct, v)
+	}
+	return NewThingTreeSortedSet(less).fromSorted(distinct)
+}
+
+// fromSorted returns a new set ordered the same way as this one, holding
+// the given items, which must be sorted and distinct.
+func (ts *ThingTreeSortedSet) fromSorted(items []Thing) *ThingTreeSortedSet {
+	return &ThingTreeSortedSet{less: ts.less, compare: ts.compare, root: buildTreeSortedSetThingNode(items), length: len(items)}
+}
+
+// buildTreeSortedSetThingNode returns a subtree holding items, split
+// evenly about the middle one all the way down.
+func buildTreeSortedSetThingNode(items []Thing) *treeSortedSetThingNode {
+	if len(items) == 0 {
+		return nil
+	}
+	m := len(items) / 2
+	n := &treeSortedSetThingNode{value: items[m]}
+	n.left = buildTreeSortedSetThingNode(items[:m])
+	n.right = buildTreeSortedSetThingNode(items[m+1:])
+	n.update()
+	return n
+}
+
+// h returns the height of the subtree under n, 0 if n is nil.
+func (n *treeSortedSetThingNode) h() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+// update sets n's height from its children's.
+func (n *treeSortedSetThingNode) update() {
+	n.height = n.left.h()
+	if r := n.right.h(); r > n.height {
+		n.height = r
+	}
+	n.height++
+}
+
+func (n *treeSortedSetThingNode) rotateLeft() *treeSortedSetThingNode {
+	r := n.right
+	n.right = r.left
+	r.left = n
+	n.update()
+	r.update()
+	return r
+}
+
+func (n *treeSortedSetThingNode) rotateRight() *treeSortedSetThingNode {
+	l := n.left
+	n.left = l.right
+	l.right = n
+	n.update()
+	l.update()
+	return l
+}
+
+// rebalance updates n's height after one of its subtrees has grown or
+// shrunk by one, rotating if they now differ by two, and returns the node
+// now at the top of the subtree.
+func (n *treeSortedSetThingNode) rebalance() *treeSortedSetThingNode {
+	n.update()
+	switch b := n.left.h() - n.right.h(); {
+	case b > 1:
+		if n.left.left.h() < n.left.right.h() {
+			n.left = n.left.rotateLeft()
+		}
+		return n.rotateRight()
+	case b < -1:
+		if n.right.right.h() < n.right.left.h() {
+			n.right = n.right.rotateRight()
+		}
+		return n.rotateLeft()
+	}
+	return n
+}
+
+// insert adds v to the subtree under n, returning the subtree's new top
+// and whether v was added.
+func (ts *ThingTreeSortedSet) insert(n *treeSortedSetThingNode, v Thing) (*treeSortedSetThingNode, bool) {
+	if n == nil {
+		return &treeSortedSetThingNode{value: v, height: 1}, true
+	}
+	var added bool
+	switch c := ts.compare(v, n.value); {
+	case c < 0:
+		n.left, added = ts.insert(n.left, v)
+	case c > 0:
+		n.right, added = ts.insert(n.right, v)
+	}
+	if !added {
+		return n, false
+	}
+	return n.rebalance(), true
+}
+
+// Adds an item to the set if it doesn't already exist in the set.
+func (ts *ThingTreeSortedSet) Add(v Thing) bool {
+	var added bool
+	ts.root, added = ts.insert(ts.root, v)
+	if added {
+		ts.length++
+	}
+	return added
+}
+
+// AddAll adds every given item not already in the set, returning how many were added.
+func (ts *ThingTreeSortedSet) AddAll(items ...Thing) int {
+	added := 0
+	for _, v := range items {
+		if ts.Add(v) {
+			added++
+		}
+	}
+	return added
+}
+
+// remove removes v from the subtree under n, returning the subtree's new
+// top and whether v was removed.
+func (ts *ThingTreeSortedSet) remove(n *treeSortedSetThingNode, v Thing) (*treeSortedSetThingNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+	var removed bool
+	switch c := ts.compare(v, n.value); {
+	case c < 0:
+		n.left, removed = ts.remove(n.left, v)
+	case c > 0:
+		n.right, removed = ts.remove(n.right, v)
+	default:
+		if n.left == nil {
+			return n.right, true
+		}
+		if n.right == nil {
+			return n.left, true
+		}
+		// put the least item after v in its place
+		var next *treeSortedSetThingNode
+		n.right, next = n.right.removeFirst()
+		next.left, next.right = n.left, n.right
+		return next.rebalance(), true
+	}
+	if !removed {
+		return n, false
+	}
+	return n.rebalance(), true
+}
+
+// removeFirst takes the least node out of the subtree under n, returning
+// the subtree's new top and the node.
+func (n *treeSortedSetThingNode) removeFirst() (top, first *treeSortedSetThingNode) {
+	if n.left == nil {
+		return n.right, n
+	}
+	n.left, first = n.left.removeFirst()
+	return n.rebalance(), first
+}
+
+// removeLast takes the greatest node out of the subtree under n, as removeFirst.
+func (n *treeSortedSetThingNode) removeLast() (top, last *treeSortedSetThingNode) {
+	if n.right == nil {
+		return n.left, n
+	}
+	n.right, last = n.right.removeLast()
+	return n.rebalance(), last
+}
+
+// Allows the removal of a single item in the set.
+// Returns true if the item was in the set.
+func (ts *ThingTreeSortedSet) Remove(v Thing) bool {
+	var removed bool
+	ts.root, removed = ts.remove(ts.root, v)
+	if removed {
+		ts.length--
+	}
+	return removed
+}
+
+// RemoveAll removes every given item that is in the set, returning how many were removed.
+func (ts *ThingTreeSortedSet) RemoveAll(items ...Thing) int {
+	removed := 0
+	for _, v := range items {
+		if ts.Remove(v) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// RetainAll removes every item that is not in the other set, returning how
+// many were removed.
+func (ts *ThingTreeSortedSet) RetainAll(other *ThingTreeSortedSet) int {
+	before := ts.length
+	ts.IntersectWith(other)
+	return before - ts.length
+}
+
+// RemoveIf removes every item for which pred returns true, returning how
+// many were removed. The set is rebuilt in a single pass.
+func (ts *ThingTreeSortedSet) RemoveIf(pred func(v Thing) bool) int {
+	before := ts.length
+	var kept []Thing
+	ts.Each(func(v Thing) bool {
+		if !pred(v) {
+			kept = append(kept, v)
+		}
+		return true
+	})
+	*ts = *ts.fromSorted(kept)
+	return before - ts.length
+}
+
+// Clears the entire set to be the empty set.
+func (ts *ThingTreeSortedSet) Clear() {
+	ts.root = nil
+	ts.length = 0
+}
+
+// Determines if a given item is already in the set.
+func (ts *ThingTreeSortedSet) Contains(v Thing) bool {
+	n := ts.root
+	for n != nil {
+		switch c := ts.compare(v, n.value); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Determines if the given items are all in the set
+func (ts *ThingTreeSortedSet) ContainsAll(i ...Thing) bool {
+	for _, v := range i {
+		if !ts.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// floor returns the greatest item in the set less than or equal to v, or
+// if strict, less than v.
+func (ts *ThingTreeSortedSet) floor(v Thing, strict bool) (val Thing, ok bool) {
+	for n := ts.root; n != nil; {
+		c := ts.compare(n.value, v)
+		if c == 0 && !strict {
+			return n.value, true
+		}
+		if c < 0 {
+			// a candidate, but there may be a greater one to the right
+			val, ok = n.value, true
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return
+}
+
+// ceiling returns the least item in the set greater than or equal to v, or
+// if strict, greater than v.
+func (ts *ThingTreeSortedSet) ceiling(v Thing, strict bool) (val Thing, ok bool) {
+	for n := ts.root; n != nil; {
+		c := ts.compare(n.value, v)
+		if c == 0 && !strict {
+			return n.value, true
+		}
+		if c > 0 {
+			// a candidate, but there may be a lesser one to the left
+			val, ok = n.value, true
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return
+}
+
+// Floor returns the greatest item in the set less than or equal to v.
+// ok is false if there is no such item.
+func (ts *ThingTreeSortedSet) Floor(v Thing) (val Thing, ok bool) {
+	return ts.floor(v, false)
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+// ok is false if there is no such item.
+func (ts *ThingTreeSortedSet) Ceiling(v Thing) (val Thing, ok bool) {
+	return ts.ceiling(v, false)
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+// ok is false if there is no such item.
+func (ts *ThingTreeSortedSet) Lower(v Thing) (val Thing, ok bool) {
+	return ts.floor(v, true)
+}
+
+// Higher returns the least item in the set strictly greater than v.
+// ok is false if there is no such item.
+func (ts *ThingTreeSortedSet) Higher(v Thing) (val Thing, ok bool) {
+	return ts.ceiling(v, true)
+}
+
+// A read-only view of the items of a ThingTreeSortedSet between two
+// bounds. The view is evaluated lazily, seeking to its lower bound each time
+// it is iterated, so it reflects changes made to the set after it was created.
+type ThingTreeSortedSetView struct {
+	ts                       *ThingTreeSortedSet
+	lo, hi                   Thing
+	hasLo, hasHi             bool
+	loInclusive, hiInclusive bool
+}
+
+// Range returns a view of the items between lo and hi, each bound being
+// included or excluded as requested.
+func (ts *ThingTreeSortedSet) Range(lo, hi Thing, loInclusive, hiInclusive bool) ThingTreeSortedSetView {
+	return ThingTreeSortedSetView{ts: ts, lo: lo, hi: hi, hasLo: true, hasHi: true, loInclusive: loInclusive, hiInclusive: hiInclusive}
+}
+
+// HeadSet returns a view of the items strictly less than hi.
+func (ts *ThingTreeSortedSet) HeadSet(hi Thing) ThingTreeSortedSetView {
+	return ThingTreeSortedSetView{ts: ts, hi: hi, hasHi: true}
+}
+
+// TailSet returns a view of the items greater than or equal to lo.
+func (ts *ThingTreeSortedSet) TailSet(lo Thing) ThingTreeSortedSetView {
+	return ThingTreeSortedSetView{ts: ts, lo: lo, hasLo: true, loInclusive: true}
+}
+
+// Each calls f for every item in the view in ascending order, stopping
+// early if f returns false.
+func (sv ThingTreeSortedSetView) Each(f func(v Thing) bool) {
+	it := sv.ts.Iterator()
+	var ok bool
+	if !sv.hasLo {
+		ok = it.Next()
+	} else if ok = it.Seek(sv.lo); ok && !sv.loInclusive && !sv.ts.less(sv.lo, it.Value()) {
+		ok = it.Next()
+	}
+	for ; ok; ok = it.Next() {
+		v := it.Value()
+		if sv.hasHi && (sv.ts.less(sv.hi, v) || !sv.hiInclusive && !sv.ts.less(v, sv.hi)) {
+			return
+		}
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// Cardinality returns how many items are currently in the view.
+// Unlike the set's, this walks every item in the view.
+func (sv ThingTreeSortedSetView) Cardinality() int {
+	n := 0
+	sv.Each(func(v Thing) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// ToSlice returns the items in the view in ascending order.
+func (sv ThingTreeSortedSetView) ToSlice() []Thing {
+	var s []Thing
+	sv.Each(func(v Thing) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// ToSet copies the items in the view into a new set.
+func (sv ThingTreeSortedSetView) ToSet() *ThingTreeSortedSet {
+	return sv.ts.fromSorted(sv.ToSlice())
+}
+
+// merge walks both sets side by side in O(n+m), calling f with every item
+// in either set and whether it is in each, until f returns false.
+func (ts *ThingTreeSortedSet) merge(other *ThingTreeSortedSet, f func(v Thing, inThis, inOther bool) bool) {
+	it, ot := ts.Iterator(), other.Iterator()
+	ok, ook := it.Next(), ot.Next()
+	for ok || ook {
+		var c int
+		switch {
+		case !ook:
+			c = -1
+		case !ok:
+			c = 1
+		default:
+			c = ts.compare(it.Value(), ot.Value())
+		}
+		switch {
+		case c < 0:
+			if !f(it.Value(), true, false) {
+				return
+			}
+			ok = it.Next()
+		case c > 0:
+			if !f(ot.Value(), false, true) {
+				return
+			}
+			ook = ot.Next()
+		default:
+			if !f(it.Value(), true, true) {
+				return
+			}
+			ok, ook = it.Next(), ot.Next()
+		}
+	}
+}
+
+// mergeInto returns a new set of the items merge finds for which keep
+// returns true.
+func (ts *ThingTreeSortedSet) mergeInto(other *ThingTreeSortedSet, keep func(inThis, inOther bool) bool) *ThingTreeSortedSet {
+	var items []Thing
+	ts.merge(other, func(v Thing, inThis, inOther bool) bool {
+		if keep(inThis, inOther) {
+			items = append(items, v)
+		}
+		return true
+	})
+	return ts.fromSorted(items)
+}
+
+// IsSubset determines if every item in this set is in the other set.
+func (ts *ThingTreeSortedSet) IsSubset(other *ThingTreeSortedSet) bool {
+	if ts.length > other.length {
+		return false
+	}
+	subset := true
+	ts.merge(other, func(v Thing, inThis, inOther bool) bool {
+		subset = !inThis || inOther
+		return subset
+	})
+	return subset
+}
+
+// IsSuperset determines if every item in the other set is in this set.
+func (ts *ThingTreeSortedSet) IsSuperset(other *ThingTreeSortedSet) bool {
+	return other.IsSubset(ts)
+}
+
+// IsProperSubset determines if this set is a subset of the other set and
+// the other set has at least one item this one does not.
+func (ts *ThingTreeSortedSet) IsProperSubset(other *ThingTreeSortedSet) bool {
+	return ts.length < other.length && ts.IsSubset(other)
+}
+
+// IsProperSuperset determines if this set is a superset of the other set
+// and has at least one item the other set does not.
+func (ts *ThingTreeSortedSet) IsProperSuperset(other *ThingTreeSortedSet) bool {
+	return other.IsProperSubset(ts)
+}
+
+// IsDisjoint determines if the two sets have no items in common.
+// An empty set is disjoint with every set, itself included.
+func (ts *ThingTreeSortedSet) IsDisjoint(other *ThingTreeSortedSet) bool {
+	disjoint := true
+	ts.merge(other, func(v Thing, inThis, inOther bool) bool {
+		disjoint = !inThis || !inOther
+		return disjoint
+	})
+	return disjoint
+}
+
+// Overlaps determines if the two sets have at least one item in common.
+func (ts *ThingTreeSortedSet) Overlaps(other *ThingTreeSortedSet) bool {
+	return !ts.IsDisjoint(other)
+}
+
+// Returns a new set with all items in both sets, in O(n+m).
+func (ts *ThingTreeSortedSet) Union(other *ThingTreeSortedSet) *ThingTreeSortedSet {
+	return ts.mergeInto(other, func(inThis, inOther bool) bool {
+		return true
+	})
+}
+
+// Returns a new set with items that exist only in both sets, in O(n+m).
+func (ts *ThingTreeSortedSet) Intersect(other *ThingTreeSortedSet) *ThingTreeSortedSet {
+	return ts.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis && inOther
+	})
+}
+
+// Returns a new set with items in the current set but not in the other set, in O(n+m).
+func (ts *ThingTreeSortedSet) Difference(other *ThingTreeSortedSet) *ThingTreeSortedSet {
+	return ts.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis && !inOther
+	})
+}
+
+// Returns a new set with items in the current set or the other set but not in both, in O(n+m).
+func (ts *ThingTreeSortedSet) SymmetricDifference(other *ThingTreeSortedSet) *ThingTreeSortedSet {
+	return ts.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis != inOther
+	})
+}
+
+// UnionWith adds every item of the other set to this one.
+func (ts *ThingTreeSortedSet) UnionWith(other *ThingTreeSortedSet) {
+	*ts = *ts.Union(other)
+}
+
+// IntersectWith removes every item that is not also in the other set.
+func (ts *ThingTreeSortedSet) IntersectWith(other *ThingTreeSortedSet) {
+	*ts = *ts.Intersect(other)
+}
+
+// DifferenceWith removes every item that is also in the other set.
+func (ts *ThingTreeSortedSet) DifferenceWith(other *ThingTreeSortedSet) {
+	*ts = *ts.Difference(other)
+}
+
+// Cardinality returns how many items are currently in the set.
+func (ts *ThingTreeSortedSet) Cardinality() int {
+	return ts.length
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (ts *ThingTreeSortedSet) Len() int {
+	return ts.length
+}
+
+// A cursor over the items of a ThingTreeSortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next, Prev or Seek has returned true.
+type ThingTreeSortedSetIterator struct {
+	ts      *ThingTreeSortedSet
+	stack   []*treeSortedSetThingNode // the path from the root to the node at the cursor
+	started bool                      // with an empty stack, whether the cursor is past the last item rather than before the first
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (ts *ThingTreeSortedSet) Iterator() *ThingTreeSortedSetIterator {
+	return &ThingTreeSortedSetIterator{ts: ts}
+}
+
+// ReverseIterator returns a cursor positioned after the last item of the
+// set, to be walked from largest to smallest with Prev.
+func (ts *ThingTreeSortedSet) ReverseIterator() *ThingTreeSortedSetIterator {
+	return &ThingTreeSortedSetIterator{ts: ts, started: true}
+}
+
+// pushFirst extends the path down to the least item under n.
+func (it *ThingTreeSortedSetIterator) pushFirst(n *treeSortedSetThingNode) {
+	for ; n != nil; n = n.left {
+		it.stack = append(it.stack, n)
+	}
+}
+
+// pushLast extends the path down to the greatest item under n.
+func (it *ThingTreeSortedSetIterator) pushLast(n *treeSortedSetThingNode) {
+	for ; n != nil; n = n.right {
+		it.stack = append(it.stack, n)
+	}
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *ThingTreeSortedSetIterator) Next() bool {
+	if len(it.stack) == 0 {
+		if it.started || it.ts.root == nil {
+			it.started = true
+			return false
+		}
+		it.started = true
+		it.pushFirst(it.ts.root)
+		return true
+	}
+	if n := it.stack[len(it.stack)-1]; n.right != nil {
+		it.pushFirst(n.right)
+		return true
+	}
+	// climb to the nearest node whose left subtree the cursor was in
+	for {
+		child := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			return false
+		}
+		if it.stack[len(it.stack)-1].left == child {
+			return true
+		}
+	}
+}
+
+// Prev moves the cursor to the previous item, returning false once it has
+// moved before the first one.
+func (it *ThingTreeSortedSetIterator) Prev() bool {
+	if len(it.stack) == 0 {
+		if !it.started || it.ts.root == nil {
+			it.started = false
+			return false
+		}
+		it.pushLast(it.ts.root)
+		return true
+	}
+	if n := it.stack[len(it.stack)-1]; n.left != nil {
+		it.pushLast(n.left)
+		return true
+	}
+	// climb to the nearest node whose right subtree the cursor was in
+	for {
+		child := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			it.started = false
+			return false
+		}
+		if it.stack[len(it.stack)-1].right == child {
+			return true
+		}
+	}
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *ThingTreeSortedSetIterator) Seek(v Thing) bool {
+	it.stack = it.stack[:0]
+	it.started = true
+	// the length of the path down to the least item seen greater than v
+	ceiling := 0
+	for n := it.ts.root; n != nil; {
+		it.stack = append(it.stack, n)
+		switch c := it.ts.compare(v, n.value); {
+		case c < 0:
+			ceiling = len(it.stack)
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return true
+		}
+	}
+	it.stack = it.stack[:ceiling]
+	return ceiling > 0
+}
+
+// Value returns the item at the cursor.
+func (it *ThingTreeSortedSetIterator) Value() Thing {
+	return it.stack[len(it.stack)-1].value
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (ts *ThingTreeSortedSet) Each(f func(v Thing) bool) {
+	ts.root.each(f)
+}
+
+func (n *treeSortedSetThingNode) each(f func(v Thing) bool) bool {
+	for ; n != nil; n = n.right {
+		if !n.left.each(f) || !f(n.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+func (ts *ThingTreeSortedSet) ReverseEach(f func(v Thing) bool) {
+	ts.root.reverseEach(f)
+}
+
+func (n *treeSortedSetThingNode) reverseEach(f func(v Thing) bool) bool {
+	for ; n != nil; n = n.left {
+		if !n.right.reverseEach(f) || !f(n.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// First returns the least item in the set.
+// ok is false if the set is empty.
+func (ts *ThingTreeSortedSet) First() (val Thing, ok bool) {
+	n := ts.root
+	if n == nil {
+		return
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return n.value, true
+}
+
+// PopFirst removes and returns the least item in the set.
+// ok is false if the set is empty.
+func (ts *ThingTreeSortedSet) PopFirst() (val Thing, ok bool) {
+	if ts.root == nil {
+		return
+	}
+	var first *treeSortedSetThingNode
+	ts.root, first = ts.root.removeFirst()
+	ts.length--
+	return first.value, true
+}
+
+// PopN removes and returns the k least items in the set in ascending
+// order, or every item if there are fewer than k.
+func (ts *ThingTreeSortedSet) PopN(k int) []Thing {
+	var popped []Thing
+	for len(popped) < k {
+		v, ok := ts.PopFirst()
+		if !ok {
+			break
+		}
+		popped = append(popped, v)
+	}
+	return popped
+}
+
+// Last returns the greatest item in the set.
+// ok is false if the set is empty.
+func (ts *ThingTreeSortedSet) Last() (val Thing, ok bool) {
+	n := ts.root
+	if n == nil {
+		return
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return n.value, true
+}
+
+// PopLast removes and returns the greatest item in the set.
+// ok is false if the set is empty.
+func (ts *ThingTreeSortedSet) PopLast() (val Thing, ok bool) {
+	if ts.root == nil {
+		return
+	}
+	var last *treeSortedSetThingNode
+	ts.root, last = ts.root.removeLast()
+	ts.length--
+	return last.value, true
+}
+
+// PopMax removes and returns the greatest item in the set, like PopLast.
+func (ts *ThingTreeSortedSet) PopMax() (val Thing, ok bool) {
+	return ts.PopLast()
+}
+
+// Iter() returns a channel of type Thing that you can range over,
+// like ThingSortedSet's. The set must not be changed until the channel
+// is drained, and breaking out of the range loop leaks the goroutine
+// feeding the channel, so prefer Iterator or Each.
+func (ts *ThingTreeSortedSet) Iter() <-chan Thing {
+	ch := make(chan Thing)
+	go func() {
+		ts.Each(func(v Thing) bool {
+			ch <- v
+			return true
+		})
+		close(ch)
+	}()
+
+	return ch
+}
+
+// Equal determines if two sets are equal to each other.
+// If they both are the same size and have the same items they are considered equal.
+func (ts *ThingTreeSortedSet) Equal(other *ThingTreeSortedSet) bool {
+	return ts.length == other.length && ts.IsSubset(other)
+}
+
+// Returns a clone of the set, in O(n).
+// Does NOT clone the underlying elements.
+func (ts *ThingTreeSortedSet) Clone() *ThingTreeSortedSet {
+	return &ThingTreeSortedSet{less: ts.less, compare: ts.compare, root: ts.root.clone(), length: ts.length}
+}
+
+func (n *treeSortedSetThingNode) clone() *treeSortedSetThingNode {
+	if n == nil {
+		return nil
+	}
+	c := *n
+	c.left, c.right = n.left.clone(), n.right.clone()
+	return &c
 }
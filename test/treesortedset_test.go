@@ -0,0 +1,231 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func makeTreeSortedSet(ints []int) *ThingTreeSortedSet {
+	set := NewThingTreeSortedSetNatural()
+	for _, i := range ints {
+		set.Add(Thing(i))
+	}
+	return set
+}
+
+// checkTreeSortedSet checks the set's items both ways round, and that every
+// node's height is right and its subtrees' heights differ by at most one.
+func checkTreeSortedSet(t *testing.T, name string, s *ThingTreeSortedSet, expected ...Thing) {
+	got := []Thing{}
+	s.Each(func(v Thing) bool {
+		got = append(got, v)
+		return true
+	})
+	var reversed []Thing
+	s.ReverseEach(func(v Thing) bool {
+		reversed = append(reversed, v)
+		return true
+	})
+	ok := len(got) == len(expected) && len(reversed) == len(expected) && s.Len() == len(expected)
+	for i := 0; ok && i < len(expected); i++ {
+		ok = got[i] == expected[i] && reversed[len(expected)-1-i] == expected[i] && s.Contains(expected[i])
+	}
+	if !ok {
+		t.Errorf("%s should be %v, got %v (reversed %v, Len %d)", name, expected, got, reversed, s.Len())
+	}
+
+	var walk func(n *treeSortedSetThingNode) int
+	walk = func(n *treeSortedSetThingNode) int {
+		if n == nil {
+			return 0
+		}
+		l, r := walk(n.left), walk(n.right)
+		if l-r > 1 || r-l > 1 {
+			t.Errorf("%s has a node at %v with subtrees of heights %d and %d", name, n.value, l, r)
+		}
+		h := l + 1
+		if r >= l {
+			h = r + 1
+		}
+		if n.height != h {
+			t.Errorf("%s has a node at %v of height %d recorded as %d", name, n.value, h, n.height)
+		}
+		return h
+	}
+	walk(s.root)
+}
+
+func Test_TreeSortedSetAddRemove(t *testing.T) {
+	a := makeTreeSortedSet(nil)
+	checkTreeSortedSet(t, "empty", a)
+
+	r := rand.New(rand.NewSource(5))
+	present := map[int]bool{}
+	for i := 0; i < 5000; i++ {
+		v := r.Intn(500)
+		if r.Intn(3) == 0 {
+			if a.Remove(Thing(v)) != present[v] {
+				t.Fatalf("Remove(%d) should return %v", v, present[v])
+			}
+			delete(present, v)
+		} else {
+			if a.Add(Thing(v)) == present[v] {
+				t.Fatalf("Add(%d) should return %v", v, !present[v])
+			}
+			present[v] = true
+		}
+		if i%500 == 0 {
+			var expected []Thing
+			for v := range present {
+				expected = append(expected, Thing(v))
+			}
+			sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+			checkTreeSortedSet(t, "during random changes", a, expected...)
+		}
+	}
+}
+
+func Test_TreeSortedSetWorstCaseHeight(t *testing.T) {
+	// ascending items are the worst case for an unbalanced tree; an AVL
+	// tree of n items is never taller than about 1.44 log2(n+2)
+	a := makeTreeSortedSet(nil)
+	for i := 0; i < 1<<14; i++ {
+		a.Add(Thing(i))
+	}
+	if limit := int(1.4405*math.Log2(float64(a.Len()+2)) - 0.3277); a.root.height > limit {
+		t.Errorf("a tree of %d items should be at most %d high, is %d", a.Len(), limit, a.root.height)
+	}
+	for i := 0; i < 1<<14; i += 2 {
+		a.Remove(Thing(i))
+	}
+	if limit := int(1.4405*math.Log2(float64(a.Len()+2)) - 0.3277); a.root.height > limit {
+		t.Errorf("a tree of %d items should be at most %d high, is %d", a.Len(), limit, a.root.height)
+	}
+	var expected []Thing
+	for i := 1; i < 1<<14; i += 2 {
+		expected = append(expected, Thing(i))
+	}
+	checkTreeSortedSet(t, "after removing the even items", a, expected...)
+}
+
+func Test_TreeSortedSetFromSortedSlice(t *testing.T) {
+	for n := 0; n < 100; n++ {
+		var s, expected []Thing
+		for i := 0; i < n; i++ {
+			// every item twice
+			s = append(s, Thing(i), Thing(i))
+			expected = append(expected, Thing(i))
+		}
+		a := NewThingTreeSortedSetFromSortedSlice(func(a, b Thing) bool { return a < b }, s)
+		checkTreeSortedSet(t, "FromSortedSlice", a, expected...)
+	}
+
+	a := NewThingTreeSortedSetFromSlice(func(a, b Thing) bool { return a < b }, []Thing{5, 1, 3, 1})
+	checkTreeSortedSet(t, "FromSlice", a, 1, 3, 5)
+}
+
+func Test_TreeSortedSetSeek(t *testing.T) {
+	var ints []int
+	for i := 0; i < 200; i += 2 {
+		ints = append(ints, i)
+	}
+	a := makeTreeSortedSet(ints)
+	it := a.Iterator()
+
+	for v := -1; v < 199; v++ {
+		want := v + v%2
+		if v < 0 {
+			want = 0
+		}
+		if !it.Seek(Thing(v)) || it.Value() != Thing(want) {
+			t.Fatalf("Seek(%d) should move to %d", v, want)
+		}
+		// and walk on both ways from there
+		if want > 0 && (!it.Prev() || it.Value() != Thing(want-2) || !it.Next()) {
+			t.Fatalf("Prev after Seek(%d) should move to %d", v, want-2)
+		}
+		if want < 198 && (!it.Next() || it.Value() != Thing(want+2)) {
+			t.Fatalf("Next after Seek(%d) should move to %d", v, want+2)
+		}
+	}
+	if it.Seek(199) {
+		t.Error("Seek past the last item should return false")
+	}
+	if !it.Prev() || it.Value() != 198 {
+		t.Error("Prev after seeking past the end should move to the last item")
+	}
+}
+
+func Test_TreeSortedSetAlgebra(t *testing.T) {
+	a := makeTreeSortedSet([]int{1, 3, 5, 7, 9, 11})
+	b := makeTreeSortedSet([]int{0, 3, 4, 9, 12})
+	empty := makeTreeSortedSet(nil)
+
+	checkTreeSortedSet(t, "a.Union(b)", a.Union(b), 0, 1, 3, 4, 5, 7, 9, 11, 12)
+	checkTreeSortedSet(t, "a.Intersect(b)", a.Intersect(b), 3, 9)
+	checkTreeSortedSet(t, "a.Difference(b)", a.Difference(b), 1, 5, 7, 11)
+	checkTreeSortedSet(t, "a.SymmetricDifference(b)", a.SymmetricDifference(b), 0, 1, 4, 5, 7, 11, 12)
+	checkTreeSortedSet(t, "a.Union(empty)", a.Union(empty), 1, 3, 5, 7, 9, 11)
+	checkTreeSortedSet(t, "empty.Intersect(a)", empty.Intersect(a))
+
+	c := a.Clone()
+	c.UnionWith(b)
+	checkTreeSortedSet(t, "UnionWith", c, 0, 1, 3, 4, 5, 7, 9, 11, 12)
+	c.DifferenceWith(b)
+	checkTreeSortedSet(t, "DifferenceWith", c, 1, 5, 7, 11)
+	c.IntersectWith(a)
+	checkTreeSortedSet(t, "IntersectWith", c, 1, 5, 7, 11)
+	checkTreeSortedSet(t, "a is unchanged", a, 1, 3, 5, 7, 9, 11)
+
+	if !c.IsSubset(a) || !c.IsProperSubset(a) || !a.IsSuperset(c) || !a.IsProperSuperset(c) {
+		t.Error("c should be a proper subset of a")
+	}
+	if a.IsSubset(c) || !a.IsSubset(a) || a.IsProperSubset(a) {
+		t.Error("a should only be a subset of itself")
+	}
+	if a.IsDisjoint(b) || !a.Overlaps(b) || !c.IsDisjoint(b) || !empty.IsDisjoint(empty) {
+		t.Error("a and b overlap, c and b don't")
+	}
+	if !a.Equal(a.Clone()) || a.Equal(c) || !empty.Equal(makeTreeSortedSet(nil)) {
+		t.Error("only sets with the same items are equal")
+	}
+
+	if n := a.RetainAll(b); n != 4 {
+		t.Errorf("RetainAll should remove 4 items, removed %d", n)
+	}
+	checkTreeSortedSet(t, "after RetainAll", a, 3, 9)
+}
+
+func Test_TreeSortedSetViews(t *testing.T) {
+	a := makeTreeSortedSet([]int{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	check := func(name string, v ThingTreeSortedSetView, expected ...Thing) {
+		if v.Cardinality() != len(expected) {
+			t.Errorf("%s should have %d items, has %d", name, len(expected), v.Cardinality())
+		}
+		checkTreeSortedSet(t, name, v.ToSet(), expected...)
+	}
+	check("Range[3,6]", a.Range(3, 6, true, true), 3, 4, 5, 6)
+	check("Range(3,6)", a.Range(3, 6, false, false), 4, 5)
+	check("HeadSet(4)", a.HeadSet(4), 1, 2, 3)
+	check("TailSet(7)", a.TailSet(7), 7, 8, 9)
+	check("Range(9,10)", a.Range(9, 10, false, true))
+}
+
+func benchmarkTreeSortedSetAddRemove(b *testing.B, n int) {
+	a := NewThingTreeSortedSetNatural()
+	for i := 0; i < n; i++ {
+		a.Add(Thing(2 * i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := Thing(2*(i%n) + 1)
+		a.Add(v)
+		a.Remove(v)
+	}
+}
+
+func Benchmark_TreeSortedSetAddRemove1000(b *testing.B)   { benchmarkTreeSortedSetAddRemove(b, 1000) }
+func Benchmark_TreeSortedSetAddRemove100000(b *testing.B) { benchmarkTreeSortedSetAddRemove(b, 100000) }
@@ -0,0 +1,1263 @@
+// Generated by: setup
+// TypeWriter: sorted_container
+// Directive: +test on main.Version
+
+// SortedSet is a modification of https://github.com/wfreeman/go-skiplist/sortedset.go
+// The MIT License (MIT)
+// Copyright (c) 2014 Wes Freeman (freeman.wes@gmail.com)
+
+package main
+
+import (
+	"math"
+	"math/bits"
+	"math/rand"
+	"sort"
+)
+
+// the height bookkeeping shared by the skiplists behind VersionSortedSet,
+// VersionSortedMap, VersionSortedMultiSet and VersionSortedList
+type skiplistVersionLevels struct {
+	level     int // how many levels have any elements on them
+	maxLevels int
+	p         float64
+	r         *rand.Rand
+}
+
+func newSkiplistVersionLevels() skiplistVersionLevels {
+	return skiplistVersionLevels{
+		maxLevels: 64,
+		p:         0.5,
+		r:         rand.New(rand.NewSource(123123)),
+	}
+}
+
+// randomLevels picks how many levels a new element is on, in a skiplist of
+// n items. Levels are capped a few above the log2(n) such a skiplist needs,
+// so an unlucky run can't make a tower that every search has to climb down.
+func (lv *skiplistVersionLevels) randomLevels(n int) int {
+	limit := bits.Len(uint(n)) + 4
+	if limit > lv.maxLevels {
+		limit = lv.maxLevels
+	}
+	level := int(math.Log(1.0-lv.r.Float64()) / math.Log(lv.p))
+	if level >= limit {
+		level = limit
+	}
+	if level == 0 {
+		level++
+	}
+	return level
+}
+
+// raise makes searches start high enough to see an element on the given
+// number of levels.
+func (lv *skiplistVersionLevels) raise(levels int) {
+	if levels > lv.level {
+		lv.level = levels
+	}
+}
+
+// The primary type that represents a sorted set
+// backed by a skiplist
+type VersionSortedSet struct {
+	less    func(a, b Version) bool
+	compare func(a, b Version) int
+	head    []*sortedSetVersionElement
+	tail    *sortedSetVersionElement
+	length  int
+	skiplistVersionLevels
+	options []VersionSortedSetOption   // kept to make sets ordered and tuned the same way
+	update  []*sortedSetVersionElement // scratch backPointer for Add and Remove
+	arena   *sortedSetVersionArena     // nil unless made with VersionSortedSetWithArena
+}
+
+// the struct to hold elements of the skiplist
+type sortedSetVersionElement struct {
+	val  Version
+	next []*sortedSetVersionElement
+	prev *sortedSetVersionElement // the previous element on level 0
+}
+
+// Creates and returns a reference to an empty set.
+func NewVersionSortedSet(less func(Version, Version) bool) *VersionSortedSet {
+	return NewVersionSortedSetWithOptions(less)
+}
+
+// Creates and returns a reference to an empty set ordered by a three-way
+// comparison, which returns a negative number when a is less than b, a
+// positive one when a is greater, and zero when they are equal.
+// Searching calls it once per element visited, where a less func has to
+// be called twice to tell equal items apart.
+func NewVersionSortedSetWithCompare(compare func(Version, Version) int) *VersionSortedSet {
+	less := func(a, b Version) bool {
+		return compare(a, b) < 0
+	}
+	return newVersionSortedSet(less, compare)
+}
+
+// Creates and returns a reference to an empty set ordered by
+// Version's Compare method.
+func NewVersionSortedSetNatural() *VersionSortedSet {
+	return NewVersionSortedSetWithCompare(func(a, b Version) int {
+		return a.Compare(b)
+	})
+}
+
+// A VersionSortedSetOption tunes the skiplist behind a set made by
+// NewVersionSortedSetWithOptions.
+type VersionSortedSetOption func(ss *VersionSortedSet)
+
+// VersionSortedSetWithMaxLevel caps how many levels an element can reach,
+// 64 by default. A skiplist with p = 1/2 stays fast up to about 2^maxLevel
+// items, and a lower cap saves memory in every search.
+func VersionSortedSetWithMaxLevel(maxLevel int) VersionSortedSetOption {
+	if maxLevel < 1 {
+		panic("VersionSortedSet: max level must be at least 1")
+	}
+	return func(ss *VersionSortedSet) {
+		ss.maxLevels = maxLevel
+	}
+}
+
+// VersionSortedSetWithP sets the chance that an element reaching one level
+// reaches the next, 1/2 by default. A lower p, e.g. 1/4, gives elements
+// fewer links, using less memory for slightly longer searches.
+func VersionSortedSetWithP(p float64) VersionSortedSetOption {
+	if !(p > 0 && p < 1) {
+		panic("VersionSortedSet: p must be between 0 and 1")
+	}
+	return func(ss *VersionSortedSet) {
+		ss.p = p
+	}
+}
+
+// VersionSortedSetWithSeed seeds the random levels, 123123 by default.
+// Sets with the same seed given the same items in the same order end up
+// with the same layout.
+func VersionSortedSetWithSeed(seed int64) VersionSortedSetOption {
+	return func(ss *VersionSortedSet) {
+		ss.r = rand.New(rand.NewSource(seed))
+	}
+}
+
+// VersionSortedSetWithRand draws the random levels from r, e.g. one seeded
+// from the clock for a layout that differs from run to run. r is shared by
+// every set made from this one, such as by Union or Clone, so none of them
+// can be added to from another goroutine without locking r as well. Making
+// those sets doesn't draw from r, as their levels are spread evenly.
+func VersionSortedSetWithRand(r *rand.Rand) VersionSortedSetOption {
+	return func(ss *VersionSortedSet) {
+		ss.r = r
+	}
+}
+
+// VersionSortedSetWithArena allocates elements chunkSize at a time, with
+// their links, rather than making two allocations for every item, and
+// reuses the elements of removed items for items added later. This eases
+// the load on the garbage collector for very large sets.
+// Since elements are reused, an Iterator must not be moved on from an
+// item once that item has been removed.
+func VersionSortedSetWithArena(chunkSize int) VersionSortedSetOption {
+	if chunkSize < 1 {
+		panic("VersionSortedSet: arena chunk size must be at least 1")
+	}
+	return func(ss *VersionSortedSet) {
+		ss.arena = &sortedSetVersionArena{chunkSize: chunkSize}
+	}
+}
+
+// Creates and returns a reference to an empty set ordered by less and
+// tuned by the given options.
+func NewVersionSortedSetWithOptions(less func(Version, Version) bool, options ...VersionSortedSetOption) *VersionSortedSet {
+	compare := func(a, b Version) int {
+		if less(a, b) {
+			return -1
+		}
+		if less(b, a) {
+			return 1
+		}
+		return 0
+	}
+	return newVersionSortedSet(less, compare, options...)
+}
+
+func newVersionSortedSet(less func(Version, Version) bool, compare func(Version, Version) int, options ...VersionSortedSetOption) *VersionSortedSet {
+	ss := &VersionSortedSet{
+		less:                  less,
+		compare:               compare,
+		skiplistVersionLevels: newSkiplistVersionLevels(),
+		options:               options,
+	}
+	for _, option := range options {
+		option(ss)
+	}
+	ss.head = make([]*sortedSetVersionElement, ss.maxLevels)
+	ss.update = make([]*sortedSetVersionElement, ss.maxLevels)
+	if ss.arena != nil {
+		ss.arena.free = make([]*sortedSetVersionElement, ss.maxLevels)
+	}
+	return ss
+}
+
+// empty returns a new, empty set ordered and tuned the same way as this one.
+func (ss *VersionSortedSet) empty() *VersionSortedSet {
+	return newVersionSortedSet(ss.less, ss.compare, ss.options...)
+}
+
+func newSortedSetVersionElement(v Version, levels int) *sortedSetVersionElement {
+	return &sortedSetVersionElement{val: v, next: make([]*sortedSetVersionElement, levels)}
+}
+
+// newElement returns an element for v on the given number of levels, from
+// the set's arena if it has one.
+func (ss *VersionSortedSet) newElement(v Version, levels int) *sortedSetVersionElement {
+	if ss.arena != nil {
+		return ss.arena.element(v, levels)
+	}
+	return newSortedSetVersionElement(v, levels)
+}
+
+// the chunks a set made with VersionSortedSetWithArena takes its elements
+// from; elements given back by recycle are kept on a free list for each
+// number of levels, chained through next[0]
+type sortedSetVersionArena struct {
+	chunkSize int
+	elems     []sortedSetVersionElement  // the unused part of the current chunk of elements
+	links     []*sortedSetVersionElement // the unused part of the current chunk of links
+	free      []*sortedSetVersionElement
+}
+
+func (a *sortedSetVersionArena) element(v Version, levels int) *sortedSetVersionElement {
+	if e := a.free[levels-1]; e != nil {
+		a.free[levels-1] = e.next[0]
+		e.next[0] = nil
+		e.val = v
+		return e
+	}
+	if len(a.elems) == 0 {
+		a.elems = make([]sortedSetVersionElement, a.chunkSize)
+	}
+	if len(a.links) < levels {
+		// elements are on two levels on average with p = 1/2
+		n := 2 * a.chunkSize
+		if n < levels {
+			n = levels
+		}
+		a.links = make([]*sortedSetVersionElement, n)
+	}
+	e := &a.elems[0]
+	a.elems = a.elems[1:]
+	e.val = v
+	e.next = a.links[:levels:levels]
+	a.links = a.links[levels:]
+	return e
+}
+
+// recycle puts e, which must no longer be linked into the set, on the free list.
+func (a *sortedSetVersionArena) recycle(e *sortedSetVersionElement) {
+	// drop what e refers to, so the garbage collector can have it
+	var zero Version
+	e.val = zero
+	e.prev = nil
+	for level := range e.next {
+		e.next[level] = nil
+	}
+	e.next[0] = a.free[len(e.next)-1]
+	a.free[len(e.next)-1] = e
+}
+
+// Creates and returns a reference to a set from an existing slice.
+// The slice is copied and sorted, then built up as by
+// NewVersionSortedSetFromSortedSlice; the first of any equal items is kept.
+func NewVersionSortedSetFromSlice(less func(Version, Version) bool, s []Version) *VersionSortedSet {
+	sorted := make([]Version, len(s))
+	copy(sorted, s)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return NewVersionSortedSetFromSortedSlice(less, sorted)
+}
+
+// Creates and returns a reference to a set from a slice already sorted by
+// less, in O(n). Runs of equal items are only added once.
+// Rather than being random, levels are spread evenly: every 2^k-th item
+// reaches level k, as in a perfectly balanced skiplist.
+func NewVersionSortedSetFromSortedSlice(less func(Version, Version) bool, s []Version) *VersionSortedSet {
+	a := NewVersionSortedSet(less)
+	ap := a.appender()
+	for i, v := range s {
+		if i > 0 && !less(s[i-1], v) {
+			// equal to the item before it
+			continue
+		}
+		ap.push(v)
+	}
+	ap.finish()
+	return a
+}
+
+// Adds an item to the current set if it doesn't already exist in the set.
+func (ss *VersionSortedSet) Add(v Version) bool {
+	backPointer := ss.updateVector()
+	for level := ss.level - 1; level >= 0; level-- {
+		var e *sortedSetVersionElement = nil
+		if level+1 == ss.level || backPointer[level+1] == nil {
+			e = ss.head[level]
+		} else {
+			e = backPointer[level+1]
+		}
+		for e != nil {
+			c := ss.compare(v, e.val)
+			// if they are equal, overwrite?
+			if c == 0 {
+				return false
+			}
+			// if inspected val is greater than k, go back and down a level
+			if c < 0 {
+				break
+			}
+			backPointer[level] = e
+			e = e.next[level]
+		}
+	}
+	ss.insert(v, backPointer)
+	return true
+}
+
+// updateVector returns the set's scratch backPointer, cleared, so that
+// Add and Remove don't allocate one each call. Only methods that change
+// the set may use it: methods that only read can be called from many
+// goroutines at once, e.g. under VersionConcurrentSortedSet's read lock.
+func (ss *VersionSortedSet) updateVector() []*sortedSetVersionElement {
+	for i := range ss.update {
+		ss.update[i] = nil
+	}
+	return ss.update
+}
+
+// insert creates an element for v and connects it up after backPointer,
+// which must hold the last element before v on every level.
+func (ss *VersionSortedSet) insert(v Version, backPointer []*sortedSetVersionElement) *sortedSetVersionElement {
+	// create new element
+	e := ss.newElement(v, ss.randomLevels(ss.length))
+
+	// connect new element up with backPointer
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			e.next[level] = ss.head[level]
+			ss.head[level] = e
+		} else {
+			e.next[level] = backPointer[level].next[level]
+			backPointer[level].next[level] = e
+		}
+	}
+	e.prev = backPointer[0]
+	if e.next[0] != nil {
+		e.next[0].prev = e
+	} else {
+		ss.tail = e
+	}
+	ss.raise(len(e.next))
+
+	ss.length++
+	return e
+}
+
+// unlink disconnects e, given backPointer holding the last element before
+// it on every level.
+func (ss *VersionSortedSet) unlink(e *sortedSetVersionElement, backPointer []*sortedSetVersionElement) {
+	for level := 0; level < len(e.next); level++ {
+		if backPointer[level] == nil {
+			ss.head[level] = e.next[level]
+		} else {
+			backPointer[level].next[level] = e.next[level]
+		}
+	}
+	if e.next[0] != nil {
+		e.next[0].prev = backPointer[0]
+	} else {
+		ss.tail = backPointer[0]
+	}
+
+	ss.length--
+	ss.lower()
+	if ss.arena != nil {
+		ss.arena.recycle(e)
+	}
+}
+
+// drop hands e, which a bulk removal has left out of the set, back to the
+// arena if the set has one.
+func (ss *VersionSortedSet) drop(e *sortedSetVersionElement) {
+	if ss.arena != nil {
+		ss.arena.recycle(e)
+	}
+}
+
+// dropFrom drops e and every element after it on level 0.
+func (ss *VersionSortedSet) dropFrom(e *sortedSetVersionElement) {
+	if ss.arena == nil {
+		return
+	}
+	for e != nil {
+		next := e.next[0]
+		ss.arena.recycle(e)
+		e = next
+	}
+}
+
+// lower drops the height to the highest level that still has elements.
+func (ss *VersionSortedSet) lower() {
+	for ss.level > 0 && ss.head[ss.level-1] == nil {
+		ss.level--
+	}
+}
+
+// seek fills backPointer with the last element before v on every level,
+// returning the element holding v, or nil if v is not in the set.
+// Whatever backPointer already holds is used as a finger to start each
+// level from, so it must be nil or elements before v; a run of seeks for
+// ascending items then walks each level only once.
+func (ss *VersionSortedSet) seek(v Version, backPointer []*sortedSetVersionElement) *sortedSetVersionElement {
+	var next *sortedSetVersionElement
+	for level := ss.level - 1; level >= 0; level-- {
+		// start from the finger or from where the level above stopped,
+		// whichever is further along
+		e := backPointer[level]
+		if level+1 < ss.level && backPointer[level+1] != nil {
+			if e == nil || ss.compare(e.val, backPointer[level+1].val) < 0 {
+				e = backPointer[level+1]
+			}
+		}
+		if e == nil {
+			next = ss.head[level]
+		} else {
+			next = e.next[level]
+		}
+		for next != nil && ss.compare(next.val, v) < 0 {
+			e = next
+			next = e.next[level]
+		}
+		backPointer[level] = e
+	}
+	if next != nil && ss.compare(v, next.val) == 0 {
+		return next
+	}
+	return nil
+}
+
+// sortedBatch returns a copy of items sorted by less, so that they can be
+// sought one after another with a finger.
+func (ss *VersionSortedSet) sortedBatch(items []Version) []Version {
+	sorted := make([]Version, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return ss.less(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// AddAll adds every given item not already in the set, returning how many
+// were added. The items are sorted first so the search for each one starts
+// where the last left off.
+func (ss *VersionSortedSet) AddAll(items ...Version) int {
+	var backPointer = make([]*sortedSetVersionElement, ss.maxLevels)
+	added := 0
+	for _, v := range ss.sortedBatch(items) {
+		if ss.seek(v, backPointer) != nil {
+			continue
+		}
+		ss.insert(v, backPointer)
+		added++
+	}
+	return added
+}
+
+// RemoveAll removes every given item that is in the set, returning how many
+// were removed. The items are sorted first so the search for each one starts
+// where the last left off.
+func (ss *VersionSortedSet) RemoveAll(items ...Version) int {
+	var backPointer = make([]*sortedSetVersionElement, ss.maxLevels)
+	removed := 0
+	for _, v := range ss.sortedBatch(items) {
+		if e := ss.seek(v, backPointer); e != nil {
+			ss.unlink(e, backPointer)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RetainAll removes every item that is not in the other set, returning how
+// many were removed.
+func (ss *VersionSortedSet) RetainAll(other *VersionSortedSet) int {
+	before := ss.length
+	ss.IntersectWith(other)
+	return before - ss.length
+}
+
+// RemoveIf removes every item for which pred returns true, returning how
+// many were removed. The set is relinked in a single pass.
+func (ss *VersionSortedSet) RemoveIf(pred func(v Version) bool) int {
+	before := ss.length
+	e := ss.head[0]
+	a := ss.appender()
+	for e != nil {
+		// recycling e clears its links, so step past it first
+		next := e.next[0]
+		if pred(e.val) {
+			ss.drop(e)
+		} else {
+			a.link(e)
+		}
+		e = next
+	}
+	a.finish()
+	return before - ss.length
+}
+
+// Determines if a given item is already in the set.
+func (ss *VersionSortedSet) Contains(v Version) bool {
+	// only the last element before v matters, so no backPointer is kept
+	var prev *sortedSetVersionElement
+	for level := ss.level - 1; level >= 0; level-- {
+		e := ss.head[level]
+		if prev != nil {
+			e = prev.next[level]
+		}
+		for ; e != nil; e = e.next[level] {
+			c := ss.compare(v, e.val)
+			// if they are equal, return val
+			if c == 0 {
+				return true
+			}
+			// if inspected val is greater than v, go back and down a level
+			if c < 0 {
+				break
+			}
+			prev = e
+		}
+	}
+	return false
+}
+
+// neighbors descends the skiplist the same way Add does, returning the last
+// element less than v and the first element not less than v. Either is nil
+// if there is no such element.
+func (ss *VersionSortedSet) neighbors(v Version) (lower, ceiling *sortedSetVersionElement) {
+	for level := ss.level - 1; level >= 0; level-- {
+		e := ss.head[level]
+		if lower != nil {
+			e = lower.next[level]
+		}
+		for ; e != nil; e = e.next[level] {
+			// if inspected val is not less than v, go back and down a level
+			if ss.compare(e.val, v) >= 0 {
+				break
+			}
+			lower = e
+		}
+	}
+	if lower == nil {
+		return nil, ss.head[0]
+	}
+	return lower, lower.next[0]
+}
+
+// Floor returns the greatest item in the set less than or equal to v.
+// ok is false if there is no such item.
+func (ss *VersionSortedSet) Floor(v Version) (val Version, ok bool) {
+	lower, ceiling := ss.neighbors(v)
+	if ceiling != nil && !ss.less(v, ceiling.val) {
+		return ceiling.val, true
+	}
+	if lower != nil {
+		return lower.val, true
+	}
+	return
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+// ok is false if there is no such item.
+func (ss *VersionSortedSet) Ceiling(v Version) (val Version, ok bool) {
+	_, ceiling := ss.neighbors(v)
+	if ceiling != nil {
+		return ceiling.val, true
+	}
+	return
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+// ok is false if there is no such item.
+func (ss *VersionSortedSet) Lower(v Version) (val Version, ok bool) {
+	lower, _ := ss.neighbors(v)
+	if lower != nil {
+		return lower.val, true
+	}
+	return
+}
+
+// Higher returns the least item in the set strictly greater than v.
+// ok is false if there is no such item.
+func (ss *VersionSortedSet) Higher(v Version) (val Version, ok bool) {
+	_, e := ss.neighbors(v)
+	if e != nil && !ss.less(v, e.val) {
+		e = e.next[0]
+	}
+	if e != nil {
+		return e.val, true
+	}
+	return
+}
+
+// A read-only view of the items of a VersionSortedSet between two bounds.
+// The view is evaluated lazily, seeking to its lower bound each time it
+// is iterated, so it reflects changes made to the set after it was created.
+type VersionSortedSetView struct {
+	ss                       *VersionSortedSet
+	lo, hi                   Version
+	hasLo, hasHi             bool
+	loInclusive, hiInclusive bool
+}
+
+// Range returns a view of the items between lo and hi, each bound being
+// included or excluded as requested.
+func (ss *VersionSortedSet) Range(lo, hi Version, loInclusive, hiInclusive bool) VersionSortedSetView {
+	return VersionSortedSetView{ss: ss, lo: lo, hi: hi, hasLo: true, hasHi: true, loInclusive: loInclusive, hiInclusive: hiInclusive}
+}
+
+// HeadSet returns a view of the items strictly less than hi.
+func (ss *VersionSortedSet) HeadSet(hi Version) VersionSortedSetView {
+	return VersionSortedSetView{ss: ss, hi: hi, hasHi: true}
+}
+
+// TailSet returns a view of the items greater than or equal to lo.
+func (ss *VersionSortedSet) TailSet(lo Version) VersionSortedSetView {
+	return VersionSortedSetView{ss: ss, lo: lo, hasLo: true, loInclusive: true}
+}
+
+// first returns the first element inside the lower bound of the view.
+func (sv VersionSortedSetView) first() *sortedSetVersionElement {
+	if !sv.hasLo {
+		return sv.ss.head[0]
+	}
+	_, e := sv.ss.neighbors(sv.lo)
+	if e != nil && !sv.loInclusive && !sv.ss.less(sv.lo, e.val) {
+		e = e.next[0]
+	}
+	return e
+}
+
+// inside determines if v is inside the upper bound of the view.
+func (sv VersionSortedSetView) inside(v Version) bool {
+	if !sv.hasHi {
+		return true
+	}
+	if sv.hiInclusive {
+		return !sv.ss.less(sv.hi, v)
+	}
+	return sv.ss.less(v, sv.hi)
+}
+
+// Each calls f for every item in the view in ascending order, stopping
+// early if f returns false.
+func (sv VersionSortedSetView) Each(f func(v Version) bool) {
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		if !f(e.val) {
+			return
+		}
+	}
+}
+
+// Cardinality returns how many items are currently in the view.
+// Unlike the set's, this walks every item in the view.
+func (sv VersionSortedSetView) Cardinality() int {
+	n := 0
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		n++
+	}
+	return n
+}
+
+// ToSlice returns the items in the view in ascending order.
+func (sv VersionSortedSetView) ToSlice() []Version {
+	var s []Version
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		s = append(s, e.val)
+	}
+	return s
+}
+
+// ToSet copies the items in the view into a new set.
+func (sv VersionSortedSetView) ToSet() *VersionSortedSet {
+	set := sv.ss.empty()
+	for e := sv.first(); e != nil && sv.inside(e.val); e = e.next[0] {
+		set.Add(e.val)
+	}
+	return set
+}
+
+// Determines if the given items are all in the set
+func (ss *VersionSortedSet) ContainsAll(i ...Version) bool {
+	for _, elem := range i {
+		if !ss.Contains(elem) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubset determines if every item in this set is in the other set.
+// An empty set is a subset of every set, and every set is a subset of itself.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *VersionSortedSet) IsSubset(other *VersionSortedSet) bool {
+	if ss.length > other.length {
+		return false
+	}
+	e, o := ss.head[0], other.head[0]
+	for e != nil {
+		if o == nil {
+			return false
+		}
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			// e.val was skipped over in the other set
+			return false
+		case c > 0:
+			o = o.next[0]
+		default:
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	return true
+}
+
+// IsSuperset determines if every item in the other set is in this set.
+func (ss *VersionSortedSet) IsSuperset(other *VersionSortedSet) bool {
+	return other.IsSubset(ss)
+}
+
+// IsProperSubset determines if this set is a subset of the other set and
+// the other set has at least one item this one does not.
+func (ss *VersionSortedSet) IsProperSubset(other *VersionSortedSet) bool {
+	return ss.length < other.length && ss.IsSubset(other)
+}
+
+// IsProperSuperset determines if the other set is a proper subset of this one.
+func (ss *VersionSortedSet) IsProperSuperset(other *VersionSortedSet) bool {
+	return other.IsProperSubset(ss)
+}
+
+// IsDisjoint determines if the two sets have no items in common.
+// An empty set is disjoint with every set, itself included.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *VersionSortedSet) IsDisjoint(other *VersionSortedSet) bool {
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Overlaps determines if the two sets have at least one item in common.
+func (ss *VersionSortedSet) Overlaps(other *VersionSortedSet) bool {
+	return !ss.IsDisjoint(other)
+}
+
+// sortedSetVersionAppender refills a set with elements given in ascending
+// order, linking each one after the last element appended on every level
+// instead of searching for its place.
+type sortedSetVersionAppender struct {
+	ss   *VersionSortedSet
+	ends []*sortedSetVersionElement
+}
+
+// appender empties the set, leaving its elements alone so they can be
+// linked back in, and returns an appender to refill it.
+func (ss *VersionSortedSet) appender() *sortedSetVersionAppender {
+	ss.tail = nil
+	ss.length = 0
+	ss.level = 0
+	return &sortedSetVersionAppender{ss, make([]*sortedSetVersionElement, ss.maxLevels)}
+}
+
+// push appends a new element holding v. Its levels are spread evenly
+// rather than drawn at random: with p = 1/2, every 2^k-th item appended
+// reaches level k. So Clone and the set algebra never touch the set's
+// rand, which sets copied under a read lock may share.
+func (a *sortedSetVersionAppender) push(v Version) {
+	step := int(1/a.ss.p + 0.5)
+	if step < 2 {
+		step = 2
+	}
+	n := a.ss.length + 1
+	levels := 1
+	for span := step; levels < a.ss.maxLevels && n%span == 0; span *= step {
+		levels++
+	}
+	a.link(a.ss.newElement(v, levels))
+}
+
+// link appends e, which must not be less than anything appended before it.
+func (a *sortedSetVersionAppender) link(e *sortedSetVersionElement) {
+	for level := 0; level < len(e.next); level++ {
+		if a.ends[level] == nil {
+			a.ss.head[level] = e
+		} else {
+			a.ends[level].next[level] = e
+		}
+		a.ends[level] = e
+	}
+	e.prev = a.ss.tail
+	a.ss.tail = e
+	a.ss.length++
+}
+
+// finish terminates every level after the last element appended to it.
+func (a *sortedSetVersionAppender) finish() {
+	for level, e := range a.ends {
+		if e == nil {
+			a.ss.head[level] = nil
+		} else {
+			e.next[level] = nil
+			a.ss.level = level + 1
+		}
+	}
+}
+
+// Returns a new set with all items in both sets.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *VersionSortedSet) Union(other *VersionSortedSet) *VersionSortedSet {
+	unionedSet := ss.empty()
+	a := unionedSet.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.push(e.val)
+			e = e.next[0]
+		case c > 0:
+			a.push(o.val)
+			o = o.next[0]
+		default:
+			a.push(e.val)
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	for ; o != nil; o = o.next[0] {
+		a.push(o.val)
+	}
+	a.finish()
+	return unionedSet
+}
+
+// Returns a new set with items that exist only in both sets.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *VersionSortedSet) Intersect(other *VersionSortedSet) *VersionSortedSet {
+	intersection := ss.empty()
+	a := intersection.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			a.push(e.val)
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	a.finish()
+	return intersection
+}
+
+// Returns a new set with items in the current set but not in the other set.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *VersionSortedSet) Difference(other *VersionSortedSet) *VersionSortedSet {
+	differencedSet := ss.empty()
+	a := differencedSet.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.push(e.val)
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	a.finish()
+	return differencedSet
+}
+
+// Returns a new set with items in the current set or the other set but not in both.
+// Both sets are walked once, side by side, in O(n+m).
+func (ss *VersionSortedSet) SymmetricDifference(other *VersionSortedSet) *VersionSortedSet {
+	symmetricDifference := ss.empty()
+	a := symmetricDifference.appender()
+	e, o := ss.head[0], other.head[0]
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.push(e.val)
+			e = e.next[0]
+		case c > 0:
+			a.push(o.val)
+			o = o.next[0]
+		default:
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	for ; o != nil; o = o.next[0] {
+		a.push(o.val)
+	}
+	a.finish()
+	return symmetricDifference
+}
+
+// UnionWith adds every item of the other set to this one, in O(n+m).
+// Elements already in this set are relinked rather than copied.
+func (ss *VersionSortedSet) UnionWith(other *VersionSortedSet) {
+	if other == ss {
+		return
+	}
+	e, o := ss.head[0], other.head[0]
+	a := ss.appender()
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.link(e)
+			e = e.next[0]
+		case c > 0:
+			a.push(o.val)
+			o = o.next[0]
+		default:
+			a.link(e)
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.link(e)
+	}
+	for ; o != nil; o = o.next[0] {
+		a.push(o.val)
+	}
+	a.finish()
+}
+
+// IntersectWith removes every item that is not also in the other set, in O(n+m).
+func (ss *VersionSortedSet) IntersectWith(other *VersionSortedSet) {
+	if other == ss {
+		return
+	}
+	e, o := ss.head[0], other.head[0]
+	a := ss.appender()
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			next := e.next[0]
+			ss.drop(e)
+			e = next
+		case c > 0:
+			o = o.next[0]
+		default:
+			a.link(e)
+			e, o = e.next[0], o.next[0]
+		}
+	}
+	ss.dropFrom(e)
+	a.finish()
+}
+
+// DifferenceWith removes every item that is also in the other set, in O(n+m).
+func (ss *VersionSortedSet) DifferenceWith(other *VersionSortedSet) {
+	if other == ss {
+		ss.Clear()
+		return
+	}
+	e, o := ss.head[0], other.head[0]
+	a := ss.appender()
+	for e != nil && o != nil {
+		switch c := ss.compare(e.val, o.val); {
+		case c < 0:
+			a.link(e)
+			e = e.next[0]
+		case c > 0:
+			o = o.next[0]
+		default:
+			next := e.next[0]
+			ss.drop(e)
+			e, o = next, o.next[0]
+		}
+	}
+	for ; e != nil; e = e.next[0] {
+		a.link(e)
+	}
+	a.finish()
+}
+
+// Clears the entire set to be the empty set.
+func (ss *VersionSortedSet) Clear() {
+	*ss = *ss.empty()
+}
+
+// Allows the removal of a single item in the set.
+// Returns true if the item was in the set.
+func (ss *VersionSortedSet) Remove(v Version) bool {
+	backPointer := ss.updateVector()
+	for level := ss.level - 1; level >= 0; level-- {
+		var e *sortedSetVersionElement = nil
+		if level+1 == ss.level || backPointer[level+1] == nil {
+			e = ss.head[level]
+		} else {
+			e = backPointer[level+1]
+		}
+		for e != nil {
+			c := ss.compare(v, e.val)
+			// if they are equal, remove
+			if level == 0 && c == 0 {
+				ss.unlink(e, backPointer)
+				return true
+			}
+			// if inspected val is greater than or equal to k, go back and down a level
+			if c <= 0 {
+				break
+			}
+			backPointer[level] = e
+			e = e.next[level]
+		}
+	}
+	return false
+}
+
+// Cardinality returns how many items are currently in the set.
+func (ss *VersionSortedSet) Cardinality() int {
+	return ss.length
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (ss *VersionSortedSet) Len() int {
+	return ss.length
+}
+
+// A cursor over the items of a VersionSortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next, Prev or Seek has returned true.
+type VersionSortedSetIterator struct {
+	ss      *VersionSortedSet
+	e       *sortedSetVersionElement
+	started bool // with a nil e, whether the cursor is past the last item rather than before the first
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (ss *VersionSortedSet) Iterator() *VersionSortedSetIterator {
+	return &VersionSortedSetIterator{ss: ss}
+}
+
+// ReverseIterator returns a cursor positioned after the last item of the
+// set, to be walked from largest to smallest with Prev.
+func (ss *VersionSortedSet) ReverseIterator() *VersionSortedSetIterator {
+	return &VersionSortedSetIterator{ss: ss, started: true}
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *VersionSortedSetIterator) Next() bool {
+	if !it.started {
+		it.e = it.ss.head[0]
+		it.started = true
+	} else if it.e != nil {
+		it.e = it.e.next[0]
+	}
+	return it.e != nil
+}
+
+// Prev moves the cursor to the previous item, returning false once it has
+// moved before the first one.
+func (it *VersionSortedSetIterator) Prev() bool {
+	if !it.started {
+		return false
+	}
+	if it.e == nil {
+		it.e = it.ss.last()
+	} else {
+		it.e = it.e.prev
+	}
+	if it.e == nil {
+		it.started = false
+	}
+	return it.e != nil
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *VersionSortedSetIterator) Seek(v Version) bool {
+	_, it.e = it.ss.neighbors(v)
+	it.started = true
+	return it.e != nil
+}
+
+// Value returns the item at the cursor.
+func (it *VersionSortedSetIterator) Value() Version {
+	return it.e.val
+}
+
+// last returns the element holding the greatest item, or nil if the set is empty.
+func (ss *VersionSortedSet) last() *sortedSetVersionElement {
+	return ss.tail
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (ss *VersionSortedSet) Each(f func(v Version) bool) {
+	for e := ss.head[0]; e != nil; e = e.next[0] {
+		if !f(e.val) {
+			return
+		}
+	}
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+func (ss *VersionSortedSet) ReverseEach(f func(v Version) bool) {
+	for e := ss.last(); e != nil; e = e.prev {
+		if !f(e.val) {
+			return
+		}
+	}
+}
+
+// First returns the least item in the set.
+// ok is false if the set is empty.
+func (ss *VersionSortedSet) First() (val Version, ok bool) {
+	if e := ss.head[0]; e != nil {
+		return e.val, true
+	}
+	return
+}
+
+// PopFirst removes and returns the least item in the set.
+// ok is false if the set is empty.
+func (ss *VersionSortedSet) PopFirst() (val Version, ok bool) {
+	e := ss.head[0]
+	if e == nil {
+		return
+	}
+	// the first element comes straight after the head on every level it is on
+	for level := 0; level < len(e.next); level++ {
+		ss.head[level] = e.next[level]
+	}
+	if e.next[0] != nil {
+		e.next[0].prev = nil
+	} else {
+		ss.tail = nil
+	}
+	ss.length--
+	ss.lower()
+	val = e.val
+	if ss.arena != nil {
+		ss.arena.recycle(e)
+	}
+	return val, true
+}
+
+// PopN removes and returns the k least items in the set in ascending
+// order, or every item if there are fewer than k.
+func (ss *VersionSortedSet) PopN(k int) []Version {
+	var popped []Version
+	for len(popped) < k {
+		v, ok := ss.PopFirst()
+		if !ok {
+			break
+		}
+		popped = append(popped, v)
+	}
+	return popped
+}
+
+// Last returns the greatest item in the set.
+// ok is false if the set is empty.
+func (ss *VersionSortedSet) Last() (val Version, ok bool) {
+	if e := ss.last(); e != nil {
+		return e.val, true
+	}
+	return
+}
+
+// PopLast removes and returns the greatest item in the set, like PopMax.
+// ok is false if the set is empty.
+func (ss *VersionSortedSet) PopLast() (val Version, ok bool) {
+	return ss.PopMax()
+}
+
+// PopMax removes and returns the greatest item in the set.
+// ok is false if the set is empty.
+func (ss *VersionSortedSet) PopMax() (val Version, ok bool) {
+	val, ok = ss.Last()
+	if ok {
+		ss.Remove(val)
+	}
+	return
+}
+
+// Iter() returns a channel of type Version that you can range over.
+// It is kept for compatibility: breaking out of the range loop leaks the
+// goroutine feeding the channel, so prefer Iterator or Each.
+func (ss *VersionSortedSet) Iter() <-chan Version {
+	ch := make(chan Version)
+	go func() {
+		ss.Each(func(v Version) bool {
+			ch <- v
+			return true
+		})
+		close(ch)
+	}()
+
+	return ch
+}
+
+// Equal determines if two sets are equal to each other.
+// If they both are the same size and have the same items they are considered equal.
+// Order of items is not relevent for sets to be equal.
+func (ss *VersionSortedSet) Equal(other *VersionSortedSet) bool {
+	if ss.Cardinality() != other.Cardinality() {
+		return false
+	}
+	// both sets are in order, so walk them side by side in O(n)
+	for e, o := ss.head[0], other.head[0]; e != nil; e, o = e.next[0], o.next[0] {
+		if ss.compare(e.val, o.val) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a clone of the set, in O(n).
+// Does NOT clone the underlying elements.
+func (ss *VersionSortedSet) Clone() *VersionSortedSet {
+	clonedSet := ss.empty()
+	a := clonedSet.appender()
+	for e := ss.head[0]; e != nil; e = e.next[0] {
+		a.push(e.val)
+	}
+	a.finish()
+	return clonedSet
+}
@@ -0,0 +1,883 @@
+package container
+
+import (
+	"github.com/clipperhouse/gen/typewriter"
+)
+
+var treeSortedSet = &typewriter.Template{
+	Text: `
+
+// A sorted set backed by an AVL tree. It has the methods of
+// {{.Name}}BTreeSortedSet, but Add, Remove and Contains are O(log n) in the
+// worst case, where the skiplist's bounds only hold on average and its
+// levels can be predicted from the seed.
+// Changing a set invalidates its iterators, and f must not change the set
+// while Each or ReverseEach is running.
+type {{.Name}}TreeSortedSet struct {
+	less    func(a, b {{.Pointer}}{{.Name}}) bool
+	compare func(a, b {{.Pointer}}{{.Name}}) int
+	root    *treeSortedSet{{.Name}}Node // nil when the set is empty
+	length  int
+}
+
+// the struct to hold nodes of the tree; the heights of a node's subtrees
+// differ by at most one
+type treeSortedSet{{.Name}}Node struct {
+	value       {{.Pointer}}{{.Name}}
+	left, right *treeSortedSet{{.Name}}Node
+	height      int // of the subtree under the node, 1 for a leaf
+}
+
+// Creates and returns a reference to an empty set.
+func New{{.Name}}TreeSortedSet(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool) *{{.Name}}TreeSortedSet {
+	compare := func(a, b {{.Pointer}}{{.Name}}) int {
+		if less(a, b) {
+			return -1
+		}
+		if less(b, a) {
+			return 1
+		}
+		return 0
+	}
+	return &{{.Name}}TreeSortedSet{less: less, compare: compare}
+}
+
+// Creates and returns a reference to an empty set ordered by a three-way
+// comparison, as New{{.Name}}SortedSetWithCompare.
+func New{{.Name}}TreeSortedSetWithCompare(compare func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) int) *{{.Name}}TreeSortedSet {
+	less := func(a, b {{.Pointer}}{{.Name}}) bool {
+		return compare(a, b) < 0
+	}
+	return &{{.Name}}TreeSortedSet{less: less, compare: compare}
+}
+{{if .Natural}}
+{{if eq .Natural "Compare"}}// Creates and returns a reference to an empty set ordered by
+// {{.Pointer}}{{.Name}}'s Compare method.
+func New{{.Name}}TreeSortedSetNatural() *{{.Name}}TreeSortedSet {
+	return New{{.Name}}TreeSortedSetWithCompare(func(a, b {{.Pointer}}{{.Name}}) int {
+		return a.Compare(b)
+	})
+}
+{{else if eq .Natural "Less"}}// Creates and returns a reference to an empty set ordered by
+// {{.Pointer}}{{.Name}}'s Less method.
+func New{{.Name}}TreeSortedSetNatural() *{{.Name}}TreeSortedSet {
+	return New{{.Name}}TreeSortedSet(func(a, b {{.Pointer}}{{.Name}}) bool {
+		return a.Less(b)
+	})
+}
+{{else}}// Creates and returns a reference to an empty set ordered by the < operator.
+func New{{.Name}}TreeSortedSetNatural() *{{.Name}}TreeSortedSet {
+	return New{{.Name}}TreeSortedSetWithCompare(func(a, b {{.Name}}) int {
+		switch {
+		case a < b:
+			return -1
+		case b < a:
+			return 1
+		}
+		return 0
+	})
+}
+{{end}}{{end}}
+// Creates and returns a reference to a set from an existing slice.
+func New{{.Name}}TreeSortedSetFromSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) *{{.Name}}TreeSortedSet {
+	ts := New{{.Name}}TreeSortedSet(less)
+	for _, v := range s {
+		ts.Add(v)
+	}
+	return ts
+}
+
+// Creates and returns a reference to a set from a slice already sorted by
+// less, in O(n). Runs of equal items are only added once.
+func New{{.Name}}TreeSortedSetFromSortedSlice(less func({{.Pointer}}{{.Name}}, {{.Pointer}}{{.Name}}) bool, s []{{.Pointer}}{{.Name}}) *{{.Name}}TreeSortedSet {
+	distinct := make([]{{.Pointer}}{{.Name}}, 0, len(s))
+	for i, v := range s {
+		if i > 0 && !less(s[i-1], v) {
+			// equal to the item before it
+			continue
+		}
+		distinct = append(distinct, v)
+	}
+	return New{{.Name}}TreeSortedSet(less).fromSorted(distinct)
+}
+
+// fromSorted returns a new set ordered the same way as this one, holding
+// the given items, which must be sorted and distinct.
+func (ts *{{.Name}}TreeSortedSet) fromSorted(items []{{.Pointer}}{{.Name}}) *{{.Name}}TreeSortedSet {
+	return &{{.Name}}TreeSortedSet{less: ts.less, compare: ts.compare, root: buildTreeSortedSet{{.Name}}Node(items), length: len(items)}
+}
+
+// buildTreeSortedSet{{.Name}}Node returns a subtree holding items, split
+// evenly about the middle one all the way down.
+func buildTreeSortedSet{{.Name}}Node(items []{{.Pointer}}{{.Name}}) *treeSortedSet{{.Name}}Node {
+	if len(items) == 0 {
+		return nil
+	}
+	m := len(items) / 2
+	n := &treeSortedSet{{.Name}}Node{value: items[m]}
+	n.left = buildTreeSortedSet{{.Name}}Node(items[:m])
+	n.right = buildTreeSortedSet{{.Name}}Node(items[m+1:])
+	n.update()
+	return n
+}
+
+// h returns the height of the subtree under n, 0 if n is nil.
+func (n *treeSortedSet{{.Name}}Node) h() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+// update sets n's height from its children's.
+func (n *treeSortedSet{{.Name}}Node) update() {
+	n.height = n.left.h()
+	if r := n.right.h(); r > n.height {
+		n.height = r
+	}
+	n.height++
+}
+
+func (n *treeSortedSet{{.Name}}Node) rotateLeft() *treeSortedSet{{.Name}}Node {
+	r := n.right
+	n.right = r.left
+	r.left = n
+	n.update()
+	r.update()
+	return r
+}
+
+func (n *treeSortedSet{{.Name}}Node) rotateRight() *treeSortedSet{{.Name}}Node {
+	l := n.left
+	n.left = l.right
+	l.right = n
+	n.update()
+	l.update()
+	return l
+}
+
+// rebalance updates n's height after one of its subtrees has grown or
+// shrunk by one, rotating if they now differ by two, and returns the node
+// now at the top of the subtree.
+func (n *treeSortedSet{{.Name}}Node) rebalance() *treeSortedSet{{.Name}}Node {
+	n.update()
+	switch b := n.left.h() - n.right.h(); {
+	case b > 1:
+		if n.left.left.h() < n.left.right.h() {
+			n.left = n.left.rotateLeft()
+		}
+		return n.rotateRight()
+	case b < -1:
+		if n.right.right.h() < n.right.left.h() {
+			n.right = n.right.rotateRight()
+		}
+		return n.rotateLeft()
+	}
+	return n
+}
+
+// insert adds v to the subtree under n, returning the subtree's new top
+// and whether v was added.
+func (ts *{{.Name}}TreeSortedSet) insert(n *treeSortedSet{{.Name}}Node, v {{.Pointer}}{{.Name}}) (*treeSortedSet{{.Name}}Node, bool) {
+	if n == nil {
+		return &treeSortedSet{{.Name}}Node{value: v, height: 1}, true
+	}
+	var added bool
+	switch c := ts.compare(v, n.value); {
+	case c < 0:
+		n.left, added = ts.insert(n.left, v)
+	case c > 0:
+		n.right, added = ts.insert(n.right, v)
+	}
+	if !added {
+		return n, false
+	}
+	return n.rebalance(), true
+}
+
+// Adds an item to the set if it doesn't already exist in the set.
+func (ts *{{.Name}}TreeSortedSet) Add(v {{.Pointer}}{{.Name}}) bool {
+	var added bool
+	ts.root, added = ts.insert(ts.root, v)
+	if added {
+		ts.length++
+	}
+	return added
+}
+
+// AddAll adds every given item not already in the set, returning how many were added.
+func (ts *{{.Name}}TreeSortedSet) AddAll(items ...{{.Pointer}}{{.Name}}) int {
+	added := 0
+	for _, v := range items {
+		if ts.Add(v) {
+			added++
+		}
+	}
+	return added
+}
+
+// remove removes v from the subtree under n, returning the subtree's new
+// top and whether v was removed.
+func (ts *{{.Name}}TreeSortedSet) remove(n *treeSortedSet{{.Name}}Node, v {{.Pointer}}{{.Name}}) (*treeSortedSet{{.Name}}Node, bool) {
+	if n == nil {
+		return nil, false
+	}
+	var removed bool
+	switch c := ts.compare(v, n.value); {
+	case c < 0:
+		n.left, removed = ts.remove(n.left, v)
+	case c > 0:
+		n.right, removed = ts.remove(n.right, v)
+	default:
+		if n.left == nil {
+			return n.right, true
+		}
+		if n.right == nil {
+			return n.left, true
+		}
+		// put the least item after v in its place
+		var next *treeSortedSet{{.Name}}Node
+		n.right, next = n.right.removeFirst()
+		next.left, next.right = n.left, n.right
+		return next.rebalance(), true
+	}
+	if !removed {
+		return n, false
+	}
+	return n.rebalance(), true
+}
+
+// removeFirst takes the least node out of the subtree under n, returning
+// the subtree's new top and the node.
+func (n *treeSortedSet{{.Name}}Node) removeFirst() (top, first *treeSortedSet{{.Name}}Node) {
+	if n.left == nil {
+		return n.right, n
+	}
+	n.left, first = n.left.removeFirst()
+	return n.rebalance(), first
+}
+
+// removeLast takes the greatest node out of the subtree under n, as removeFirst.
+func (n *treeSortedSet{{.Name}}Node) removeLast() (top, last *treeSortedSet{{.Name}}Node) {
+	if n.right == nil {
+		return n.left, n
+	}
+	n.right, last = n.right.removeLast()
+	return n.rebalance(), last
+}
+
+// Allows the removal of a single item in the set.
+// Returns true if the item was in the set.
+func (ts *{{.Name}}TreeSortedSet) Remove(v {{.Pointer}}{{.Name}}) bool {
+	var removed bool
+	ts.root, removed = ts.remove(ts.root, v)
+	if removed {
+		ts.length--
+	}
+	return removed
+}
+
+// RemoveAll removes every given item that is in the set, returning how many were removed.
+func (ts *{{.Name}}TreeSortedSet) RemoveAll(items ...{{.Pointer}}{{.Name}}) int {
+	removed := 0
+	for _, v := range items {
+		if ts.Remove(v) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// RetainAll removes every item that is not in the other set, returning how
+// many were removed.
+func (ts *{{.Name}}TreeSortedSet) RetainAll(other *{{.Name}}TreeSortedSet) int {
+	before := ts.length
+	ts.IntersectWith(other)
+	return before - ts.length
+}
+
+// RemoveIf removes every item for which pred returns true, returning how
+// many were removed. The set is rebuilt in a single pass.
+func (ts *{{.Name}}TreeSortedSet) RemoveIf(pred func(v {{.Pointer}}{{.Name}}) bool) int {
+	before := ts.length
+	var kept []{{.Pointer}}{{.Name}}
+	ts.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		if !pred(v) {
+			kept = append(kept, v)
+		}
+		return true
+	})
+	*ts = *ts.fromSorted(kept)
+	return before - ts.length
+}
+
+// Clears the entire set to be the empty set.
+func (ts *{{.Name}}TreeSortedSet) Clear() {
+	ts.root = nil
+	ts.length = 0
+}
+
+// Determines if a given item is already in the set.
+func (ts *{{.Name}}TreeSortedSet) Contains(v {{.Pointer}}{{.Name}}) bool {
+	n := ts.root
+	for n != nil {
+		switch c := ts.compare(v, n.value); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Determines if the given items are all in the set
+func (ts *{{.Name}}TreeSortedSet) ContainsAll(i ...{{.Pointer}}{{.Name}}) bool {
+	for _, v := range i {
+		if !ts.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// floor returns the greatest item in the set less than or equal to v, or
+// if strict, less than v.
+func (ts *{{.Name}}TreeSortedSet) floor(v {{.Pointer}}{{.Name}}, strict bool) (val {{.Pointer}}{{.Name}}, ok bool) {
+	for n := ts.root; n != nil; {
+		c := ts.compare(n.value, v)
+		if c == 0 && !strict {
+			return n.value, true
+		}
+		if c < 0 {
+			// a candidate, but there may be a greater one to the right
+			val, ok = n.value, true
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return
+}
+
+// ceiling returns the least item in the set greater than or equal to v, or
+// if strict, greater than v.
+func (ts *{{.Name}}TreeSortedSet) ceiling(v {{.Pointer}}{{.Name}}, strict bool) (val {{.Pointer}}{{.Name}}, ok bool) {
+	for n := ts.root; n != nil; {
+		c := ts.compare(n.value, v)
+		if c == 0 && !strict {
+			return n.value, true
+		}
+		if c > 0 {
+			// a candidate, but there may be a lesser one to the left
+			val, ok = n.value, true
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return
+}
+
+// Floor returns the greatest item in the set less than or equal to v.
+// ok is false if there is no such item.
+func (ts *{{.Name}}TreeSortedSet) Floor(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return ts.floor(v, false)
+}
+
+// Ceiling returns the least item in the set greater than or equal to v.
+// ok is false if there is no such item.
+func (ts *{{.Name}}TreeSortedSet) Ceiling(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return ts.ceiling(v, false)
+}
+
+// Lower returns the greatest item in the set strictly less than v.
+// ok is false if there is no such item.
+func (ts *{{.Name}}TreeSortedSet) Lower(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return ts.floor(v, true)
+}
+
+// Higher returns the least item in the set strictly greater than v.
+// ok is false if there is no such item.
+func (ts *{{.Name}}TreeSortedSet) Higher(v {{.Pointer}}{{.Name}}) (val {{.Pointer}}{{.Name}}, ok bool) {
+	return ts.ceiling(v, true)
+}
+
+// A read-only view of the items of a {{.Name}}TreeSortedSet between two
+// bounds. The view is evaluated lazily, seeking to its lower bound each time
+// it is iterated, so it reflects changes made to the set after it was created.
+type {{.Name}}TreeSortedSetView struct {
+	ts                       *{{.Name}}TreeSortedSet
+	lo, hi                   {{.Pointer}}{{.Name}}
+	hasLo, hasHi             bool
+	loInclusive, hiInclusive bool
+}
+
+// Range returns a view of the items between lo and hi, each bound being
+// included or excluded as requested.
+func (ts *{{.Name}}TreeSortedSet) Range(lo, hi {{.Pointer}}{{.Name}}, loInclusive, hiInclusive bool) {{.Name}}TreeSortedSetView {
+	return {{.Name}}TreeSortedSetView{ts: ts, lo: lo, hi: hi, hasLo: true, hasHi: true, loInclusive: loInclusive, hiInclusive: hiInclusive}
+}
+
+// HeadSet returns a view of the items strictly less than hi.
+func (ts *{{.Name}}TreeSortedSet) HeadSet(hi {{.Pointer}}{{.Name}}) {{.Name}}TreeSortedSetView {
+	return {{.Name}}TreeSortedSetView{ts: ts, hi: hi, hasHi: true}
+}
+
+// TailSet returns a view of the items greater than or equal to lo.
+func (ts *{{.Name}}TreeSortedSet) TailSet(lo {{.Pointer}}{{.Name}}) {{.Name}}TreeSortedSetView {
+	return {{.Name}}TreeSortedSetView{ts: ts, lo: lo, hasLo: true, loInclusive: true}
+}
+
+// Each calls f for every item in the view in ascending order, stopping
+// early if f returns false.
+func (sv {{.Name}}TreeSortedSetView) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	it := sv.ts.Iterator()
+	var ok bool
+	if !sv.hasLo {
+		ok = it.Next()
+	} else if ok = it.Seek(sv.lo); ok && !sv.loInclusive && !sv.ts.less(sv.lo, it.Value()) {
+		ok = it.Next()
+	}
+	for ; ok; ok = it.Next() {
+		v := it.Value()
+		if sv.hasHi && (sv.ts.less(sv.hi, v) || !sv.hiInclusive && !sv.ts.less(v, sv.hi)) {
+			return
+		}
+		if !f(v) {
+			return
+		}
+	}
+}
+
+// Cardinality returns how many items are currently in the view.
+// Unlike the set's, this walks every item in the view.
+func (sv {{.Name}}TreeSortedSetView) Cardinality() int {
+	n := 0
+	sv.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// ToSlice returns the items in the view in ascending order.
+func (sv {{.Name}}TreeSortedSetView) ToSlice() []{{.Pointer}}{{.Name}} {
+	var s []{{.Pointer}}{{.Name}}
+	sv.Each(func(v {{.Pointer}}{{.Name}}) bool {
+		s = append(s, v)
+		return true
+	})
+	return s
+}
+
+// ToSet copies the items in the view into a new set.
+func (sv {{.Name}}TreeSortedSetView) ToSet() *{{.Name}}TreeSortedSet {
+	return sv.ts.fromSorted(sv.ToSlice())
+}
+
+// merge walks both sets side by side in O(n+m), calling f with every item
+// in either set and whether it is in each, until f returns false.
+func (ts *{{.Name}}TreeSortedSet) merge(other *{{.Name}}TreeSortedSet, f func(v {{.Pointer}}{{.Name}}, inThis, inOther bool) bool) {
+	it, ot := ts.Iterator(), other.Iterator()
+	ok, ook := it.Next(), ot.Next()
+	for ok || ook {
+		var c int
+		switch {
+		case !ook:
+			c = -1
+		case !ok:
+			c = 1
+		default:
+			c = ts.compare(it.Value(), ot.Value())
+		}
+		switch {
+		case c < 0:
+			if !f(it.Value(), true, false) {
+				return
+			}
+			ok = it.Next()
+		case c > 0:
+			if !f(ot.Value(), false, true) {
+				return
+			}
+			ook = ot.Next()
+		default:
+			if !f(it.Value(), true, true) {
+				return
+			}
+			ok, ook = it.Next(), ot.Next()
+		}
+	}
+}
+
+// mergeInto returns a new set of the items merge finds for which keep
+// returns true.
+func (ts *{{.Name}}TreeSortedSet) mergeInto(other *{{.Name}}TreeSortedSet, keep func(inThis, inOther bool) bool) *{{.Name}}TreeSortedSet {
+	var items []{{.Pointer}}{{.Name}}
+	ts.merge(other, func(v {{.Pointer}}{{.Name}}, inThis, inOther bool) bool {
+		if keep(inThis, inOther) {
+			items = append(items, v)
+		}
+		return true
+	})
+	return ts.fromSorted(items)
+}
+
+// IsSubset determines if every item in this set is in the other set.
+func (ts *{{.Name}}TreeSortedSet) IsSubset(other *{{.Name}}TreeSortedSet) bool {
+	if ts.length > other.length {
+		return false
+	}
+	subset := true
+	ts.merge(other, func(v {{.Pointer}}{{.Name}}, inThis, inOther bool) bool {
+		subset = !inThis || inOther
+		return subset
+	})
+	return subset
+}
+
+// IsSuperset determines if every item in the other set is in this set.
+func (ts *{{.Name}}TreeSortedSet) IsSuperset(other *{{.Name}}TreeSortedSet) bool {
+	return other.IsSubset(ts)
+}
+
+// IsProperSubset determines if this set is a subset of the other set and
+// the other set has at least one item this one does not.
+func (ts *{{.Name}}TreeSortedSet) IsProperSubset(other *{{.Name}}TreeSortedSet) bool {
+	return ts.length < other.length && ts.IsSubset(other)
+}
+
+// IsProperSuperset determines if this set is a superset of the other set
+// and has at least one item the other set does not.
+func (ts *{{.Name}}TreeSortedSet) IsProperSuperset(other *{{.Name}}TreeSortedSet) bool {
+	return other.IsProperSubset(ts)
+}
+
+// IsDisjoint determines if the two sets have no items in common.
+// An empty set is disjoint with every set, itself included.
+func (ts *{{.Name}}TreeSortedSet) IsDisjoint(other *{{.Name}}TreeSortedSet) bool {
+	disjoint := true
+	ts.merge(other, func(v {{.Pointer}}{{.Name}}, inThis, inOther bool) bool {
+		disjoint = !inThis || !inOther
+		return disjoint
+	})
+	return disjoint
+}
+
+// Overlaps determines if the two sets have at least one item in common.
+func (ts *{{.Name}}TreeSortedSet) Overlaps(other *{{.Name}}TreeSortedSet) bool {
+	return !ts.IsDisjoint(other)
+}
+
+// Returns a new set with all items in both sets, in O(n+m).
+func (ts *{{.Name}}TreeSortedSet) Union(other *{{.Name}}TreeSortedSet) *{{.Name}}TreeSortedSet {
+	return ts.mergeInto(other, func(inThis, inOther bool) bool {
+		return true
+	})
+}
+
+// Returns a new set with items that exist only in both sets, in O(n+m).
+func (ts *{{.Name}}TreeSortedSet) Intersect(other *{{.Name}}TreeSortedSet) *{{.Name}}TreeSortedSet {
+	return ts.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis && inOther
+	})
+}
+
+// Returns a new set with items in the current set but not in the other set, in O(n+m).
+func (ts *{{.Name}}TreeSortedSet) Difference(other *{{.Name}}TreeSortedSet) *{{.Name}}TreeSortedSet {
+	return ts.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis && !inOther
+	})
+}
+
+// Returns a new set with items in the current set or the other set but not in both, in O(n+m).
+func (ts *{{.Name}}TreeSortedSet) SymmetricDifference(other *{{.Name}}TreeSortedSet) *{{.Name}}TreeSortedSet {
+	return ts.mergeInto(other, func(inThis, inOther bool) bool {
+		return inThis != inOther
+	})
+}
+
+// UnionWith adds every item of the other set to this one.
+func (ts *{{.Name}}TreeSortedSet) UnionWith(other *{{.Name}}TreeSortedSet) {
+	*ts = *ts.Union(other)
+}
+
+// IntersectWith removes every item that is not also in the other set.
+func (ts *{{.Name}}TreeSortedSet) IntersectWith(other *{{.Name}}TreeSortedSet) {
+	*ts = *ts.Intersect(other)
+}
+
+// DifferenceWith removes every item that is also in the other set.
+func (ts *{{.Name}}TreeSortedSet) DifferenceWith(other *{{.Name}}TreeSortedSet) {
+	*ts = *ts.Difference(other)
+}
+
+// Cardinality returns how many items are currently in the set.
+func (ts *{{.Name}}TreeSortedSet) Cardinality() int {
+	return ts.length
+}
+
+// Len returns how many items are currently in the set, like Cardinality.
+func (ts *{{.Name}}TreeSortedSet) Len() int {
+	return ts.length
+}
+
+// A cursor over the items of a {{.Name}}TreeSortedSet in ascending order.
+// It starts out positioned before the first item; Value is only valid
+// after Next, Prev or Seek has returned true.
+type {{.Name}}TreeSortedSetIterator struct {
+	ts      *{{.Name}}TreeSortedSet
+	stack   []*treeSortedSet{{.Name}}Node // the path from the root to the node at the cursor
+	started bool                          // with an empty stack, whether the cursor is past the last item rather than before the first
+}
+
+// Iterator returns a cursor positioned before the first item of the set.
+func (ts *{{.Name}}TreeSortedSet) Iterator() *{{.Name}}TreeSortedSetIterator {
+	return &{{.Name}}TreeSortedSetIterator{ts: ts}
+}
+
+// ReverseIterator returns a cursor positioned after the last item of the
+// set, to be walked from largest to smallest with Prev.
+func (ts *{{.Name}}TreeSortedSet) ReverseIterator() *{{.Name}}TreeSortedSetIterator {
+	return &{{.Name}}TreeSortedSetIterator{ts: ts, started: true}
+}
+
+// pushFirst extends the path down to the least item under n.
+func (it *{{.Name}}TreeSortedSetIterator) pushFirst(n *treeSortedSet{{.Name}}Node) {
+	for ; n != nil; n = n.left {
+		it.stack = append(it.stack, n)
+	}
+}
+
+// pushLast extends the path down to the greatest item under n.
+func (it *{{.Name}}TreeSortedSetIterator) pushLast(n *treeSortedSet{{.Name}}Node) {
+	for ; n != nil; n = n.right {
+		it.stack = append(it.stack, n)
+	}
+}
+
+// Next moves the cursor to the next item, returning false once it has
+// moved past the last one.
+func (it *{{.Name}}TreeSortedSetIterator) Next() bool {
+	if len(it.stack) == 0 {
+		if it.started || it.ts.root == nil {
+			it.started = true
+			return false
+		}
+		it.started = true
+		it.pushFirst(it.ts.root)
+		return true
+	}
+	if n := it.stack[len(it.stack)-1]; n.right != nil {
+		it.pushFirst(n.right)
+		return true
+	}
+	// climb to the nearest node whose left subtree the cursor was in
+	for {
+		child := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			return false
+		}
+		if it.stack[len(it.stack)-1].left == child {
+			return true
+		}
+	}
+}
+
+// Prev moves the cursor to the previous item, returning false once it has
+// moved before the first one.
+func (it *{{.Name}}TreeSortedSetIterator) Prev() bool {
+	if len(it.stack) == 0 {
+		if !it.started || it.ts.root == nil {
+			it.started = false
+			return false
+		}
+		it.pushLast(it.ts.root)
+		return true
+	}
+	if n := it.stack[len(it.stack)-1]; n.left != nil {
+		it.pushLast(n.left)
+		return true
+	}
+	// climb to the nearest node whose right subtree the cursor was in
+	for {
+		child := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			it.started = false
+			return false
+		}
+		if it.stack[len(it.stack)-1].right == child {
+			return true
+		}
+	}
+}
+
+// Seek moves the cursor to the least item greater than or equal to v,
+// returning false if there is no such item.
+func (it *{{.Name}}TreeSortedSetIterator) Seek(v {{.Pointer}}{{.Name}}) bool {
+	it.stack = it.stack[:0]
+	it.started = true
+	// the length of the path down to the least item seen greater than v
+	ceiling := 0
+	for n := it.ts.root; n != nil; {
+		it.stack = append(it.stack, n)
+		switch c := it.ts.compare(v, n.value); {
+		case c < 0:
+			ceiling = len(it.stack)
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return true
+		}
+	}
+	it.stack = it.stack[:ceiling]
+	return ceiling > 0
+}
+
+// Value returns the item at the cursor.
+func (it *{{.Name}}TreeSortedSetIterator) Value() {{.Pointer}}{{.Name}} {
+	return it.stack[len(it.stack)-1].value
+}
+
+// Each calls f for every item in ascending order, stopping early if f returns false.
+func (ts *{{.Name}}TreeSortedSet) Each(f func(v {{.Pointer}}{{.Name}}) bool) {
+	ts.root.each(f)
+}
+
+func (n *treeSortedSet{{.Name}}Node) each(f func(v {{.Pointer}}{{.Name}}) bool) bool {
+	for ; n != nil; n = n.right {
+		if !n.left.each(f) || !f(n.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReverseEach calls f for every item in descending order, stopping early if f returns false.
+func (ts *{{.Name}}TreeSortedSet) ReverseEach(f func(v {{.Pointer}}{{.Name}}) bool) {
+	ts.root.reverseEach(f)
+}
+
+func (n *treeSortedSet{{.Name}}Node) reverseEach(f func(v {{.Pointer}}{{.Name}}) bool) bool {
+	for ; n != nil; n = n.left {
+		if !n.right.reverseEach(f) || !f(n.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// First returns the least item in the set.
+// ok is false if the set is empty.
+func (ts *{{.Name}}TreeSortedSet) First() (val {{.Pointer}}{{.Name}}, ok bool) {
+	n := ts.root
+	if n == nil {
+		return
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return n.value, true
+}
+
+// PopFirst removes and returns the least item in the set.
+// ok is false if the set is empty.
+func (ts *{{.Name}}TreeSortedSet) PopFirst() (val {{.Pointer}}{{.Name}}, ok bool) {
+	if ts.root == nil {
+		return
+	}
+	var first *treeSortedSet{{.Name}}Node
+	ts.root, first = ts.root.removeFirst()
+	ts.length--
+	return first.value, true
+}
+
+// PopN removes and returns the k least items in the set in ascending
+// order, or every item if there are fewer than k.
+func (ts *{{.Name}}TreeSortedSet) PopN(k int) []{{.Pointer}}{{.Name}} {
+	var popped []{{.Pointer}}{{.Name}}
+	for len(popped) < k {
+		v, ok := ts.PopFirst()
+		if !ok {
+			break
+		}
+		popped = append(popped, v)
+	}
+	return popped
+}
+
+// Last returns the greatest item in the set.
+// ok is false if the set is empty.
+func (ts *{{.Name}}TreeSortedSet) Last() (val {{.Pointer}}{{.Name}}, ok bool) {
+	n := ts.root
+	if n == nil {
+		return
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return n.value, true
+}
+
+// PopLast removes and returns the greatest item in the set.
+// ok is false if the set is empty.
+func (ts *{{.Name}}TreeSortedSet) PopLast() (val {{.Pointer}}{{.Name}}, ok bool) {
+	if ts.root == nil {
+		return
+	}
+	var last *treeSortedSet{{.Name}}Node
+	ts.root, last = ts.root.removeLast()
+	ts.length--
+	return last.value, true
+}
+
+// PopMax removes and returns the greatest item in the set, like PopLast.
+func (ts *{{.Name}}TreeSortedSet) PopMax() (val {{.Pointer}}{{.Name}}, ok bool) {
+	return ts.PopLast()
+}
+
+// Iter() returns a channel of type {{.Pointer}}{{.Name}} that you can range over,
+// like {{.Name}}SortedSet's. The set must not be changed until the channel
+// is drained, and breaking out of the range loop leaks the goroutine
+// feeding the channel, so prefer Iterator or Each.
+func (ts *{{.Name}}TreeSortedSet) Iter() <-chan {{.Pointer}}{{.Name}} {
+	ch := make(chan {{.Pointer}}{{.Name}})
+	go func() {
+		ts.Each(func(v {{.Pointer}}{{.Name}}) bool {
+			ch <- v
+			return true
+		})
+		close(ch)
+	}()
+
+	return ch
+}
+
+// Equal determines if two sets are equal to each other.
+// If they both are the same size and have the same items they are considered equal.
+func (ts *{{.Name}}TreeSortedSet) Equal(other *{{.Name}}TreeSortedSet) bool {
+	return ts.length == other.length && ts.IsSubset(other)
+}
+
+// Returns a clone of the set, in O(n).
+// Does NOT clone the underlying elements.
+func (ts *{{.Name}}TreeSortedSet) Clone() *{{.Name}}TreeSortedSet {
+	return &{{.Name}}TreeSortedSet{less: ts.less, compare: ts.compare, root: ts.root.clone(), length: ts.length}
+}
+
+func (n *treeSortedSet{{.Name}}Node) clone() *treeSortedSet{{.Name}}Node {
+	if n == nil {
+		return nil
+	}
+	c := *n
+	c.left, c.right = n.left.clone(), n.right.clone()
+	return &c
+}
+`,
+}